@@ -0,0 +1,3 @@
+
+ALTER TABLE IF EXISTS mcp_server ADD IF NOT EXISTS auth_type smallint NOT NULl DEFAULT 0;
+ALTER TABLE IF EXISTS mcp_server ADD IF NOT EXISTS credential jsonb NOT NULl DEFAULT '{}';
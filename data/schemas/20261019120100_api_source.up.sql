@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS api_capability ADD IF NOT EXISTS source_id bigint NOT NULl DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE IF EXISTS corpus_document ADD IF NOT EXISTS source_id bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE IF EXISTS corpus_document ADD IF NOT EXISTS ts_cfg name NOT NULL DEFAULT '';
+ALTER TABLE IF EXISTS corpus_document ADD IF NOT EXISTS ts_vec tsvector;
+
+CREATE INDEX IF NOT EXISTS idx_corpus_document_ts_vec
+     ON corpus_document
+     USING GIN (ts_vec);
@@ -0,0 +1,22 @@
+-- 向量列改为不限维度，按模型区分向量空间，索引和匹配函数改为按维度生成
+DROP INDEX IF EXISTS idx_corpus_vector_400_embedding;
+DROP INDEX IF EXISTS idx_capability_vector_embedding;
+DROP INDEX IF EXISTS idx_tool_vector_embedding;
+
+ALTER TABLE IF EXISTS corpus_vector_400 ALTER COLUMN embedding TYPE vector;
+ALTER TABLE IF EXISTS corpus_vector_400 ADD IF NOT EXISTS model text NOT NULL DEFAULT '';
+
+ALTER TABLE IF EXISTS api_capability_vector ALTER COLUMN embedding TYPE vector;
+ALTER TABLE IF EXISTS api_capability_vector ADD IF NOT EXISTS model text NOT NULL DEFAULT '';
+
+ALTER TABLE IF EXISTS mcp_tool_vector ALTER COLUMN embedding TYPE vector;
+ALTER TABLE IF EXISTS mcp_tool_vector ADD IF NOT EXISTS model text NOT NULL DEFAULT '';
+ALTER TABLE IF EXISTS mcp_tool_vector DROP CONSTRAINT IF EXISTS mcp_tool_vector_name_key;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint
+    WHERE conname = 'mcp_tool_vector_name_model_key' AND conrelid = 'mcp_tool_vector'::regclass) THEN
+    ALTER TABLE mcp_tool_vector ADD CONSTRAINT mcp_tool_vector_name_model_key UNIQUE (name, model);
+  END IF;
+END $$;
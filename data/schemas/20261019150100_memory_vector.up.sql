@@ -0,0 +1,9 @@
+-- 记忆向量迁出知识库文档向量表，按所有人过滤匹配
+INSERT INTO convo_memory_vector (id, created, updated, creator_id, mem_id, owner_id, subject, embedding, model, meta)
+SELECT cv.id, cv.created, cv.updated, cv.creator_id, cv.doc_id, m.owner_id, cv.subject, cv.embedding, cv.model, cv.meta
+  FROM corpus_vector_400 cv JOIN convo_memory m ON m.id = cv.doc_id
+ON CONFLICT (id) DO NOTHING;
+
+-- 同时清理已删除记忆残留的向量
+DELETE FROM corpus_vector_400 cv
+ WHERE NOT EXISTS (SELECT 1 FROM corpus_document cd WHERE cd.id = cv.doc_id);
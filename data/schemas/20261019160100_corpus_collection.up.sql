@@ -0,0 +1,11 @@
+-- 文档集合，按集合限定可见范围，同一集合内标题和小节唯一
+ALTER TABLE IF EXISTS corpus_document ADD IF NOT EXISTS collection_id bigint NOT NULL DEFAULT 0;
+ALTER TABLE IF EXISTS corpus_document DROP CONSTRAINT IF EXISTS corpus_title_heading_key;
+
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint
+    WHERE conname = 'corpus_collection_title_heading_key' AND conrelid = 'corpus_document'::regclass) THEN
+    ALTER TABLE corpus_document ADD CONSTRAINT corpus_collection_title_heading_key UNIQUE (title, heading, collection_id);
+  END IF;
+END $$;
@@ -0,0 +1,9 @@
+-- 文档修订，以启用前的文档内容作为各文档的首个修订
+CREATE INDEX IF NOT EXISTS corpus_document_revision_doc_id_idx ON corpus_document_revision (doc_id);
+
+INSERT INTO corpus_document_revision (id, created, updated, creator_id, doc_id, title, heading, content, author, source)
+SELECT cd.id, cd.updated, cd.updated, cd.creator_id, cd.id, cd.title, cd.heading, cd.content,
+       COALESCE(cd.meta->>'creator', ''), 'baseline'
+  FROM corpus_document cd
+ WHERE NOT EXISTS (SELECT 1 FROM corpus_document_revision r WHERE r.doc_id = cd.id)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,2 @@
+-- 文档向量记下生成时内容的摘要，同步时跳过内容未变化的文档
+ALTER TABLE corpus_vector_400 ADD COLUMN IF NOT EXISTS content_hash text NOT NULL DEFAULT '';
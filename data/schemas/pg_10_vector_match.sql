@@ -0,0 +1,12 @@
+-- Note: Different providers have varying dimensions for Vector settings.
+-- 400=1024 (bge-m3), 600=1536 (openai embedding)
+--
+-- The embedding columns are untyped vector, match functions and partial hnsw indexes
+-- are generated per dimension by stores.EnsureVectorSpace:
+--   vector_match_docs_{hex}(query_embedding, model_name, similarity_threshold, match_count)
+--   vector_match_capability_{hex}(...)
+--   vector_match_tool_{hex}(...)
+
+DROP FUNCTION IF EXISTS vector_match_docs_4(vector, float, int);
+DROP FUNCTION IF EXISTS vector_match_capability_4(vector, float, int);
+DROP FUNCTION IF EXISTS vector_match_tool_4(vector, float, int);
@@ -4,10 +4,12 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"syscall"
 
@@ -22,6 +24,7 @@ import (
 	"github.com/liut/morign/pkg/services/llm"
 	"github.com/liut/morign/pkg/services/stores"
 	"github.com/liut/morign/pkg/settings"
+	"github.com/liut/morign/pkg/utils/doctext"
 	"github.com/liut/morign/pkg/web"
 )
 
@@ -53,7 +56,7 @@ func importDocs(cc *cli.Context) error {
 	} else {
 		lw = os.Stderr
 	}
-	err = stores.Sgt().Corpus().ImportDocs(cc.Context, file, lw)
+	err = stores.Sgt().Corpus().ImportDocs(cc.Context, cc.String("collection"), file, lw)
 	if err != nil {
 		logger().Warnw("import fail", "input", input, "err", err)
 		return err
@@ -87,7 +90,18 @@ func importSwagger(cc *cli.Context) error {
 		lw = os.Stderr
 	}
 
-	err = stores.Sgt().Capability().ImportCapabilities(cc.Context, file, lw)
+	source := cc.String("source")
+	if baseURL := cc.String("base-url"); len(baseURL) > 0 {
+		if len(source) == 0 {
+			return fmt.Errorf("source is required with base-url")
+		}
+		if _, err = stores.Sgt().Capability().EnsureSource(cc.Context, source, baseURL, true); err != nil {
+			logger().Warnw("ensure source fail", "source", source, "err", err)
+			return err
+		}
+	}
+
+	err = stores.Sgt().Capability().ImportCapabilities(cc.Context, source, file, lw)
 	if err != nil {
 		logger().Warnw("import swagger fail", "input", input, "err", err)
 		return err
@@ -95,6 +109,65 @@ func importSwagger(cc *cli.Context) error {
 	return nil
 }
 
+// ingestDocs 导入文件或目录中支持格式的文档，按标题切分为分块
+func ingestDocs(cc *cli.Context) error {
+	if cc.NArg() == 0 {
+		return fmt.Errorf("input file or directory is required")
+	}
+	var files []string
+	for _, input := range cc.Args().Slice() {
+		err := filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && (path == input || len(doctext.FormatOf(path)) > 0) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			logger().Warnw("walk fail", "input", input, "err", err)
+			return err
+		}
+	}
+	if len(files) > 1 && (len(cc.String("name")) > 0 || len(cc.String("title")) > 0 || len(cc.String("url")) > 0) {
+		return fmt.Errorf("name, title and url are only for a single file")
+	}
+
+	sto := stores.Sgt().Corpus()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logger().Warnw("read fail", "file", file, "err", err)
+			return err
+		}
+		name := cc.String("name")
+		if len(name) == 0 {
+			name = filepath.ToSlash(file)
+		}
+		res, err := sto.IngestDocument(cc.Context, stores.IngestArg{
+			Name:       name,
+			Title:      cc.String("title"),
+			Format:     cc.String("format"),
+			Data:       data,
+			ChunkSize:  cc.Int("chunk-size"),
+			Overlap:    cc.Int("overlap"),
+			Collection: cc.String("collection"),
+			URL:        cc.String("url"),
+		})
+		if err != nil {
+			logger().Warnw("ingest fail", "file", file, "err", err)
+			return err
+		}
+		if res.Unchanged {
+			fmt.Printf("%s: unchanged\n", name)
+		} else {
+			fmt.Printf("%s: %q %d chunks\n", name, res.Source.Title, res.Source.Chunks)
+		}
+	}
+	return nil
+}
+
 func exportDocs(cc *cli.Context) error {
 	output := cc.Args().First() // csv
 	file, err := os.OpenFile(output, os.O_RDWR|os.O_CREATE, 0644)
@@ -103,41 +176,121 @@ func exportDocs(cc *cli.Context) error {
 		return err
 	}
 	defer file.Close()
-	ctx := context.Background()
+	ctx := cc.Context
 	spec := &stores.CobDocumentSpec{}
 	spec.Limit = 90
 	spec.Sort = "id"
 	ea := stores.ExportArg{
-		Spec:   spec,
-		Out:    file,
-		Format: cc.String("format"),
+		Spec:       spec,
+		Out:        file,
+		Format:     cc.String("format"),
+		Collection: cc.String("collection"),
 	}
 	return stores.Sgt().Corpus().ExportDocs(ctx, ea)
 }
 
 func embeddingDocVector(cc *cli.Context) error {
-	ctx := context.Background()
-	target := cc.String("target")
+	return syncEmbedding(cc.Context, cc.String("target"), cc.Int("page-size"))
+}
 
+// syncEmbedding 分页为目标生成向量并打印报告，向量空间由 ctx 决定，
+// 中断后再次运行从断点继续，失败的编号在再次运行时会被重试
+func syncEmbedding(ctx context.Context, target string, pageSize int) error {
+	var (
+		rep *stores.SyncReport
+		err error
+	)
 	switch target {
 	case "doc":
 		spec := &stores.CobDocumentSpec{}
-		spec.Limit = cc.Int("limit")
-		spec.Sort = "id"
-		return stores.Sgt().Corpus().SyncEmbeddingDocments(ctx, spec)
+		spec.Limit = pageSize
+		rep, err = stores.Sgt().Corpus().SyncEmbeddingDocments(ctx, spec)
 	case "mem":
 		spec := &stores.ConvoMemorySpec{}
-		spec.Limit = cc.Int("limit")
-		spec.Sort = "id"
-		return stores.Sgt().Convo().SyncEmbeddingMemories(ctx, spec)
+		spec.Limit = pageSize
+		rep, err = stores.Sgt().Convo().SyncEmbeddingMemories(ctx, spec)
 	case "capability":
 		spec := &stores.CapCapabilitySpec{}
-		spec.Limit = cc.Int("limit")
-		spec.Sort = "id"
-		return stores.Sgt().Capability().SyncEmbeddingCapabilities(ctx, spec)
+		spec.Limit = pageSize
+		rep, err = stores.Sgt().Capability().SyncEmbeddingCapabilities(ctx, spec)
 	default:
 		return fmt.Errorf("unsupported target: %s (supported: doc, mem, capability)", target)
 	}
+	if rep != nil {
+		fmt.Println(rep)
+		if len(rep.Failed) > 0 {
+			fmt.Printf("failed: %s\n", rep.Failed)
+		}
+	}
+	return err
+}
+
+func vectorStatus(cc *cli.Context) error {
+	stats, err := stores.VectorStats(cc.Context, stores.SgtDB())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("active: %s\n", stores.ActiveSpace())
+	for _, st := range stats {
+		model := st.Model
+		if len(model) == 0 {
+			model = "(legacy)"
+		}
+		fmt.Printf("%-12s %-36s %5d %8d\n", st.Target, model, st.Dim, st.Count)
+	}
+	return nil
+}
+
+// vectorMigrate 以新的嵌入模型重新生成向量，当前模型的向量保留并继续服务，
+// 完成后修改 Embedding 配置并重启即切换到新模型
+func vectorMigrate(cc *cli.Context) error {
+	ctx := cc.Context
+	p := settings.Current.Embedding
+	p.Model = cc.String("model")
+	if v := cc.String("type"); len(v) > 0 {
+		p.Type = v
+	}
+	if v := cc.String("url"); len(v) > 0 {
+		p.URL = v
+	}
+	if v := cc.String("api-key"); len(v) > 0 {
+		p.APIKey = v
+	}
+	vs, err := stores.NewVectorSpace(p, cc.Int("dim"))
+	if err != nil {
+		return err
+	}
+	active := stores.ActiveSpace()
+	if vs.Model == active.Model {
+		return fmt.Errorf("model %s is already active", vs.Model)
+	}
+
+	db := stores.SgtDB()
+	// 未记录模型的旧向量先归入当前空间，以免与新空间混淆
+	if _, err = stores.ClaimLegacyVectors(ctx, db, active); err != nil {
+		return err
+	}
+	if err = stores.EnsureVectorSpace(ctx, db, vs); err != nil {
+		return err
+	}
+	sctx := stores.ContextWithVectorSpace(ctx, vs)
+	for _, target := range cc.StringSlice("target") {
+		fmt.Printf("embedding %s into %s\n", target, vs)
+		if err = syncEmbedding(sctx, target, cc.Int("page-size")); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("done, switch Embedding_Model and Embedding_Dim to %s and restart to cut over\n", vs)
+	return vectorStatus(cc)
+}
+
+func vectorDrop(cc *cli.Context) error {
+	n, err := stores.DropVectorSpace(cc.Context, stores.SgtDB(), cc.String("model"))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("dropped %d vectors of %s\n", n, cc.String("model"))
+	return nil
 }
 
 func agent(cc *cli.Context) error {
@@ -231,15 +384,33 @@ func main() {
 				Action: importDocs,
 				Flags: []cli.Flag{
 					&cli.StringFlag{Name: "diff", Aliases: []string{"diff-log"}, Value: "", Usage: "a filename of diff"},
+					&cli.StringFlag{Name: "collection", Aliases: []string{"c"}, Value: "", Usage: "collection name, default is the public default collection"},
+				},
+			},
+			{
+				Name:      "ingest",
+				Usage:     "ingest markdown, html, pdf, docx or text files into chunked documents",
+				ArgsUsage: "<file or directory>...",
+				Action:    ingestDocs,
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "name", Aliases: []string{"n"}, Value: "", Usage: "source name, default is the file path"},
+					&cli.StringFlag{Name: "title", Value: "", Usage: "document title, default is the only h1 or the file name"},
+					&cli.StringFlag{Name: "format", Aliases: []string{"t"}, Value: "", Usage: "md|html|pdf|docx|txt, default by extension"},
+					&cli.IntFlag{Name: "chunk-size", Aliases: []string{"s"}, Value: 0, Usage: "max characters of a chunk, default Ingest_Chunk_Size"},
+					&cli.IntFlag{Name: "overlap", Value: 0, Usage: "overlapped characters between chunks, default Ingest_Chunk_Overlap"},
+					&cli.StringFlag{Name: "collection", Aliases: []string{"c"}, Value: "", Usage: "collection name, default is the public default collection"},
+					&cli.StringFlag{Name: "url", Value: "", Usage: "source url of the document, linked by citations in answers"},
 				},
 			},
 			{
 				Name:    "import-swagger",
-				Usage:   "import API capabilities from swagger yaml/json",
+				Usage:   "import API capabilities from swagger 2.0 or openapi 3.x yaml/json",
 				Aliases: []string{"import-capability"},
 				Action:  importSwagger,
 				Flags: []cli.Flag{
 					&cli.StringFlag{Name: "diff", Aliases: []string{"diff-log"}, Value: "", Usage: "a filename of diff"},
+					&cli.StringFlag{Name: "source", Aliases: []string{"s"}, Value: "", Usage: "api source name, empty for the default Bus prefix"},
+					&cli.StringFlag{Name: "base-url", Value: "", Usage: "base url of the source, created if not exists"},
 				},
 			},
 			{
@@ -248,6 +419,7 @@ func main() {
 				Aliases: []string{"exportDocs"},
 				Flags: []cli.Flag{
 					&cli.StringFlag{Name: "format", Aliases: []string{"t"}, Value: "csv", Usage: "csv|jsonl"},
+					&cli.StringFlag{Name: "collection", Aliases: []string{"c"}, Value: "", Usage: "collection name, default is all collections"},
 				},
 				Action: exportDocs,
 			},
@@ -258,7 +430,40 @@ func main() {
 				Action:  embeddingDocVector,
 				Flags: []cli.Flag{
 					&cli.StringFlag{Name: "target", Aliases: []string{"t"}, Value: "doc", Usage: "target to embed: doc|mem|capability"},
-					&cli.IntFlag{Name: "limit", Aliases: []string{"l"}, Value: 90, Usage: "limit for query"},
+					&cli.IntFlag{Name: "page-size", Aliases: []string{"limit", "l"}, Usage: "items per page, default as Embed_Sync_Page_Size"},
+				},
+			},
+			{
+				Name:  "vector",
+				Usage: "manage vector spaces of embedding models",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "status",
+						Usage:  "show vectors by target, model and dimension",
+						Action: vectorStatus,
+					},
+					{
+						Name:   "migrate",
+						Usage:  "re-embed into a new model while the active one keeps serving",
+						Action: vectorMigrate,
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "model", Aliases: []string{"m"}, Required: true, Usage: "embedding model of the new space"},
+							&cli.IntFlag{Name: "dim", Aliases: []string{"d"}, Required: true, Usage: "dimension of the new model"},
+							&cli.StringFlag{Name: "type", Usage: "provider type, default as Embedding_Type"},
+							&cli.StringFlag{Name: "url", Usage: "provider url, default as Embedding_URL"},
+							&cli.StringFlag{Name: "api-key", Usage: "provider api key, default as Embedding_Api_Key"},
+							&cli.StringSliceFlag{Name: "target", Aliases: []string{"t"}, Value: cli.NewStringSlice("doc", "mem", "capability"), Usage: "targets to embed, tools are synced at server start"},
+							&cli.IntFlag{Name: "page-size", Aliases: []string{"limit", "l"}, Usage: "items per page, default as Embed_Sync_Page_Size"},
+						},
+					},
+					{
+						Name:   "drop",
+						Usage:  "drop vectors of a retired model",
+						Action: vectorDrop,
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "model", Aliases: []string{"m"}, Required: true, Usage: "embedding model to drop, not the active one"},
+						},
+					},
 				},
 			},
 			{
@@ -295,7 +500,8 @@ func main() {
 	// 	webRun()
 	// 	return
 	// }
-	if err := app.Run(os.Args); err != nil {
+	// 命令行是受信任的调用，不受知识库集合规则限制
+	if err := app.RunContext(stores.ContextWithTrusted(context.Background()), os.Args); err != nil {
 		logger().Fatalw("app run fail", "err", err)
 	}
 }
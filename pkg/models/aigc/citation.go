@@ -0,0 +1,34 @@
+package aigc
+
+import (
+	"github.com/cupogo/andvari/models/oid"
+)
+
+// Citation 回答引用的知识库文档
+// 模型在回答中以 [^Ref] 标记引用，前端据此链接到文档或来源地址
+type Citation struct {
+	// 引用序号 同一轮对话中从 1 开始
+	Ref int `extensions:"x-order=A" json:"ref"`
+	// 文档编号
+	DocID oid.OID `extensions:"x-order=B" json:"docID" swaggertype:"string"`
+	// 主标题
+	Title string `extensions:"x-order=C" json:"title"`
+	// 小节标题
+	Heading string `extensions:"x-order=D" json:"heading,omitempty"`
+	// 相似度 检索得分
+	Similarity float32 `extensions:"x-order=E" json:"similarity,omitempty"`
+	// 来源地址 可选
+	URL string `extensions:"x-order=F" json:"url,omitempty"`
+} // @name aigcCitation
+
+// Citations is a slice of Citation
+type Citations []Citation
+
+// DocIDs returns the document IDs of all citations
+func (z Citations) DocIDs() (out oid.OIDs) {
+	out = make(oid.OIDs, 0, len(z))
+	for _, c := range z {
+		out = append(out, c.DocID)
+	}
+	return
+}
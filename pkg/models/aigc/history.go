@@ -22,6 +22,9 @@ type HistoryItem struct {
 
 	// chat
 	ChatItem *HistoryChatItem `json:"ci"`
+
+	// 本轮回答引用的知识库文档
+	Citations Citations `json:"citations,omitempty"`
 }
 
 // calcTokens calculates the token count for history record (approximate)
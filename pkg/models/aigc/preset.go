@@ -1,5 +1,7 @@
 package aigc
 
+import "time"
+
 // Preset is the preset configuration including welcome message, system prompt and tool descriptions
 type Preset struct {
 	Welcome       string `json:"welcome,omitempty" yaml:"welcome,omitempty"`
@@ -15,6 +17,38 @@ type Preset struct {
 
 	// Channels holds channel adapter configurations
 	Channels map[string]ChannelConfig `json:"channels,omitempty" yaml:"channels,omitempty"`
+
+	// ToolPolicy decides which tools are offered per channel, role and user
+	ToolPolicy ToolPolicy `json:"toolPolicy,omitempty" yaml:"toolPolicy,omitempty"`
+
+	// ToolCache enables result caching per tool name or pattern, e.g. "fetch" or "github-list_*"
+	ToolCache map[string]ToolCacheRule `json:"toolCache,omitempty" yaml:"toolCache,omitempty"`
+}
+
+// ToolCacheRule is the cache setting of read tools.
+//
+// Results are cached per user unless Shared is set, for public data only.
+// Write tools and non-GET capability invocations are never cached.
+type ToolCacheRule struct {
+	TTL    time.Duration `json:"ttl" yaml:"ttl"`
+	Shared bool          `json:"shared,omitempty" yaml:"shared,omitempty"`
+}
+
+// ToolRule is a pair of tool name patterns, e.g. "kb_*" or "github-*"
+type ToolRule struct {
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty" yaml:"deny,omitempty"`
+}
+
+// ToolPolicy holds tool rules for default and per channel, role (by name) and user (by uid).
+//
+// All rules matching the caller apply together: a deny in any of them wins,
+// otherwise a tool is allowed if some allow list matches it, or if none of them has an allow list.
+type ToolPolicy struct {
+	Default  ToolRule            `json:"default,omitzero" yaml:"default,omitempty"`
+	Channels map[string]ToolRule `json:"channels,omitempty" yaml:"channels,omitempty"`
+	Roles    map[string]ToolRule `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Users    map[string]ToolRule `json:"users,omitempty" yaml:"users,omitempty"`
 }
 
 // ChannelConfig holds configuration for a single channel adapter.
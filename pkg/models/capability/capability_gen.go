@@ -6,6 +6,7 @@ import (
 	comm "github.com/cupogo/andvari/models/comm"
 	oid "github.com/cupogo/andvari/models/oid"
 	corpus "github.com/liut/morign/pkg/models/corpus"
+	mcps "github.com/liut/morign/pkg/models/mcps"
 )
 
 // consts of Capability API
@@ -44,6 +45,8 @@ type CapabilityBasic struct {
 	Responses map[string]SwaggerResponse `bson:"responses" bun:",notnull,type:jsonb,default:'{}'" extensions:"x-order=G" json:"responses,omitempty" pg:",notnull,type:jsonb,default:'{}'"`
 	// API 标签
 	Tags []string `bson:"tags" bun:",notnull,type:jsonb,default:'[]'" extensions:"x-order=H" json:"tags,omitempty" pg:",notnull,type:jsonb,default:'[]'"`
+	// 所属 API 来源，为零时使用默认的 BusPrefix
+	SourceID oid.OID `bson:"sourceID" bun:"source_id,notnull" extensions:"x-order=I" json:"sourceID,omitempty" pg:"source_id,notnull" swaggertype:"string"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
 } // @name capabilityBasic
@@ -92,6 +95,8 @@ type CapabilitySet struct {
 	Responses *map[string]SwaggerResponse `extensions:"x-order=G" json:"responses,omitempty"`
 	// API 标签
 	Tags *[]string `extensions:"x-order=H" json:"tags,omitempty"`
+	// 所属 API 来源，为零时使用默认的 BusPrefix
+	SourceID *string `extensions:"x-order=I" json:"sourceID,omitempty"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
 } // @name capabilitySet
@@ -129,6 +134,12 @@ func (z *Capability) SetWith(o CapabilitySet) {
 		z.LogChangeValue("tags", z.Tags, o.Tags)
 		z.Tags = *o.Tags
 	}
+	if o.SourceID != nil {
+		if id := oid.Cast(*o.SourceID); z.SourceID != id {
+			z.LogChangeValue("source_id", z.SourceID, id)
+			z.SourceID = id
+		}
+	}
 	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
 		z.SetChange("meta")
 	}
@@ -166,8 +177,10 @@ type CapabilityVectorBasic struct {
 	CapID oid.OID `bson:"capID" bun:"cap_id,notnull" extensions:"x-order=A" json:"capID" pg:"cap_id,notnull" swaggertype:"string"`
 	// 主题 基于 summary + description 等生成
 	Subject string `bson:"subject" bun:"subject,notnull,type:text" extensions:"x-order=B" form:"subject" json:"subject" pg:"subject,notnull,type:text"`
-	// 语义向量 1024 维
-	Vector corpus.Vector `bson:"vector" bun:"embedding,notnull,type:vector(1024)" extensions:"x-order=C" json:"vector" pg:"embedding,notnull,type:vector(1024)"`
+	// 语义向量 维度由嵌入模型决定
+	Vector corpus.Vector `bson:"vector" bun:"embedding,notnull,type:vector" extensions:"x-order=C" json:"vector" pg:"embedding,notnull,type:vector"`
+	// 嵌入模型
+	Model string `bson:"model" bun:"model,notnull,type:text" extensions:"x-order=D" form:"model" json:"model" pg:"model,notnull,type:text"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
 } // @name capabilityCapabilityVectorBasic
@@ -204,8 +217,10 @@ type CapabilityVectorSet struct {
 	CapID *string `extensions:"x-order=A" json:"capID"`
 	// 主题 基于 summary + description 等生成
 	Subject *string `extensions:"x-order=B" json:"subject"`
-	// 语义向量 1024 维
+	// 语义向量 维度由嵌入模型决定
 	Vector *corpus.Vector `extensions:"x-order=C" json:"vector"`
+	// 嵌入模型
+	Model *string `extensions:"x-order=D" json:"model"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
 } // @name capabilityCapabilityVectorSet
@@ -225,6 +240,10 @@ func (z *CapabilityVector) SetWith(o CapabilityVectorSet) {
 		z.LogChangeValue("embedding", z.Vector, o.Vector)
 		z.Vector = *o.Vector
 	}
+	if o.Model != nil && z.Model != *o.Model {
+		z.LogChangeValue("model", z.Model, o.Model)
+		z.Model = *o.Model
+	}
 	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
 		z.SetChange("meta")
 	}
@@ -238,6 +257,149 @@ func (in *CapabilityVectorSet) MetaAddKVs(args ...any) *CapabilityVectorSet {
 	return in
 }
 
+// consts of Source API
+const (
+	SourceTable = "api_source"
+	SourceAlias = "as"
+	SourceLabel = "source"
+	SourceTypID = "capabilitySource"
+)
+
+// Source API 来源
+type Source struct {
+	comm.BaseModel `bun:"table:api_source,alias:as" json:"-"`
+
+	comm.DefaultModel
+
+	SourceBasic
+
+	// 定制头函数
+	HeaderFunc mcps.HeaderFunc `bson:"-" bun:"-" extensions:"x-order=H" json:"-" pg:"-"`
+
+	comm.MetaField
+} // @name capabilitySource
+
+type SourceBasic struct {
+	// 名称
+	Name string `binding:"required" bson:"name" bun:",notnull,unique,type:name" extensions:"x-order=A" form:"name" json:"name" pg:",notnull,unique,type:name"`
+	// 基础网址，如 https://api.example.com
+	BaseURL string `binding:"required" bson:"baseURL" bun:",notnull" extensions:"x-order=B" form:"baseURL" json:"baseURL" pg:",notnull"`
+	// 超时秒数 0 表示默认 30 秒
+	Timeout int `bson:"timeout" bun:",notnull" extensions:"x-order=C" form:"timeout" json:"timeout" pg:",notnull"`
+	// 备注
+	Remark string `bson:"remark" bun:",notnull" extensions:"x-order=D" form:"remark" json:"remark" pg:",notnull"`
+	// 头分类 authorization 表示转发当前用户的令牌
+	//  * `authorization`
+	//  * `ownerID`
+	//  * `sessionID`
+	HeaderCate mcps.HeaderCate `bson:"headerCate" bun:",notnull,type:smallint" enums:"authorization,ownerID,sessionID" extensions:"x-order=E" json:"headerCate" pg:",notnull,type:smallint" swaggertype:"string"`
+	// 认证类型
+	//  * `none` - 无
+	//  * `header` - 静态头
+	//  * `bearer` - Bearer 令牌
+	//  * `oAuth2` - OAuth2 客户端凭证
+	AuthType mcps.AuthType `bson:"authType" bun:",notnull,type:smallint" enums:"none,header,bearer,oAuth2" extensions:"x-order=F" form:"authType" json:"authType" pg:",notnull,type:smallint" swaggertype:"string"`
+	// 认证凭据 敏感字段加密存储
+	Credential mcps.Credential `bson:"credential" bun:",notnull,type:jsonb,default:'{}'" extensions:"x-order=G" json:"credential" pg:",notnull,type:jsonb,default:'{}'"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
+} // @name capabilitySourceBasic
+
+type Sources []Source
+
+// Creating function call to it's inner fields defined hooks
+func (z *Source) Creating() error {
+	if z.IsZeroID() {
+		z.SetID(oid.NewID(oid.OtFile))
+	}
+
+	return z.DefaultModel.Creating()
+}
+func NewSourceWithBasic(in SourceBasic) *Source {
+	obj := &Source{
+		SourceBasic: in,
+	}
+	_ = obj.MetaUp(in.MetaDiff)
+	return obj
+}
+func NewSourceWithID(id any) *Source {
+	obj := new(Source)
+	_ = obj.SetID(id)
+	return obj
+}
+func (_ *Source) IdentityLabel() string { return SourceLabel }
+func (_ *Source) IdentityModel() string { return SourceTypID }
+func (_ *Source) IdentityTable() string { return SourceTable }
+func (_ *Source) IdentityAlias() string { return SourceAlias }
+
+type SourceSet struct {
+	// 名称
+	Name *string `extensions:"x-order=A" json:"name"`
+	// 基础网址，如 https://api.example.com
+	BaseURL *string `extensions:"x-order=B" json:"baseURL"`
+	// 超时秒数 0 表示默认 30 秒
+	Timeout *int `extensions:"x-order=C" json:"timeout"`
+	// 备注
+	Remark *string `extensions:"x-order=D" json:"remark"`
+	// 头分类 authorization 表示转发当前用户的令牌
+	//  * `authorization`
+	//  * `ownerID`
+	//  * `sessionID`
+	HeaderCate *mcps.HeaderCate `enums:"authorization,ownerID,sessionID" extensions:"x-order=E" json:"headerCate" swaggertype:"string"`
+	// 认证类型
+	//  * `none` - 无
+	//  * `header` - 静态头
+	//  * `bearer` - Bearer 令牌
+	//  * `oAuth2` - OAuth2 客户端凭证
+	AuthType *mcps.AuthType `enums:"none,header,bearer,oAuth2" extensions:"x-order=F" json:"authType" swaggertype:"string"`
+	// 认证凭据 敏感字段加密存储
+	Credential *mcps.Credential `extensions:"x-order=G" json:"credential"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
+} // @name capabilitySourceSet
+
+func (z *Source) SetWith(o SourceSet) {
+	if o.Name != nil && z.Name != *o.Name {
+		z.LogChangeValue("name", z.Name, o.Name)
+		z.Name = *o.Name
+	}
+	if o.BaseURL != nil && z.BaseURL != *o.BaseURL {
+		z.LogChangeValue("base_url", z.BaseURL, o.BaseURL)
+		z.BaseURL = *o.BaseURL
+	}
+	if o.Timeout != nil && z.Timeout != *o.Timeout {
+		z.LogChangeValue("timeout", z.Timeout, o.Timeout)
+		z.Timeout = *o.Timeout
+	}
+	if o.Remark != nil && z.Remark != *o.Remark {
+		z.LogChangeValue("remark", z.Remark, o.Remark)
+		z.Remark = *o.Remark
+	}
+	if o.HeaderCate != nil {
+		z.LogChangeValue("header_cate", z.HeaderCate, o.HeaderCate)
+		z.HeaderCate = *o.HeaderCate
+	}
+	if o.AuthType != nil && z.AuthType != *o.AuthType {
+		z.LogChangeValue("auth_type", z.AuthType, o.AuthType)
+		z.AuthType = *o.AuthType
+	}
+	if o.Credential != nil {
+		z.SetChange("credential")
+		z.Credential = *o.Credential
+	}
+	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
+		z.SetChange("meta")
+	}
+}
+func (in *SourceBasic) MetaAddKVs(args ...any) *SourceBasic {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+func (in *SourceSet) MetaAddKVs(args ...any) *SourceSet {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+
 // consts of SwaggerParam swagger
 const (
 	SwaggerParamLabel = "swaggerParam"
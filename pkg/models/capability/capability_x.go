@@ -1,6 +1,8 @@
 package capability
 
 import (
+	"maps"
+	"regexp"
 	"strings"
 
 	"github.com/liut/morign/pkg/models/aigc"
@@ -39,6 +41,44 @@ func (z Capabilities) Endpoints() []string {
 	return out
 }
 
+var pathVarRE = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// ParamsSchema 将参数定义转换为 JSON Schema，用于校验调用参数
+//
+// header 参数由调用方注入，不在校验范围内；路径变量总是必填，
+// 即使文档遗漏了声明。参数自带 schema 时优先使用。
+func (z *CapabilityBasic) ParamsSchema() map[string]any {
+	props := map[string]any{}
+	var required []string
+	for _, p := range z.Parameters {
+		if len(p.Name) == 0 || p.In == "header" || p.In == "cookie" {
+			continue
+		}
+		ps := map[string]any{}
+		if m, ok := p.Schema.(map[string]any); ok {
+			ps = maps.Clone(m)
+		}
+		if _, ok := ps["type"]; !ok && len(p.Type) > 0 && p.Type != "file" {
+			ps["type"] = p.Type
+		}
+		props[p.Name] = ps
+		if p.Required || p.In == "path" {
+			required = append(required, p.Name)
+		}
+	}
+	for _, m := range pathVarRE.FindAllStringSubmatch(z.Endpoint, -1) {
+		if _, ok := props[m[1]]; !ok {
+			props[m[1]] = map[string]any{"type": "string"}
+			required = append(required, m[1])
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": props,
+		"required":   required,
+	}
+}
+
 // FilterParams removes parameters with the specified name from the list
 func FilterParams(params []SwaggerParam, excludeName string) []SwaggerParam {
 	if len(params) == 0 {
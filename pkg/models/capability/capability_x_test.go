@@ -110,3 +110,40 @@ func TestCapability_EnrichSortableFields(t *testing.T) {
 		})
 	}
 }
+
+func TestCapability_ParamsSchema(t *testing.T) {
+	basic := CapabilityBasic{
+		Endpoint: "/api/companies/{coId}/users/{id}",
+		Parameters: []SwaggerParam{
+			{Name: "id", In: "path", Type: "string"},
+			{Name: "limit", In: "query", Type: "integer"},
+			{Name: "token", In: "header", Type: "string", Required: true},
+			{Name: "status", In: "body", Type: "string", Required: true,
+				Schema: map[string]any{"type": "string", "enum": []any{"on", "off"}}},
+		},
+	}
+	schema := basic.ParamsSchema()
+	props := schema["properties"].(map[string]any)
+	if len(props) != 4 {
+		t.Fatalf("unexpected properties %v", props)
+	}
+	if _, ok := props["token"]; ok {
+		t.Error("header param should be skipped")
+	}
+	if props["limit"].(map[string]any)["type"] != "integer" {
+		t.Errorf("unexpected limit schema %v", props["limit"])
+	}
+	if props["status"].(map[string]any)["enum"] == nil {
+		t.Errorf("param schema should be kept: %v", props["status"])
+	}
+	required := schema["required"].([]string)
+	want := []string{"id", "status", "coId"}
+	if len(required) != len(want) {
+		t.Fatalf("got required %v, want %v", required, want)
+	}
+	for i := range want {
+		if required[i] != want[i] {
+			t.Errorf("got required %v, want %v", required, want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	comm "github.com/cupogo/andvari/models/comm"
 	oid "github.com/cupogo/andvari/models/oid"
+	corpus "github.com/liut/morign/pkg/models/corpus"
 )
 
 func init() {
@@ -49,6 +50,47 @@ func (z SessionStatus) MarshalText() ([]byte, error) {
 	return []byte(z.String()), nil
 }
 
+// 工具调用状态
+type ToolCallStatus int8
+
+const (
+	ToolCallStatusOk     ToolCallStatus = 1 + iota //  1 成功
+	ToolCallStatusFailed                           //  2 失败
+	ToolCallStatusError                            //  3 异常
+)
+
+func (z *ToolCallStatus) Decode(s string) error {
+	switch s {
+	case "1", "ok", "Ok":
+		*z = ToolCallStatusOk
+	case "2", "failed", "Failed":
+		*z = ToolCallStatusFailed
+	case "3", "error", "Error":
+		*z = ToolCallStatusError
+	default:
+		return fmt.Errorf("invalid toolCallStatus: %q", s)
+	}
+	return nil
+}
+func (z *ToolCallStatus) UnmarshalText(b []byte) error {
+	return z.Decode(string(b))
+}
+func (z ToolCallStatus) String() string {
+	switch z {
+	case ToolCallStatusOk:
+		return "ok"
+	case ToolCallStatusFailed:
+		return "failed"
+	case ToolCallStatusError:
+		return "error"
+	default:
+		return fmt.Sprintf("toolCallStatus %d", int8(z))
+	}
+}
+func (z ToolCallStatus) MarshalText() ([]byte, error) {
+	return []byte(z.String()), nil
+}
+
 // consts of Session 会话
 const (
 	SessionTable = "convo_session"
@@ -640,3 +682,192 @@ func (in *MemorySet) MetaAddKVs(args ...any) *MemorySet {
 	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
 	return in
 }
+
+// consts of MemoryVector 记忆向量
+const (
+	MemoryVectorTable = "convo_memory_vector"
+	MemoryVectorAlias = "mv"
+	MemoryVectorLabel = "memoryVector"
+	MemoryVectorTypID = "convoMemoryVector"
+)
+
+// MemoryVector 记忆向量 与知识库文档向量分开存放，按所有人过滤
+type MemoryVector struct {
+	comm.BaseModel `bun:"table:convo_memory_vector,alias:mv" json:"-"`
+
+	comm.DefaultModel
+
+	MemoryVectorBasic
+
+	comm.MetaField
+} // @name convoMemoryVector
+
+type MemoryVectorBasic struct {
+	// 记忆编号
+	MemID oid.OID `bun:"mem_id,notnull" extensions:"x-order=A" json:"memID" pg:"mem_id,notnull" swaggertype:"string"`
+	// 所有人编号
+	OwnerID oid.OID `bun:"owner_id,notnull,type:bigint" extensions:"x-order=B" json:"ownerID" pg:"owner_id,notnull,type:bigint" swaggertype:"string"`
+	// 主题 由关键点、分类和内容组成
+	Subject string `bun:"subject,notnull,type:text" extensions:"x-order=C" json:"subject" pg:"subject,notnull,type:text"`
+	// 语义向量 维度由嵌入模型决定
+	Vector corpus.Vector `bun:"embedding,notnull,type:vector" extensions:"x-order=D" json:"vector,omitempty" pg:"embedding,notnull,type:vector"`
+	// 嵌入模型
+	Model string `bun:"model,notnull,type:text" extensions:"x-order=E" form:"model" json:"model" pg:"model,notnull,type:text"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
+} // @name convoMemoryVectorBasic
+
+type MemoryVectors []MemoryVector
+
+// Creating function call to it's inner fields defined hooks
+func (z *MemoryVector) Creating() error {
+	if z.IsZeroID() {
+		z.SetID(oid.NewID(oid.OtEvent))
+	}
+
+	return z.DefaultModel.Creating()
+}
+func NewMemoryVectorWithBasic(in MemoryVectorBasic) *MemoryVector {
+	obj := &MemoryVector{
+		MemoryVectorBasic: in,
+	}
+	_ = obj.MetaUp(in.MetaDiff)
+	return obj
+}
+func NewMemoryVectorWithID(id any) *MemoryVector {
+	obj := new(MemoryVector)
+	_ = obj.SetID(id)
+	return obj
+}
+func (_ *MemoryVector) IdentityLabel() string { return MemoryVectorLabel }
+func (_ *MemoryVector) IdentityModel() string { return MemoryVectorTypID }
+func (_ *MemoryVector) IdentityTable() string { return MemoryVectorTable }
+func (_ *MemoryVector) IdentityAlias() string { return MemoryVectorAlias }
+
+type MemoryVectorSet struct {
+	// 主题 由关键点、分类和内容组成
+	Subject *string `extensions:"x-order=A" json:"subject"`
+	// 语义向量 维度由嵌入模型决定
+	Vector *corpus.Vector `extensions:"x-order=B" json:"vector,omitempty"`
+	// 嵌入模型
+	Model *string `extensions:"x-order=C" json:"model"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
+} // @name convoMemoryVectorSet
+
+func (z *MemoryVector) SetWith(o MemoryVectorSet) {
+	if o.Subject != nil && z.Subject != *o.Subject {
+		z.LogChangeValue("subject", z.Subject, o.Subject)
+		z.Subject = *o.Subject
+	}
+	if o.Vector != nil {
+		z.LogChangeValue("embedding", z.Vector, o.Vector)
+		z.Vector = *o.Vector
+	}
+	if o.Model != nil && z.Model != *o.Model {
+		z.LogChangeValue("model", z.Model, o.Model)
+		z.Model = *o.Model
+	}
+	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
+		z.SetChange("meta")
+	}
+}
+func (in *MemoryVectorBasic) MetaAddKVs(args ...any) *MemoryVectorBasic {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+func (in *MemoryVectorSet) MetaAddKVs(args ...any) *MemoryVectorSet {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+
+// consts of ToolCall 工具调用
+const (
+	ToolCallTable = "convo_tool_call"
+	ToolCallAlias = "tc"
+	ToolCallLabel = "toolCall"
+	ToolCallTypID = "convoToolCall"
+)
+
+// ToolCall 工具调用 审计记录
+type ToolCall struct {
+	comm.BaseModel `bun:"table:convo_tool_call,alias:tc" json:"-"`
+
+	comm.DefaultModel
+
+	ToolCallBasic
+
+	comm.MetaField
+} // @name convoToolCall
+
+type ToolCallBasic struct {
+	// 会话编号
+	SessionID oid.OID `bson:"session_id" bun:",notnull" extensions:"x-order=A" json:"session" pg:",notnull" swaggertype:"string"`
+	// 所有人编号
+	OwnerID oid.OID `bun:"owner_id,notnull,type:bigint" extensions:"x-order=B" json:"ownerID" pg:"owner_id,notnull,type:bigint" swaggertype:"string"`
+	// 工具名
+	ToolName string `bun:",notnull,type:varchar(125)" extensions:"x-order=C" form:"toolName" json:"toolName" pg:",notnull,type:varchar(125)"`
+	// 来源 builtin, mcp:{server}, capability
+	Source string `bun:",notnull,type:varchar(125)" extensions:"x-order=D" form:"source" json:"source" pg:",notnull,type:varchar(125)"`
+	// 参数
+	Arguments map[string]any `bun:"args,notnull,type:jsonb,default:'{}'" extensions:"x-order=E" json:"args,omitempty" pg:"args,notnull,type:jsonb,default:'{}'"`
+	// 状态
+	//  * `ok` - 成功
+	//  * `failed` - 失败
+	//  * `error` - 异常
+	Status ToolCallStatus `bun:",notnull,type:smallint" enums:"ok,failed,error" extensions:"x-order=F" form:"status" json:"status" pg:",notnull,type:smallint" swaggertype:"string"`
+	// 结果 截断
+	Result string `bun:",notnull,type:text" extensions:"x-order=G" form:"result" json:"result" pg:",notnull,type:text"`
+	// 耗时 毫秒
+	Latency int `bun:",notnull,type:int" extensions:"x-order=H" form:"latency" json:"latency" pg:",notnull,type:int"`
+	// 错误
+	Error string `bun:",notnull,type:text" extensions:"x-order=I" form:"error" json:"error,omitempty" pg:",notnull,type:text"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
+} // @name convoToolCallBasic
+
+type ToolCalls []ToolCall
+
+// Creating function call to it's inner fields defined hooks
+func (z *ToolCall) Creating() error {
+	if z.IsZeroID() {
+		z.SetID(oid.NewID(oid.OtEvent))
+	}
+
+	return z.DefaultModel.Creating()
+}
+func NewToolCallWithBasic(in ToolCallBasic) *ToolCall {
+	obj := &ToolCall{
+		ToolCallBasic: in,
+	}
+	_ = obj.MetaUp(in.MetaDiff)
+	return obj
+}
+func NewToolCallWithID(id any) *ToolCall {
+	obj := new(ToolCall)
+	_ = obj.SetID(id)
+	return obj
+}
+func (_ *ToolCall) IdentityLabel() string { return ToolCallLabel }
+func (_ *ToolCall) IdentityModel() string { return ToolCallTypID }
+func (_ *ToolCall) IdentityTable() string { return ToolCallTable }
+func (_ *ToolCall) IdentityAlias() string { return ToolCallAlias }
+
+type ToolCallSet struct {
+	// for meta update
+	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
+} // @name convoToolCallSet
+
+func (z *ToolCall) SetWith(o ToolCallSet) {
+	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
+		z.SetChange("meta")
+	}
+}
+func (in *ToolCallBasic) MetaAddKVs(args ...any) *ToolCallBasic {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+func (in *ToolCallSet) MetaAddKVs(args ...any) *ToolCallSet {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
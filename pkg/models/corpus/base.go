@@ -3,12 +3,13 @@ package corpus
 import "strings"
 
 const (
-	VectorLen = 1024
-
 	PrefixQ = "Q:"
 	PrefixA = "A:"
 )
 
+// MetaKeyURL 文档元数据中来源地址的键
+const MetaKeyURL = "url"
+
 // Vector is the vector type for document embedding
 type Vector []float32
 
@@ -23,16 +23,25 @@ type Document struct {
 
 	DocumentBasic
 
+	// 相似度 仅用于检索结果
+	Similarity float32 `bun:"-" extensions:"x-order=F" json:"similarity,omitempty" pg:"-"`
+
 	comm.MetaField
+
+	comm.TextSearchField
 } // @name corpusDocument
 
 type DocumentBasic struct {
 	// 主标题 名称
-	Title string `bun:",notnull,type:text,unique:corpus_title_heading_key" extensions:"x-order=A" form:"title" json:"title" pg:",notnull,type:text,unique:corpus_title_heading_key"`
+	Title string `bun:",notnull,type:text,unique:corpus_collection_title_heading_key" extensions:"x-order=A" form:"title" json:"title" pg:",notnull,type:text,unique:corpus_collection_title_heading_key"`
 	// 小节标题 属性 类别
-	Heading string `bun:",notnull,type:text,unique:corpus_title_heading_key" extensions:"x-order=B" form:"heading" json:"heading" pg:",notnull,type:text,unique:corpus_title_heading_key"`
+	Heading string `bun:",notnull,type:text,unique:corpus_collection_title_heading_key" extensions:"x-order=B" form:"heading" json:"heading" pg:",notnull,type:text,unique:corpus_collection_title_heading_key"`
 	// 内容 值
 	Content string `bun:",notnull,type:text" extensions:"x-order=C" form:"content" json:"content" pg:",notnull,type:text"`
+	// 来源文档编号 分块导入时有值
+	SourceID oid.OID `bun:"source_id,notnull" extensions:"x-order=D" json:"sourceID,omitempty" pg:"source_id,notnull" swaggertype:"string"`
+	// 所属集合编号 为零时属于公开的默认集合
+	CollectionID oid.OID `bun:"collection_id,notnull,unique:corpus_collection_title_heading_key" extensions:"x-order=E" json:"collectionID,omitempty" pg:"collection_id,notnull,unique:corpus_collection_title_heading_key" swaggertype:"string"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
 } // @name corpusDocumentBasic
@@ -71,6 +80,8 @@ type DocumentSet struct {
 	Heading *string `extensions:"x-order=B" json:"heading"`
 	// 内容 值
 	Content *string `extensions:"x-order=C" json:"content"`
+	// 所属集合编号 为零时属于公开的默认集合
+	CollectionID *string `extensions:"x-order=D" json:"collectionID,omitempty"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
 } // @name corpusDocumentSet
@@ -88,6 +99,12 @@ func (z *Document) SetWith(o DocumentSet) {
 		z.LogChangeValue("content", z.Content, o.Content)
 		z.Content = *o.Content
 	}
+	if o.CollectionID != nil {
+		if id := oid.Cast(*o.CollectionID); z.CollectionID != id {
+			z.LogChangeValue("collection_id", z.CollectionID, id)
+			z.CollectionID = id
+		}
+	}
 	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
 		z.SetChange("meta")
 	}
@@ -101,6 +118,301 @@ func (in *DocumentSet) MetaAddKVs(args ...any) *DocumentSet {
 	return in
 }
 
+// consts of Source 来源文档
+const (
+	SourceTable = "corpus_source"
+	SourceAlias = "cs"
+	SourceLabel = "source"
+	SourceTypID = "corpusSource"
+)
+
+// Source 来源文档 分块导入的原始文件
+type Source struct {
+	comm.BaseModel `bun:"table:corpus_source,alias:cs" json:"-"`
+
+	comm.DefaultModel
+
+	SourceBasic
+
+	comm.MetaField
+} // @name corpusSource
+
+type SourceBasic struct {
+	// 名称 通常为文件名，同名再次导入时替换其分块
+	Name string `binding:"required" bun:",notnull,unique,type:text" extensions:"x-order=A" form:"name" json:"name" pg:",notnull,unique,type:text"`
+	// 标题 取自文档一级标题或文件名
+	Title string `bun:",notnull,type:text" extensions:"x-order=B" form:"title" json:"title" pg:",notnull,type:text"`
+	// 格式 md, html, pdf, docx, txt
+	Format string `bun:",notnull" extensions:"x-order=C" form:"format" json:"format" pg:",notnull"`
+	// 文件大小 字节
+	Size int `bun:",notnull" extensions:"x-order=D" form:"size" json:"size" pg:",notnull"`
+	// 内容摘要 SHA1，未变化时不重新导入
+	Hash string `bun:",notnull" extensions:"x-order=E" form:"hash" json:"hash" pg:",notnull"`
+	// 分块数量
+	Chunks int `bun:",notnull" extensions:"x-order=F" form:"chunks" json:"chunks" pg:",notnull"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
+} // @name corpusSourceBasic
+
+type Sources []Source
+
+// Creating function call to it's inner fields defined hooks
+func (z *Source) Creating() error {
+	if z.IsZeroID() {
+		z.SetID(oid.NewID(oid.OtFile))
+	}
+
+	return z.DefaultModel.Creating()
+}
+func NewSourceWithBasic(in SourceBasic) *Source {
+	obj := &Source{
+		SourceBasic: in,
+	}
+	_ = obj.MetaUp(in.MetaDiff)
+	return obj
+}
+func NewSourceWithID(id any) *Source {
+	obj := new(Source)
+	_ = obj.SetID(id)
+	return obj
+}
+func (_ *Source) IdentityLabel() string { return SourceLabel }
+func (_ *Source) IdentityModel() string { return SourceTypID }
+func (_ *Source) IdentityTable() string { return SourceTable }
+func (_ *Source) IdentityAlias() string { return SourceAlias }
+
+type SourceSet struct {
+	// 名称 通常为文件名，同名再次导入时替换其分块
+	Name *string `extensions:"x-order=A" json:"name"`
+	// 标题 取自文档一级标题或文件名
+	Title *string `extensions:"x-order=B" json:"title"`
+	// 格式 md, html, pdf, docx, txt
+	Format *string `extensions:"x-order=C" json:"format"`
+	// 文件大小 字节
+	Size *int `extensions:"x-order=D" json:"size"`
+	// 内容摘要 SHA1，未变化时不重新导入
+	Hash *string `extensions:"x-order=E" json:"hash"`
+	// 分块数量
+	Chunks *int `extensions:"x-order=F" json:"chunks"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
+} // @name corpusSourceSet
+
+func (z *Source) SetWith(o SourceSet) {
+	if o.Name != nil && z.Name != *o.Name {
+		z.LogChangeValue("name", z.Name, o.Name)
+		z.Name = *o.Name
+	}
+	if o.Title != nil && z.Title != *o.Title {
+		z.LogChangeValue("title", z.Title, o.Title)
+		z.Title = *o.Title
+	}
+	if o.Format != nil && z.Format != *o.Format {
+		z.LogChangeValue("format", z.Format, o.Format)
+		z.Format = *o.Format
+	}
+	if o.Size != nil && z.Size != *o.Size {
+		z.LogChangeValue("size", z.Size, o.Size)
+		z.Size = *o.Size
+	}
+	if o.Hash != nil && z.Hash != *o.Hash {
+		z.LogChangeValue("hash", z.Hash, o.Hash)
+		z.Hash = *o.Hash
+	}
+	if o.Chunks != nil && z.Chunks != *o.Chunks {
+		z.LogChangeValue("chunks", z.Chunks, o.Chunks)
+		z.Chunks = *o.Chunks
+	}
+	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
+		z.SetChange("meta")
+	}
+}
+func (in *SourceBasic) MetaAddKVs(args ...any) *SourceBasic {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+func (in *SourceSet) MetaAddKVs(args ...any) *SourceSet {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+
+// consts of Collection 文档集合
+const (
+	CollectionTable = "corpus_collection"
+	CollectionAlias = "cc"
+	CollectionLabel = "collection"
+	CollectionTypID = "corpusCollection"
+)
+
+// Collection 文档集合 按角色、用户或渠道限定可见范围，均未设置时公开
+type Collection struct {
+	comm.BaseModel `bun:"table:corpus_collection,alias:cc" json:"-"`
+
+	comm.DefaultModel
+
+	CollectionBasic
+
+	comm.MetaField
+} // @name corpusCollection
+
+type CollectionBasic struct {
+	// 名称 唯一，导入导出时用于指定集合
+	Name string `binding:"required" bun:",notnull,unique,type:text" extensions:"x-order=A" form:"name" json:"name" pg:",notnull,unique,type:text"`
+	// 标题
+	Title string `bun:",notnull,type:text" extensions:"x-order=B" form:"title" json:"title" pg:",notnull,type:text"`
+	// 可见的角色
+	Roles []string `bun:",notnull,type:jsonb,default:'[]'" extensions:"x-order=C" json:"roles,omitempty" pg:",notnull,type:jsonb,default:'[]'"`
+	// 可见的用户 UID
+	Users []string `bun:",notnull,type:jsonb,default:'[]'" extensions:"x-order=D" json:"users,omitempty" pg:",notnull,type:jsonb,default:'[]'"`
+	// 可见的渠道 如 wecom，Web 不属于任何渠道
+	Channels []string `bun:",notnull,type:jsonb,default:'[]'" extensions:"x-order=E" json:"channels,omitempty" pg:",notnull,type:jsonb,default:'[]'"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
+} // @name corpusCollectionBasic
+
+type Collections []Collection
+
+// Creating function call to it's inner fields defined hooks
+func (z *Collection) Creating() error {
+	if z.IsZeroID() {
+		z.SetID(oid.NewID(oid.OtForm))
+	}
+
+	return z.DefaultModel.Creating()
+}
+func NewCollectionWithBasic(in CollectionBasic) *Collection {
+	obj := &Collection{
+		CollectionBasic: in,
+	}
+	_ = obj.MetaUp(in.MetaDiff)
+	return obj
+}
+func NewCollectionWithID(id any) *Collection {
+	obj := new(Collection)
+	_ = obj.SetID(id)
+	return obj
+}
+func (_ *Collection) IdentityLabel() string { return CollectionLabel }
+func (_ *Collection) IdentityModel() string { return CollectionTypID }
+func (_ *Collection) IdentityTable() string { return CollectionTable }
+func (_ *Collection) IdentityAlias() string { return CollectionAlias }
+
+type CollectionSet struct {
+	// 名称 唯一，导入导出时用于指定集合
+	Name *string `extensions:"x-order=A" json:"name"`
+	// 标题
+	Title *string `extensions:"x-order=B" json:"title"`
+	// 可见的角色
+	Roles *[]string `extensions:"x-order=C" json:"roles,omitempty"`
+	// 可见的用户 UID
+	Users *[]string `extensions:"x-order=D" json:"users,omitempty"`
+	// 可见的渠道 如 wecom，Web 不属于任何渠道
+	Channels *[]string `extensions:"x-order=E" json:"channels,omitempty"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
+} // @name corpusCollectionSet
+
+func (z *Collection) SetWith(o CollectionSet) {
+	if o.Name != nil && z.Name != *o.Name {
+		z.LogChangeValue("name", z.Name, o.Name)
+		z.Name = *o.Name
+	}
+	if o.Title != nil && z.Title != *o.Title {
+		z.LogChangeValue("title", z.Title, o.Title)
+		z.Title = *o.Title
+	}
+	if o.Roles != nil {
+		z.LogChangeValue("roles", z.Roles, o.Roles)
+		z.Roles = *o.Roles
+	}
+	if o.Users != nil {
+		z.LogChangeValue("users", z.Users, o.Users)
+		z.Users = *o.Users
+	}
+	if o.Channels != nil {
+		z.LogChangeValue("channels", z.Channels, o.Channels)
+		z.Channels = *o.Channels
+	}
+	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
+		z.SetChange("meta")
+	}
+}
+func (in *CollectionBasic) MetaAddKVs(args ...any) *CollectionBasic {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+func (in *CollectionSet) MetaAddKVs(args ...any) *CollectionSet {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+
+// consts of Revision 文档修订
+const (
+	RevisionTable = "corpus_document_revision"
+	RevisionAlias = "cdr"
+	RevisionLabel = "revision"
+	RevisionTypID = "corpusRevision"
+)
+
+// Revision 文档修订 文档每次内容变化后的快照
+type Revision struct {
+	comm.BaseModel `bun:"table:corpus_document_revision,alias:cdr" json:"-"`
+
+	comm.DefaultModel
+
+	RevisionBasic
+
+	comm.MetaField
+} // @name corpusRevision
+
+type RevisionBasic struct {
+	// 文档编号
+	DocID oid.OID `bun:"doc_id,notnull" extensions:"x-order=A" json:"docID" pg:"doc_id,notnull" swaggertype:"string"`
+	// 主标题
+	Title string `bun:",notnull,type:text" extensions:"x-order=B" form:"title" json:"title" pg:",notnull,type:text"`
+	// 小节标题
+	Heading string `bun:",notnull,type:text" extensions:"x-order=C" form:"heading" json:"heading" pg:",notnull,type:text"`
+	// 内容
+	Content string `bun:",notnull,type:text" extensions:"x-order=D" form:"content" json:"content" pg:",notnull,type:text"`
+	// 作者 修改者的用户名，命令行导入时为空
+	Author string `bun:",notnull,type:text" extensions:"x-order=E" form:"author" json:"author" pg:",notnull,type:text"`
+	// 来源 import, api, tool, restore, baseline
+	Source string `bun:",notnull,type:text" extensions:"x-order=F" form:"source" json:"source" pg:",notnull,type:text"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
+} // @name corpusRevisionBasic
+
+type Revisions []Revision
+
+// Creating function call to it's inner fields defined hooks
+func (z *Revision) Creating() error {
+	if z.IsZeroID() {
+		z.SetID(oid.NewID(oid.OtEvent))
+	}
+
+	return z.DefaultModel.Creating()
+}
+func NewRevisionWithBasic(in RevisionBasic) *Revision {
+	obj := &Revision{
+		RevisionBasic: in,
+	}
+	_ = obj.MetaUp(in.MetaDiff)
+	return obj
+}
+func NewRevisionWithID(id any) *Revision {
+	obj := new(Revision)
+	_ = obj.SetID(id)
+	return obj
+}
+func (_ *Revision) IdentityLabel() string { return RevisionLabel }
+func (_ *Revision) IdentityModel() string { return RevisionTypID }
+func (_ *Revision) IdentityTable() string { return RevisionTable }
+func (_ *Revision) IdentityAlias() string { return RevisionAlias }
+func (in *RevisionBasic) MetaAddKVs(args ...any) *RevisionBasic {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+
 // consts of DocVector 文档向量
 const (
 	DocVectorTable = "corpus_vector_400"
@@ -109,7 +421,7 @@ const (
 	DocVectorTypID = "corpusDocVector"
 )
 
-// DocVector 文档向量 400=1024, 600=1536
+// DocVector 文档向量 不同嵌入模型和维度的向量并存，以 model 区分
 type DocVector struct {
 	comm.BaseModel `bun:"table:corpus_vector_400,alias:cv" json:"-"`
 
@@ -128,8 +440,12 @@ type DocVectorBasic struct {
 	DocID oid.OID `bun:"doc_id,notnull" extensions:"x-order=A" json:"docID" pg:"doc_id,notnull" swaggertype:"string"`
 	// 主题 由名称+属性组成
 	Subject string `bun:"subject,notnull,type:text" extensions:"x-order=B" form:"subject" json:"subject" pg:"subject,notnull,type:text"`
-	// 向量值 长为1024的浮点数集
-	Vector Vector `bun:"embedding,type:vector(1024)" extensions:"x-order=C" json:"vector,omitempty" pg:"embedding,type:vector(1024)"`
+	// 向量值 维度由嵌入模型决定
+	Vector Vector `bun:"embedding,type:vector" extensions:"x-order=C" json:"vector,omitempty" pg:"embedding,type:vector"`
+	// 嵌入模型
+	Model string `bun:"model,notnull,type:text" extensions:"x-order=D" json:"model" pg:"model,notnull,type:text"`
+	// 内容摘要 标题、小节和内容的 sha1，用于跳过未变化的文档
+	ContentHash string `bun:"content_hash,notnull,type:text" extensions:"x-order=E" json:"contentHash,omitempty" pg:"content_hash,notnull,type:text"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
 } // @name corpusDocVectorBasic
@@ -164,8 +480,12 @@ func (_ *DocVector) IdentityAlias() string { return DocVectorAlias }
 type DocVectorSet struct {
 	// 主题 由名称+属性组成
 	Subject *string `extensions:"x-order=A" json:"subject"`
-	// 向量值 长为1024的浮点数集
+	// 向量值 维度由嵌入模型决定
 	Vector *Vector `extensions:"x-order=B" json:"vector,omitempty"`
+	// 嵌入模型
+	Model *string `extensions:"x-order=C" json:"model"`
+	// 内容摘要 标题、小节和内容的 sha1，用于跳过未变化的文档
+	ContentHash *string `extensions:"x-order=D" json:"contentHash,omitempty"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
 } // @name corpusDocVectorSet
@@ -179,6 +499,14 @@ func (z *DocVector) SetWith(o DocVectorSet) {
 		z.LogChangeValue("embedding", z.Vector, o.Vector)
 		z.Vector = *o.Vector
 	}
+	if o.Model != nil && z.Model != *o.Model {
+		z.LogChangeValue("model", z.Model, o.Model)
+		z.Model = *o.Model
+	}
+	if o.ContentHash != nil && z.ContentHash != *o.ContentHash {
+		z.LogChangeValue("content_hash", z.ContentHash, o.ContentHash)
+		z.ContentHash = *o.ContentHash
+	}
 	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
 		z.SetChange("meta")
 	}
@@ -30,3 +30,15 @@ A: I am
 	}
 
 }
+
+func TestDocumentContentHash(t *testing.T) {
+	doc := &Document{DocumentBasic: DocumentBasic{Title: "Handbook", Heading: "Leave", Content: "Ten days"}}
+	hash := doc.GetContentHash()
+	assert.Len(t, hash, 40)
+
+	same := &Document{DocumentBasic: DocumentBasic{Title: "Handbook", Heading: "Leave", Content: "Ten days"}}
+	assert.Equal(t, hash, same.GetContentHash())
+
+	doc.Content = "Twelve days"
+	assert.NotEqual(t, hash, doc.GetContentHash())
+}
@@ -1,6 +1,8 @@
 package corpus
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
@@ -14,10 +16,21 @@ func (z *Document) GetSubject() string {
 	return fmt.Sprintf("%s %s", z.Title, z.Heading)
 }
 
+// GetKeywordText returns the text for full-text search vector (ts_vec)
+func (z *Document) GetKeywordText() string {
+	return z.Title + " " + z.Heading + " " + z.Content
+}
+
 func (z *Document) GetFullText() string {
 	return fmt.Sprintf("%s\n%s\n%s", z.Title, z.Heading, z.Content)
 }
 
+// GetContentHash returns the sha1 of title, heading and content, recorded in DocVector
+func (z *Document) GetContentHash() string {
+	sum := sha1.Sum([]byte(z.GetFullText()))
+	return hex.EncodeToString(sum[:])
+}
+
 // IDs returns all document IDs in the document list
 func (z Documents) IDs() (out oid.OIDs) {
 	for _, doc := range z {
@@ -28,13 +41,29 @@ func (z Documents) IDs() (out oid.OIDs) {
 
 // MarkdownText converts document list to Markdown format text for LLM context
 func (z Documents) MarkdownText() string {
+	return z.MarkdownTextWithRefs(nil)
+}
+
+// MarkdownTextWithRefs 同 MarkdownText，refs 与文档一一对应时为每篇文档加上引用标记 [^n]，
+// 并提示模型在回答中以该标记注明出处
+func (z Documents) MarkdownTextWithRefs(refs []int) string {
 	if len(z) == 0 {
 		return "No relevant information found in the knowledge base."
 	}
+	if len(refs) != len(z) {
+		refs = nil
+	}
 	var sb strings.Builder
 	sb.WriteString(fmt.Sprintf("Found %d relevant documents in the knowledge base:\n\n", len(z)))
-	for _, doc := range z {
-		sb.WriteString("---\nID: ")
+	if refs != nil {
+		sb.WriteString("When using information from these documents, cite the source by appending its marker, e.g. [^1], right after the relevant sentence. Only use the markers listed below.\n\n")
+	}
+	for i, doc := range z {
+		sb.WriteString("---\n")
+		if refs != nil {
+			sb.WriteString(fmt.Sprintf("Marker: [^%d]\n", refs[i]))
+		}
+		sb.WriteString("ID: ")
 		sb.WriteString(doc.StringID())
 		sb.WriteString("\n\n## ")
 		sb.WriteString(doc.Title)
@@ -47,6 +76,16 @@ func (z Documents) MarkdownText() string {
 	return sb.String()
 }
 
+// URL returns the source URL of the document from meta, if any
+func (z *Document) URL() string {
+	if v, ok := z.MetaGet(MetaKeyURL); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
 // Headings returns all document headings in the document list
 func (z Documents) Headings() []string {
 	headings := make([]string, len(z))
@@ -81,3 +120,34 @@ func (z DocMatches) Subjects(n int) (out []string) {
 	}
 	return
 }
+
+// 文档修订的来源
+const (
+	RevisionSourceAPI      = "api"
+	RevisionSourceImport   = "import"
+	RevisionSourceTool     = "tool"
+	RevisionSourceRestore  = "restore"
+	RevisionSourceBaseline = "baseline" // 启用修订记录前已存在的内容
+)
+
+// NewRevisionOf returns a revision snapshot of the document
+func NewRevisionOf(doc *Document, author, source string) *Revision {
+	return NewRevisionWithBasic(RevisionBasic{
+		DocID:   doc.ID,
+		Title:   doc.Title,
+		Heading: doc.Heading,
+		Content: doc.Content,
+		Author:  author,
+		Source:  source,
+	})
+}
+
+// GetFullText returns the snapshot text in the same layout as Document.GetFullText
+func (z *Revision) GetFullText() string {
+	return fmt.Sprintf("%s\n%s\n%s", z.Title, z.Heading, z.Content)
+}
+
+// SameAs reports whether the snapshot has the same title, heading and content as the document
+func (z *Revision) SameAs(doc *Document) bool {
+	return z.Title == doc.Title && z.Heading == doc.Heading && z.Content == doc.Content
+}
@@ -0,0 +1,77 @@
+package mcps
+
+// SecretMask 接口返回时用于替代敏感值的占位
+const SecretMask = "******"
+
+// Credential 服务器认证凭据
+//
+// Headers 的值、Token 和 ClientSecret 视为敏感字段，入库前加密，接口返回时以 SecretMask 替代。
+type Credential struct {
+	// 静态头，如 X-Api-Key (AuthType 为 header 时有效)
+	Headers map[string]string `json:"headers,omitempty"`
+	// Bearer 令牌 (AuthType 为 bearer 时有效)
+	Token string `json:"token,omitempty"`
+	// OAuth2 令牌地址 (AuthType 为 oAuth2 时有效，下同)
+	TokenURL string `json:"tokenURL,omitempty"`
+	// OAuth2 客户端ID
+	ClientID string `json:"clientID,omitempty"`
+	// OAuth2 客户端密钥
+	ClientSecret string `json:"clientSecret,omitempty"`
+	// OAuth2 授权范围
+	Scopes []string `json:"scopes,omitempty"`
+} // @name mcpsCredential
+
+// WalkSecrets 遍历所有非空敏感字段，fn 返回的新值会写回
+func (c *Credential) WalkSecrets(fn func(s string) (string, error)) error {
+	var err error
+	for k, v := range c.Headers {
+		if len(v) == 0 {
+			continue
+		}
+		if c.Headers[k], err = fn(v); err != nil {
+			return err
+		}
+	}
+	if len(c.Token) > 0 {
+		if c.Token, err = fn(c.Token); err != nil {
+			return err
+		}
+	}
+	if len(c.ClientSecret) > 0 {
+		if c.ClientSecret, err = fn(c.ClientSecret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Mask 以 SecretMask 替换所有敏感字段
+func (c *Credential) Mask() {
+	_ = c.WalkSecrets(func(string) (string, error) { return SecretMask, nil })
+}
+
+// HasMasked 是否有敏感字段为占位值，即调用方未修改原值
+func (c *Credential) HasMasked() (yes bool) {
+	_ = c.WalkSecrets(func(s string) (string, error) {
+		if s == SecretMask {
+			yes = true
+		}
+		return s, nil
+	})
+	return
+}
+
+// Restore 将占位值还原为 old 中对应的原值
+func (c *Credential) Restore(old Credential) {
+	for k, v := range c.Headers {
+		if v == SecretMask {
+			c.Headers[k] = old.Headers[k]
+		}
+	}
+	if c.Token == SecretMask {
+		c.Token = old.Token
+	}
+	if c.ClientSecret == SecretMask {
+		c.ClientSecret = old.ClientSecret
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	comm "github.com/cupogo/andvari/models/comm"
 	oid "github.com/cupogo/andvari/models/oid"
+	corpus "github.com/liut/morign/pkg/models/corpus"
 )
 
 // MCP 传输类型
@@ -143,6 +144,52 @@ func (z HeaderCate) MarshalText() ([]byte, error) {
 	return []byte(z.String()), nil
 }
 
+// 认证类型
+type AuthType int8
+
+const (
+	AuthTypeNone   AuthType = 0 + iota //  0 无
+	AuthTypeHeader                     //  1 静态头
+	AuthTypeBearer                     //  2 Bearer 令牌
+	AuthTypeOAuth2                     //  3 OAuth2 客户端凭证
+)
+
+func (z *AuthType) Decode(s string) error {
+	switch s {
+	case "0", "none", "None":
+		*z = AuthTypeNone
+	case "1", "header", "Header":
+		*z = AuthTypeHeader
+	case "2", "bearer", "Bearer":
+		*z = AuthTypeBearer
+	case "3", "oAuth2", "OAuth2", "oauth2":
+		*z = AuthTypeOAuth2
+	default:
+		return fmt.Errorf("invalid authType: %q", s)
+	}
+	return nil
+}
+func (z *AuthType) UnmarshalText(b []byte) error {
+	return z.Decode(string(b))
+}
+func (z AuthType) String() string {
+	switch z {
+	case AuthTypeNone:
+		return "none"
+	case AuthTypeHeader:
+		return "header"
+	case AuthTypeBearer:
+		return "bearer"
+	case AuthTypeOAuth2:
+		return "oAuth2"
+	default:
+		return fmt.Sprintf("authType %d", int8(z))
+	}
+}
+func (z AuthType) MarshalText() ([]byte, error) {
+	return []byte(z.String()), nil
+}
+
 // consts of Server 服务器
 const (
 	ServerTable = "mcp_server"
@@ -160,7 +207,7 @@ type Server struct {
 	ServerBasic
 
 	// 定制头函数
-	HeaderFunc HeaderFunc `bson:"-" bun:"-" extensions:"x-order=I" json:"-" pg:"-"`
+	HeaderFunc HeaderFunc `bson:"-" bun:"-" extensions:"x-order=K" json:"-" pg:"-"`
 
 	comm.MetaField
 } // @name mcpsServer
@@ -192,6 +239,14 @@ type ServerBasic struct {
 	//  * `ownerID`
 	//  * `sessionID`
 	HeaderCate HeaderCate `bson:"headerCate" bun:",notnull,type:smallint" enums:"authorization,ownerID,sessionID" extensions:"x-order=H" json:"headerCate" pg:",notnull,type:smallint" swaggertype:"string"`
+	// 认证类型
+	//  * `none` - 无
+	//  * `header` - 静态头
+	//  * `bearer` - Bearer 令牌
+	//  * `oAuth2` - OAuth2 客户端凭证
+	AuthType AuthType `bson:"authType" bun:",notnull,type:smallint" enums:"none,header,bearer,oAuth2" extensions:"x-order=I" form:"authType" json:"authType" pg:",notnull,type:smallint" swaggertype:"string"`
+	// 认证凭据 敏感字段加密存储
+	Credential Credential `bson:"credential" bun:",notnull,type:jsonb,default:'{}'" extensions:"x-order=J" json:"credential" pg:",notnull,type:jsonb,default:'{}'"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
 } // @name mcpsServerBasic
@@ -250,6 +305,14 @@ type ServerSet struct {
 	//  * `ownerID`
 	//  * `sessionID`
 	HeaderCate *HeaderCate `enums:"authorization,ownerID,sessionID" extensions:"x-order=H" json:"headerCate" swaggertype:"string"`
+	// 认证类型
+	//  * `none` - 无
+	//  * `header` - 静态头
+	//  * `bearer` - Bearer 令牌
+	//  * `oAuth2` - OAuth2 客户端凭证
+	AuthType *AuthType `enums:"none,header,bearer,oAuth2" extensions:"x-order=I" json:"authType" swaggertype:"string"`
+	// 认证凭据 敏感字段加密存储
+	Credential *Credential `extensions:"x-order=J" json:"credential"`
 	// for meta update
 	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
 } // @name mcpsServerSet
@@ -287,6 +350,14 @@ func (z *Server) SetWith(o ServerSet) {
 		z.LogChangeValue("header_cate", z.HeaderCate, o.HeaderCate)
 		z.HeaderCate = *o.HeaderCate
 	}
+	if o.AuthType != nil && z.AuthType != *o.AuthType {
+		z.LogChangeValue("auth_type", z.AuthType, o.AuthType)
+		z.AuthType = *o.AuthType
+	}
+	if o.Credential != nil {
+		z.SetChange("credential")
+		z.Credential = *o.Credential
+	}
 	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
 		z.SetChange("meta")
 	}
@@ -299,3 +370,105 @@ func (in *ServerSet) MetaAddKVs(args ...any) *ServerSet {
 	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
 	return in
 }
+
+// consts of ToolVector 工具向量
+const (
+	ToolVectorTable = "mcp_tool_vector"
+	ToolVectorAlias = "tv"
+	ToolVectorLabel = "toolVector"
+	ToolVectorTypID = "mcpsToolVector"
+)
+
+// ToolVector 工具向量
+type ToolVector struct {
+	comm.BaseModel `bun:"table:mcp_tool_vector,alias:tv" json:"-"`
+
+	comm.DefaultModel
+
+	ToolVectorBasic
+
+	comm.MetaField
+} // @name mcpsToolVector
+
+type ToolVectorBasic struct {
+	// 工具名 MCP 工具为 server-tool 形式
+	Name string `bson:"name" bun:",notnull,type:varchar(125),unique:mcp_tool_vector_name_model_key" extensions:"x-order=A" form:"name" json:"name" pg:",notnull,type:varchar(125),unique:mcp_tool_vector_name_model_key"`
+	// 主题 基于名称和描述生成
+	Subject string `bson:"subject" bun:"subject,notnull,type:text" extensions:"x-order=B" form:"subject" json:"subject" pg:"subject,notnull,type:text"`
+	// 语义向量 维度由嵌入模型决定
+	Vector corpus.Vector `bson:"vector" bun:"embedding,notnull,type:vector" extensions:"x-order=C" json:"vector" pg:"embedding,notnull,type:vector"`
+	// 嵌入模型
+	Model string `bson:"model" bun:"model,notnull,type:text,unique:mcp_tool_vector_name_model_key" extensions:"x-order=D" form:"model" json:"model" pg:"model,notnull,type:text,unique:mcp_tool_vector_name_model_key"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `bson:"-" bun:"-" json:"metaUp,omitempty" pg:"-" swaggerignore:"true"`
+} // @name mcpsToolVectorBasic
+
+type ToolVectors []ToolVector
+
+// Creating function call to it's inner fields defined hooks
+func (z *ToolVector) Creating() error {
+	if z.IsZeroID() {
+		z.SetID(oid.NewID(oid.OtEvent))
+	}
+
+	return z.DefaultModel.Creating()
+}
+func NewToolVectorWithBasic(in ToolVectorBasic) *ToolVector {
+	obj := &ToolVector{
+		ToolVectorBasic: in,
+	}
+	_ = obj.MetaUp(in.MetaDiff)
+	return obj
+}
+func NewToolVectorWithID(id any) *ToolVector {
+	obj := new(ToolVector)
+	_ = obj.SetID(id)
+	return obj
+}
+func (_ *ToolVector) IdentityLabel() string { return ToolVectorLabel }
+func (_ *ToolVector) IdentityModel() string { return ToolVectorTypID }
+func (_ *ToolVector) IdentityTable() string { return ToolVectorTable }
+func (_ *ToolVector) IdentityAlias() string { return ToolVectorAlias }
+
+type ToolVectorSet struct {
+	// 工具名 MCP 工具为 server-tool 形式
+	Name *string `extensions:"x-order=A" json:"name"`
+	// 主题 基于名称和描述生成
+	Subject *string `extensions:"x-order=B" json:"subject"`
+	// 语义向量 维度由嵌入模型决定
+	Vector *corpus.Vector `extensions:"x-order=C" json:"vector"`
+	// 嵌入模型
+	Model *string `extensions:"x-order=D" json:"model"`
+	// for meta update
+	MetaDiff *comm.MetaDiff `json:"metaUp,omitempty" swaggerignore:"true"`
+} // @name mcpsToolVectorSet
+
+func (z *ToolVector) SetWith(o ToolVectorSet) {
+	if o.Name != nil && z.Name != *o.Name {
+		z.LogChangeValue("name", z.Name, o.Name)
+		z.Name = *o.Name
+	}
+	if o.Subject != nil && z.Subject != *o.Subject {
+		z.LogChangeValue("subject", z.Subject, o.Subject)
+		z.Subject = *o.Subject
+	}
+	if o.Vector != nil {
+		z.LogChangeValue("embedding", z.Vector, o.Vector)
+		z.Vector = *o.Vector
+	}
+	if o.Model != nil && z.Model != *o.Model {
+		z.LogChangeValue("model", z.Model, o.Model)
+		z.Model = *o.Model
+	}
+	if o.MetaDiff != nil && z.MetaUp(o.MetaDiff) {
+		z.SetChange("meta")
+	}
+}
+func (in *ToolVectorBasic) MetaAddKVs(args ...any) *ToolVectorBasic {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
+func (in *ToolVectorSet) MetaAddKVs(args ...any) *ToolVectorSet {
+	in.MetaDiff = comm.MetaDiffAddKVs(in.MetaDiff, args...)
+	return in
+}
@@ -33,3 +33,13 @@ func ServerNameFromContext(ctx context.Context) string {
 	}
 	return ""
 }
+
+// ToolMatch 工具向量匹配结果
+type ToolMatch struct {
+	// 工具名
+	Name string `bson:"name" extensions:"x-order=A" json:"name"`
+	// 主题
+	Subject string `bson:"subject" extensions:"x-order=B" json:"subject"`
+	// 相似度
+	Similarity float32 `bson:"similarity" extensions:"x-order=C" json:"similarity"`
+}
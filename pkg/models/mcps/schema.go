@@ -0,0 +1,370 @@
+package mcps
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ArgsError 工具参数校验错误，消息直接返回给模型以便修正后重试
+type ArgsError struct {
+	Problems []string
+}
+
+func (e *ArgsError) Error() string {
+	return "invalid arguments: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateArgs 按 InputSchema 校验工具参数，返回转换后的新参数
+//
+// 安全的类型不匹配会被转换，如 "5" 转为整数、"true" 转为布尔、JSON 字符串转为数组或对象，
+// 数值统一为 float64，与 JSON 解码结果一致。可选参数为 null 时视为未提供。
+// 支持 type、properties、required、additionalProperties、items、enum、
+// minimum/maximum、minLength/maxLength 以及 anyOf/oneOf，其余关键字忽略。
+func ValidateArgs(schema map[string]any, args map[string]any) (map[string]any, error) {
+	if len(schema) == 0 {
+		return args, nil
+	}
+	if args == nil {
+		args = map[string]any{}
+	}
+	v := &argsValidator{}
+	out := v.value("", schema, args)
+	if len(v.problems) > 0 {
+		return args, &ArgsError{Problems: v.problems}
+	}
+	m, _ := out.(map[string]any)
+	return m, nil
+}
+
+type argsValidator struct {
+	problems []string
+}
+
+func (v *argsValidator) fail(path, format string, a ...any) {
+	if len(path) == 0 {
+		path = "arguments"
+	}
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, a...))
+}
+
+func (v *argsValidator) value(path string, schema map[string]any, val any) any {
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if branches := schemaList(schema[key]); len(branches) > 0 {
+			return v.union(path, branches, val)
+		}
+	}
+
+	types := schemaStrings(schema["type"])
+	if len(types) > 0 {
+		var ok bool
+		if val, ok = coerceType(types, val); !ok {
+			v.fail(path, "expected %s, got %s", strings.Join(types, " or "), describe(val))
+			return val
+		}
+	}
+
+	switch x := val.(type) {
+	case map[string]any:
+		return v.object(path, schema, x)
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			out := make([]any, len(x))
+			for i, item := range x {
+				out[i] = v.value(fmt.Sprintf("%s[%d]", path, i), items, item)
+			}
+			val = out
+		}
+	case string:
+		val = v.enum(path, schema, x)
+		if n, ok := schemaNumber(schema["minLength"]); ok && float64(len([]rune(x))) < n {
+			v.fail(path, "must be at least %v characters", n)
+		}
+		if n, ok := schemaNumber(schema["maxLength"]); ok && float64(len([]rune(x))) > n {
+			v.fail(path, "must be at most %v characters", n)
+		}
+	case float64:
+		val = v.enum(path, schema, x)
+		if n, ok := schemaNumber(schema["minimum"]); ok && x < n {
+			v.fail(path, "must be >= %v, got %v", n, x)
+		}
+		if n, ok := schemaNumber(schema["maximum"]); ok && x > n {
+			v.fail(path, "must be <= %v, got %v", n, x)
+		}
+	}
+	return val
+}
+
+// union 返回第一个校验通过的分支结果
+func (v *argsValidator) union(path string, branches []map[string]any, val any) any {
+	var last []string
+	for _, branch := range branches {
+		sub := &argsValidator{}
+		out := sub.value(path, branch, val)
+		if len(sub.problems) == 0 {
+			return out
+		}
+		last = sub.problems
+	}
+	v.problems = append(v.problems, last...)
+	return val
+}
+
+func (v *argsValidator) object(path string, schema map[string]any, obj map[string]any) map[string]any {
+	props, _ := schema["properties"].(map[string]any)
+	required := schemaStrings(schema["required"])
+	out := make(map[string]any, len(obj))
+	for key, item := range obj {
+		sub := joinPath(path, key)
+		ps, known := props[key].(map[string]any)
+		if !known {
+			if ap, ok := schema["additionalProperties"].(bool); ok && !ap && props != nil {
+				v.fail(sub, "unknown property, allowed: %s", strings.Join(sortedKeys(props), ", "))
+				continue
+			}
+			out[key] = item
+			continue
+		}
+		if item == nil && !slices.Contains(schemaStrings(ps["type"]), "null") {
+			// 可选参数为 null 视为未提供
+			continue
+		}
+		out[key] = v.value(sub, ps, item)
+	}
+	for _, key := range required {
+		if _, ok := out[key]; !ok {
+			v.fail(joinPath(path, key), "is required")
+		}
+	}
+	return out
+}
+
+// enum 校验枚举值，字符串忽略大小写匹配并转为规范值
+func (v *argsValidator) enum(path string, schema map[string]any, val any) any {
+	var options []any
+	switch x := schema["enum"].(type) {
+	case []any:
+		options = x
+	case []string:
+		for _, s := range x {
+			options = append(options, s)
+		}
+	}
+	if len(options) == 0 {
+		return val
+	}
+	for _, opt := range options {
+		if normalizeNumber(opt) == val {
+			return val
+		}
+	}
+	if s, ok := val.(string); ok {
+		for _, opt := range options {
+			if os, ok := opt.(string); ok && strings.EqualFold(os, strings.TrimSpace(s)) {
+				return os
+			}
+		}
+	}
+	b, _ := json.Marshal(options)
+	v.fail(path, "must be one of %s, got %s", b, describe(val))
+	return val
+}
+
+// coerceType 将值转换为允许的类型之一
+func coerceType(types []string, val any) (any, bool) {
+	val = normalizeNumber(val)
+	// 优先原样匹配
+	for _, t := range types {
+		if matchType(t, val) {
+			return val, true
+		}
+	}
+	for _, t := range types {
+		if out, ok := coerceTo(t, val); ok {
+			return out, true
+		}
+	}
+	return val, false
+}
+
+func matchType(t string, val any) bool {
+	switch t {
+	case "string":
+		_, ok := val.(string)
+		return ok
+	case "number":
+		_, ok := val.(float64)
+		return ok
+	case "integer":
+		f, ok := val.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := val.(bool)
+		return ok
+	case "array":
+		_, ok := val.([]any)
+		return ok
+	case "object":
+		_, ok := val.(map[string]any)
+		return ok
+	case "null":
+		return val == nil
+	}
+	return true
+}
+
+func coerceTo(t string, val any) (any, bool) {
+	switch t {
+	case "string":
+		switch x := val.(type) {
+		case float64:
+			return strconv.FormatFloat(x, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(x), true
+		}
+	case "number", "integer":
+		s, ok := val.(string)
+		if !ok {
+			return val, false
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return val, false
+		}
+		if t == "integer" && f != math.Trunc(f) {
+			return val, false
+		}
+		return f, true
+	case "boolean":
+		if s, ok := val.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, true
+			}
+		}
+	case "array":
+		if s, ok := val.(string); ok {
+			if s = strings.TrimSpace(s); strings.HasPrefix(s, "[") {
+				var arr []any
+				if err := json.Unmarshal([]byte(s), &arr); err == nil {
+					return arr, true
+				}
+				return val, false
+			}
+		}
+		if val != nil {
+			if _, ok := val.(map[string]any); !ok {
+				// 单个值包装为数组
+				return []any{val}, true
+			}
+		}
+	case "object":
+		if s, ok := val.(string); ok {
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(strings.TrimSpace(s)), &obj); err == nil && obj != nil {
+				return obj, true
+			}
+		}
+	}
+	return val, false
+}
+
+// normalizeNumber 将各种数值类型统一为 float64
+func normalizeNumber(val any) any {
+	switch x := val.(type) {
+	case int:
+		return float64(x)
+	case int32:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case float32:
+		return float64(x)
+	case json.Number:
+		if f, err := x.Float64(); err == nil {
+			return f
+		}
+	}
+	return val
+}
+
+func describe(val any) string {
+	switch x := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		// 按字符截断，避免切开多字节字符
+		if r := []rune(x); len(r) > 40 {
+			x = string(r[:40]) + "..."
+		}
+		return fmt.Sprintf("string %q", x)
+	case float64:
+		return fmt.Sprintf("number %v", x)
+	case bool:
+		return fmt.Sprintf("boolean %v", x)
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+func joinPath(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func schemaStrings(v any) []string {
+	switch x := v.(type) {
+	case string:
+		return []string{x}
+	case []string:
+		return x
+	case []any:
+		out := make([]string, 0, len(x))
+		for _, s := range x {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaList(v any) []map[string]any {
+	switch x := v.(type) {
+	case []map[string]any:
+		return x
+	case []any:
+		out := make([]map[string]any, 0, len(x))
+		for _, s := range x {
+			if m, ok := s.(map[string]any); ok {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaNumber(v any) (float64, bool) {
+	if f, ok := normalizeNumber(v).(float64); ok {
+		return f, true
+	}
+	return 0, false
+}
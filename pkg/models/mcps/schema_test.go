@@ -0,0 +1,134 @@
+package mcps
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"query":  map[string]any{"type": "string", "minLength": 1},
+		"limit":  map[string]any{"type": "integer", "minimum": 1, "maximum": 20},
+		"score":  map[string]any{"type": "number"},
+		"exact":  map[string]any{"type": "boolean"},
+		"tags":   map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"order":  map[string]any{"type": "string", "enum": []string{"asc", "desc"}},
+		"filter": map[string]any{"type": "object", "properties": map[string]any{"year": map[string]any{"type": "integer"}}},
+	},
+	"required": []string{"query"},
+}
+
+func TestValidateArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		expected string
+	}{
+		{"valid", `{"query":"go","limit":5}`, `{"query":"go","limit":5}`},
+		{"string to integer", `{"query":"go","limit":"5"}`, `{"query":"go","limit":5}`},
+		{"string to number", `{"query":"go","score":" 0.5"}`, `{"query":"go","score":0.5}`},
+		{"string to boolean", `{"query":"go","exact":"true"}`, `{"query":"go","exact":true}`},
+		{"number to string", `{"query":42}`, `{"query":"42"}`},
+		{"json string to array", `{"query":"go","tags":"[\"a\",\"b\"]"}`, `{"query":"go","tags":["a","b"]}`},
+		{"single value to array", `{"query":"go","tags":"a"}`, `{"query":"go","tags":["a"]}`},
+		{"json string to object", `{"query":"go","filter":"{\"year\":\"2024\"}"}`, `{"query":"go","filter":{"year":2024}}`},
+		{"enum case", `{"query":"go","order":"DESC"}`, `{"query":"go","order":"desc"}`},
+		{"null optional", `{"query":"go","limit":null}`, `{"query":"go"}`},
+		{"unknown kept", `{"query":"go","extra":1}`, `{"query":"go","extra":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args, expected map[string]any
+			_ = json.Unmarshal([]byte(tt.args), &args)
+			_ = json.Unmarshal([]byte(tt.expected), &expected)
+			got, err := ValidateArgs(testSchema, args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("got %v, want %v", got, expected)
+			}
+		})
+	}
+}
+
+func TestValidateArgsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		expected []string
+	}{
+		{"missing required", `{}`, []string{"query: is required"}},
+		{"null required", `{"query":null}`, []string{"query: is required"}},
+		{"not integer", `{"query":"go","limit":"five"}`, []string{`limit: expected integer, got string "five"`}},
+		{"long value", `{"query":"go","limit":"` + strings.Repeat("五", 45) + `"}`, []string{`limit: expected integer, got string "` + strings.Repeat("五", 40) + `..."`}},
+		{"fraction", `{"query":"go","limit":2.5}`, []string{"limit: expected integer, got number 2.5"}},
+		{"maximum", `{"query":"go","limit":50}`, []string{"limit: must be <= 20, got 50"}},
+		{"min length", `{"query":""}`, []string{"query: must be at least 1 characters"}},
+		{"enum", `{"query":"go","order":"random"}`, []string{`order: must be one of ["asc","desc"], got string "random"`}},
+		{"nested", `{"query":"go","filter":{"year":"last"}}`, []string{"filter.year: expected integer"}},
+		{"array item", `{"query":"go","tags":["a",{"b":1}]}`, []string{"tags[1]: expected string, got object"}},
+		{"multiple", `{"limit":"x"}`, []string{"limit: expected integer", "query: is required"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var args map[string]any
+			_ = json.Unmarshal([]byte(tt.args), &args)
+			_, err := ValidateArgs(testSchema, args)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			ae, ok := err.(*ArgsError)
+			if !ok {
+				t.Fatalf("expected *ArgsError, got %T", err)
+			}
+			if len(ae.Problems) != len(tt.expected) {
+				t.Fatalf("got problems %v, want %v", ae.Problems, tt.expected)
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q should contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateArgsSchemaVariants(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id":   map[string]any{"anyOf": []any{map[string]any{"type": "integer"}, map[string]any{"type": "string"}}},
+			"note": map[string]any{"type": []any{"string", "null"}},
+		},
+		"required":             []any{"id"},
+		"additionalProperties": false,
+	}
+
+	got, err := ValidateArgs(schema, map[string]any{"id": "abc", "note": nil})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["id"] != "abc" || got["note"] != nil {
+		t.Errorf("unexpected result %v", got)
+	}
+	if _, ok := got["note"]; !ok {
+		t.Error("nullable property should be kept")
+	}
+
+	_, err = ValidateArgs(schema, map[string]any{"id": 1, "other": true})
+	if err == nil || !strings.Contains(err.Error(), "other: unknown property, allowed: id, note") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// 无 schema 时原样返回
+	args := map[string]any{"x": 1}
+	if got, err := ValidateArgs(nil, args); err != nil || !reflect.DeepEqual(got, args) {
+		t.Errorf("got %v, %v", got, err)
+	}
+}
@@ -18,6 +18,11 @@ type ToolDescriptor struct {
 	InputSchema map[string]any `json:"inputSchema"`
 }
 
+// GetSubject 返回用于语义检索的主题（名称 + 描述）
+func (td ToolDescriptor) GetSubject() string {
+	return strings.TrimSpace(td.Name + " " + td.Description)
+}
+
 // ToolCallPayload 是 MCP tools/call 的参数
 type ToolCallPayload struct {
 	Name      string         `json:"name"`
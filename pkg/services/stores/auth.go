@@ -61,6 +61,19 @@ func IsKeeper(ctx context.Context) bool {
 	return slices.Contains(user.Roles, settings.Current.KeeperRole)
 }
 
+type trustedKeyType struct{}
+
+// ContextWithTrusted 标记为受信任的内部调用（命令行、后台任务），不受知识库集合规则限制
+func ContextWithTrusted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, trustedKeyType{}, true)
+}
+
+// IsTrusted checks if the context is marked as a trusted internal caller
+func IsTrusted(ctx context.Context) bool {
+	v, _ := ctx.Value(trustedKeyType{}).(bool)
+	return v
+}
+
 // contextKey for OAuth token
 type oauthTokenKeyType struct{}
 
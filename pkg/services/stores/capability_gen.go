@@ -10,12 +10,13 @@ import (
 
 // type CapCapability = capability.Capability
 // type CapCapabilityVector = capability.CapabilityVector
+// type CapSource = capability.Source
 // type SwaggerParam = capability.SwaggerParam
 // type SwaggerResponse = capability.SwaggerResponse
 // type SwaggerSchema = capability.SwaggerSchema
 
 func init() {
-	RegisterModel((*capability.Capability)(nil), (*capability.CapabilityVector)(nil))
+	RegisterModel((*capability.Capability)(nil), (*capability.CapabilityVector)(nil), (*capability.Source)(nil))
 }
 
 type CapabilityStore interface {
@@ -30,6 +31,12 @@ type CapabilityStore interface {
 	ListCapabilityVector(ctx context.Context, spec *CapCapabilityVectorSpec) (data capability.CapabilityVectors, total int, err error)
 	GetCapabilityVector(ctx context.Context, id string) (obj *capability.CapabilityVector, err error)
 	CreateCapabilityVector(ctx context.Context, in capability.CapabilityVectorBasic) (obj *capability.CapabilityVector, err error)
+
+	ListSource(ctx context.Context, spec *CapSourceSpec) (data capability.Sources, total int, err error)
+	GetSource(ctx context.Context, id string) (obj *capability.Source, err error)
+	CreateSource(ctx context.Context, in capability.SourceBasic) (obj *capability.Source, err error)
+	UpdateSource(ctx context.Context, id string, in capability.SourceSet) error
+	DeleteSource(ctx context.Context, id string) error
 }
 
 type CapCapabilitySpec struct {
@@ -42,6 +49,8 @@ type CapCapabilitySpec struct {
 	Endpoint string `extensions:"x-order=B" form:"endpoint" json:"endpoint"`
 	// HTTP 方法 GET/POST/PUT/DELETE 等
 	Method string `extensions:"x-order=C" form:"method" json:"method"`
+	// 所属 API 来源，为零时使用默认的 BusPrefix
+	SourceID string `extensions:"x-order=D" form:"sourceID" json:"sourceID"`
 }
 
 func (spec *CapCapabilitySpec) Sift(q *ormQuery) *ormQuery {
@@ -49,6 +58,7 @@ func (spec *CapCapabilitySpec) Sift(q *ormQuery) *ormQuery {
 	q, _ = siftEqual(q, "operation_id", spec.OperationID, false)
 	q, _ = siftMatch(q, "endpoint", spec.Endpoint, false)
 	q, _ = siftEqual(q, "method", spec.Method, false)
+	q, _ = siftOID(q, "source_id", spec.SourceID, false)
 
 	return q
 }
@@ -59,11 +69,29 @@ type CapCapabilityVectorSpec struct {
 
 	// 关联的 Capability ID
 	CapID string `extensions:"x-order=A" form:"capID" json:"capID"`
+	// 嵌入模型
+	Model string `extensions:"x-order=B" form:"model" json:"model"`
 }
 
 func (spec *CapCapabilityVectorSpec) Sift(q *ormQuery) *ormQuery {
 	q = spec.ModelSpec.Sift(q)
 	q, _ = siftOID(q, "cap_id", spec.CapID, false)
+	q, _ = siftEqual(q, "model", spec.Model, false)
+
+	return q
+}
+
+type CapSourceSpec struct {
+	PageSpec
+	ModelSpec
+
+	// 名称
+	Name string `extensions:"x-order=A" form:"name" json:"name"`
+}
+
+func (spec *CapSourceSpec) Sift(q *ormQuery) *ormQuery {
+	q = spec.ModelSpec.Sift(q)
+	q, _ = siftEqual(q, "name", spec.Name, false)
 
 	return q
 }
@@ -139,3 +167,63 @@ func (s *capabilityStore) CreateCapabilityVector(ctx context.Context, in capabil
 	err = dbInsert(ctx, s.w.db, obj)
 	return
 }
+
+func (s *capabilityStore) ListSource(ctx context.Context, spec *CapSourceSpec) (data capability.Sources, total int, err error) {
+	total, err = s.w.db.ListModel(ctx, spec, &data)
+	if err == nil {
+		err = s.afterListSource(ctx, spec, data)
+	}
+	return
+}
+func (s *capabilityStore) GetSource(ctx context.Context, id string) (obj *capability.Source, err error) {
+	obj = new(capability.Source)
+	if err = dbGetWith(ctx, s.w.db, obj, "name", "=", id); err != nil && obj.SetID(id) {
+		err = dbGetWithPK(ctx, s.w.db, obj)
+	}
+	if err == nil {
+		err = s.afterLoadSource(ctx, obj)
+	}
+	return
+}
+func (s *capabilityStore) CreateSource(ctx context.Context, in capability.SourceBasic) (obj *capability.Source, err error) {
+	obj = capability.NewSourceWithBasic(in)
+	if obj.Name == "" {
+		err = ErrEmptyKey
+		return
+	}
+	if err = dbBeforeSaveSource(ctx, s.w.db, obj); err != nil {
+		return
+	}
+	dbMetaUp(ctx, s.w.db, obj)
+	err = dbInsert(ctx, s.w.db, obj, "name")
+	if err == nil {
+		err = s.afterCreatedSource(ctx, obj)
+	}
+	return
+}
+func (s *capabilityStore) UpdateSource(ctx context.Context, id string, in capability.SourceSet) error {
+	exist := new(capability.Source)
+	if err := dbGetWithPKID(ctx, s.w.db, exist, id); err != nil {
+		return err
+	}
+	exist.SetIsUpdate(true)
+	exist.SetWith(in)
+	if err := dbBeforeSaveSource(ctx, s.w.db, exist); err != nil {
+		return err
+	}
+	dbMetaUp(ctx, s.w.db, exist)
+	return dbUpdate(ctx, s.w.db, exist)
+}
+func (s *capabilityStore) DeleteSource(ctx context.Context, id string) error {
+	obj := new(capability.Source)
+	if err := dbGetWithPKID(ctx, s.w.db, obj, id); err != nil {
+		return err
+	}
+	return s.w.db.RunInTx(ctx, nil, func(ctx context.Context, tx pgTx) (err error) {
+		if err = dbBeforeDeleteSource(ctx, tx, obj); err != nil {
+			return
+		}
+		err = dbDeleteM(ctx, tx, s.w.db.Schema(), s.w.db.SchemaCrap(), obj)
+		return
+	})
+}
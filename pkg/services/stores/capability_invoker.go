@@ -9,15 +9,32 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/cast"
+
+	"github.com/liut/morign/pkg/models/capability"
+	"github.com/liut/morign/pkg/models/mcps"
 )
 
 // CapabilityInvoker invokes Bus API calls with path parameter substitution.
+//
+// The default invoker targets BusPrefix and forwards the user's OAuth token,
+// capabilities of a named source are routed through a child invoker with the source's
+// base URL, auth headers and timeout.
 type CapabilityInvoker struct {
 	httpClient *http.Client
 	baseURL    string
+	headerFunc mcps.HeaderFunc
+
+	// source id -> *sourceInvoker
+	sources sync.Map
+}
+
+type sourceInvoker struct {
+	updated *time.Time
+	inv     *CapabilityInvoker
 }
 
 // NewCapabilityInvoker creates a CapabilityInvoker with the given Bus API base URL.
@@ -28,8 +45,56 @@ func NewCapabilityInvoker(baseURL string) *CapabilityInvoker {
 	}
 }
 
+// ForSource returns the invoker of the source, nil source means the default one.
+// Child invokers are reused until the source is updated, so OAuth2 tokens are cached.
+func (inv *CapabilityInvoker) ForSource(src *capability.Source) *CapabilityInvoker {
+	if src == nil || src.ID.IsZero() {
+		return inv
+	}
+	if v, ok := inv.sources.Load(src.ID); ok {
+		if si := v.(*sourceInvoker); sameTime(si.updated, src.UpdatedAt) {
+			return si.inv
+		}
+	}
+	timeout := 30 * time.Second
+	if src.Timeout > 0 {
+		timeout = time.Duration(src.Timeout) * time.Second
+	}
+	hf := src.HeaderFunc
+	if hf == nil {
+		// 来源未设置任何认证时不转发用户令牌
+		hf = func(context.Context) map[string]string { return nil }
+	}
+	child := &CapabilityInvoker{
+		httpClient: &http.Client{Timeout: timeout},
+		baseURL:    strings.TrimSuffix(src.BaseURL, "/"),
+		headerFunc: hf,
+	}
+	inv.sources.Store(src.ID, &sourceInvoker{updated: src.UpdatedAt, inv: child})
+	return child
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
 // Invoke makes an HTTP request to the Bus API.
 func (inv *CapabilityInvoker) Invoke(ctx context.Context, method, endpoint string, params map[string]any) (*http.Response, error) {
+	req, err := inv.BuildRequest(ctx, method, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	logger().Infow("invoking api", "method", req.Method, "url", req.URL.String(), "params", params)
+
+	return inv.httpClient.Do(req)
+}
+
+// BuildRequest builds the request of the API call without sending it.
+func (inv *CapabilityInvoker) BuildRequest(ctx context.Context, method, endpoint string, params map[string]any) (*http.Request, error) {
 	method = strings.ToUpper(method)
 
 	// Build URL and body
@@ -38,8 +103,6 @@ func (inv *CapabilityInvoker) Invoke(ctx context.Context, method, endpoint strin
 		return nil, err
 	}
 
-	logger().Infow("invoking api", "method", method, "url", reqURL, "params", params)
-
 	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
@@ -47,11 +110,49 @@ func (inv *CapabilityInvoker) Invoke(ctx context.Context, method, endpoint strin
 
 	req.Header.Set("X-Ai-Agent", "morign")
 	req.Header.Set("Content-Type", "application/json")
-	if tk := OAuthTokenFromContext(ctx); len(tk) > 0 {
+	if inv.headerFunc != nil {
+		for k, v := range inv.headerFunc(ctx) {
+			req.Header.Set(k, v)
+		}
+	} else if tk := OAuthTokenFromContext(ctx); len(tk) > 0 {
 		req.Header.Set("Authorization", "Bearer "+tk)
 	}
 
-	return inv.httpClient.Do(req)
+	return req, nil
+}
+
+// Preview describes the request to be sent for dry run, values of auth headers are masked.
+func (inv *CapabilityInvoker) Preview(ctx context.Context, method, endpoint string, params map[string]any) (map[string]any, error) {
+	req, err := inv.BuildRequest(ctx, method, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(req.Header))
+	for k := range req.Header {
+		if k == "Content-Type" || k == "X-Ai-Agent" {
+			headers[k] = req.Header.Get(k)
+		} else {
+			headers[k] = mcps.SecretMask
+		}
+	}
+	out := map[string]any{
+		"method":  req.Method,
+		"url":     req.URL.String(),
+		"headers": headers,
+	}
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		var body any
+		if err := json.Unmarshal(data, &body); err != nil {
+			body = string(data)
+		}
+		out["body"] = body
+	}
+	return out, nil
 }
 
 // buildRequestData handles path parameter substitution, query string construction, and JSON body generation.
@@ -61,23 +162,26 @@ func (inv *CapabilityInvoker) buildRequestData(method, endpoint string, params m
 		return fullURL, nil, nil
 	}
 
-	u, err := url.Parse(fullURL)
-	if err != nil {
-		return "", nil, fmt.Errorf("parse url: %w", err)
-	}
-
 	// 1. Substitute path parameters, collect remaining params
+	// 路径参数转义后替换，含 / 或 .. 的值会越出目录中的端点，直接拒绝
 	remaining := make(map[string]any)
-	path := u.Path
 	for k, v := range params {
 		placeholder := "{" + k + "}"
-		if strings.Contains(path, placeholder) {
-			path = strings.ReplaceAll(path, placeholder, cast.ToString(v))
-		} else {
+		if !strings.Contains(endpoint, placeholder) {
 			remaining[k] = v
+			continue
+		}
+		val := cast.ToString(v)
+		if strings.Contains(val, "/") || strings.Contains(val, "..") {
+			return "", nil, fmt.Errorf("invalid path parameter %s: %q", k, val)
 		}
+		endpoint = strings.ReplaceAll(endpoint, placeholder, url.PathEscape(val))
+	}
+
+	u, err := url.Parse(inv.baseURL + endpoint)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse url: %w", err)
 	}
-	u.Path = path
 
 	if len(remaining) == 0 {
 		return u.String(), nil, nil
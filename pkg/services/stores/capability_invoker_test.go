@@ -1,12 +1,21 @@
 package stores
 
 import (
+	"context"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/cupogo/andvari/models/oid"
+	auth "github.com/liut/simpauth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/models/capability"
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/settings"
 )
 
 func TestBuildRequestData(t *testing.T) {
@@ -172,12 +181,23 @@ func TestBuildRequestData_EdgeCases(t *testing.T) {
 	inv := &CapabilityInvoker{baseURL: "http://localhost:8080"}
 
 	t.Run("path param with special characters", func(t *testing.T) {
-		urlStr, body, err := inv.buildRequestData(http.MethodGet, "/api/test/{id}", map[string]any{"id": "co-123/abc"})
+		urlStr, body, err := inv.buildRequestData(http.MethodGet, "/api/test/{id}", map[string]any{"id": "co 123?x=1#a"})
 		require.NoError(t, err)
-		assert.Equal(t, "http://localhost:8080/api/test/co-123/abc", urlStr)
+		assert.Equal(t, "http://localhost:8080/api/test/co%20123%3Fx=1%23a", urlStr)
 		assert.Nil(t, body)
 	})
 
+	t.Run("path param escaping the endpoint", func(t *testing.T) {
+		for _, id := range []string{"co-123/abc", "../../admin/x", ".."} {
+			_, _, err := inv.buildRequestData(http.MethodGet, "/api/test/{id}/items", map[string]any{"id": id})
+			assert.Error(t, err, id)
+		}
+		// % 本身被转义，不会被还原为 ..
+		urlStr, _, err := inv.buildRequestData(http.MethodGet, "/api/test/{id}/items", map[string]any{"id": "%2e%2e"})
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:8080/api/test/%252e%252e/items", urlStr)
+	})
+
 	t.Run("path param with empty string value", func(t *testing.T) {
 		urlStr, body, err := inv.buildRequestData(http.MethodGet, "/api/test/{id}", map[string]any{"id": ""})
 		require.NoError(t, err)
@@ -238,3 +258,88 @@ func TestBuildRequestData_EdgeCases(t *testing.T) {
 		}
 	})
 }
+
+func TestCapabilityInvokerForSource(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer ts.Close()
+
+	ctx := OAuthContextWithToken(context.Background(), "user-token")
+	def := NewCapabilityInvoker(ts.URL + "/bus/")
+
+	resp, err := def.Invoke(ctx, "get", "/api/ping", nil)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.JSONEq(t, `{"path":"/bus/api/ping"}`, string(body))
+	assert.Equal(t, "Bearer user-token", got.Get("Authorization"), "default source forwards user token")
+
+	assert.Same(t, def, def.ForSource(nil))
+
+	src := &capability.Source{SourceBasic: capability.SourceBasic{Name: "crm", BaseURL: ts.URL + "/crm", Timeout: 5}}
+	src.SetID(oid.NewID(oid.OtFile))
+	src.HeaderFunc = buildHeaderFunc(src.Name, mcps.HeaderCateNone, mcps.AuthTypeHeader,
+		mcps.Credential{Headers: map[string]string{"X-Api-Key": "k1"}})
+
+	child := def.ForSource(src)
+	assert.NotSame(t, def, child)
+	assert.Same(t, child, def.ForSource(src), "should reuse invoker until source updated")
+	assert.Equal(t, 5*time.Second, child.httpClient.Timeout)
+
+	resp, err = child.Invoke(ctx, "GET", "/api/ping", nil)
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.JSONEq(t, `{"path":"/crm/api/ping"}`, string(body))
+	assert.Equal(t, "k1", got.Get("X-Api-Key"))
+	assert.Empty(t, got.Get("Authorization"), "named source should not leak user token")
+
+	now := time.Now()
+	src.UpdatedAt = &now
+	assert.NotSame(t, child, def.ForSource(src), "should rebuild after source updated")
+}
+
+func TestCapabilityInvokerPreview(t *testing.T) {
+	inv := NewCapabilityInvoker("http://localhost:8080")
+	src := &capability.Source{SourceBasic: capability.SourceBasic{Name: "crm", BaseURL: "https://crm.example.com"}}
+	src.SetID(oid.NewID(oid.OtFile))
+	src.HeaderFunc = func(context.Context) map[string]string {
+		return map[string]string{"X-Api-Key": "secret"}
+	}
+
+	out, err := inv.ForSource(src).Preview(context.Background(), "post", "/api/contacts/{id}/notes",
+		map[string]any{"id": "c-1", "text": "hello"})
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, out["method"])
+	assert.Equal(t, "https://crm.example.com/api/contacts/c-1/notes", out["url"])
+	assert.Equal(t, map[string]any{"text": "hello"}, out["body"])
+	headers := out["headers"].(map[string]string)
+	assert.Equal(t, mcps.SecretMask, headers["X-Api-Key"])
+	assert.Equal(t, "application/json", headers["Content-Type"])
+
+	out, err = inv.Preview(context.Background(), "GET", "/api/contacts", map[string]any{"q": "x"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/api/contacts?q=x", out["url"])
+	assert.NotContains(t, out, "body")
+}
+
+func TestMethodAllowed(t *testing.T) {
+	orig := *settings.Current
+	defer func() { *settings.Current = orig }()
+	settings.Current.KeeperRole = "keeper"
+	settings.Current.CapabilityMethods = []string{"GET"}
+
+	user := auth.ContextWithUser(context.Background(), &User{OID: "u-oid", UID: "u1"})
+	keeper := auth.ContextWithUser(context.Background(), &User{OID: "k-oid", UID: "k1", Roles: []string{"keeper"}})
+
+	assert.True(t, methodAllowed(user, "GET"))
+	assert.False(t, methodAllowed(user, "POST"))
+	assert.False(t, methodAllowed(context.Background(), "DELETE"))
+	assert.True(t, methodAllowed(keeper, "DELETE"))
+
+	settings.Current.CapabilityMethods = []string{"*"}
+	assert.True(t, methodAllowed(user, "PATCH"))
+}
@@ -0,0 +1,445 @@
+package stores
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/liut/morign/pkg/models/capability"
+)
+
+// 解析 $ref 的最大深度，防止过深的嵌套模型撑大参数定义
+const maxRefDepth = 8
+
+// apiDocNormalizer 将 Swagger 2.0 或 OpenAPI 3.x 文档归一为 swaggerDoc，
+// 解析本地 $ref 并将请求体展开为 body 参数
+type apiDocNormalizer struct {
+	root map[string]any
+}
+
+// normalizeAPIDoc 按 swagger 或 openapi 字段识别版本并归一
+func normalizeAPIDoc(raw map[string]any) (*swaggerDoc, error) {
+	n := &apiDocNormalizer{root: raw}
+	doc := &swaggerDoc{Paths: map[string]map[string]swaggerOperation{}}
+	doc.Swagger, _ = raw["swagger"].(string)
+	doc.OpenAPI, _ = raw["openapi"].(string)
+	if info, ok := raw["info"].(map[string]any); ok {
+		doc.Info.Title, _ = info["title"].(string)
+	}
+	if len(doc.Swagger) == 0 && len(doc.OpenAPI) == 0 {
+		return nil, fmt.Errorf("unknown api document: missing swagger or openapi version")
+	}
+	v3 := strings.HasPrefix(doc.OpenAPI, "3")
+	if len(doc.OpenAPI) > 0 && !v3 {
+		return nil, fmt.Errorf("unsupported openapi version: %s", doc.OpenAPI)
+	}
+	doc.BaseURL = docBaseURL(raw, v3)
+
+	paths, _ := raw["paths"].(map[string]any)
+	for path, item := range paths {
+		pi, ok := n.resolve(item, nil).(map[string]any)
+		if !ok {
+			continue
+		}
+		common := listOf(pi["parameters"])
+		ops := map[string]swaggerOperation{}
+		for method, v := range pi {
+			if !isHTTPMethod(method) {
+				continue
+			}
+			op, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			ops[method] = n.operation(op, common, v3)
+		}
+		if len(ops) > 0 {
+			doc.Paths[path] = ops
+		}
+	}
+	return doc, nil
+}
+
+// docBaseURL 返回文档声明的服务地址，仅接受绝对网址
+func docBaseURL(raw map[string]any, v3 bool) string {
+	var base string
+	if v3 {
+		for _, sv := range listOf(raw["servers"]) {
+			if m, ok := sv.(map[string]any); ok {
+				if base = stringValue(m["url"]); len(base) > 0 {
+					break
+				}
+			}
+		}
+	} else if host := stringValue(raw["host"]); len(host) > 0 {
+		scheme := "https"
+		if schemes := listOf(raw["schemes"]); len(schemes) > 0 && !slices.Contains(schemes, any("https")) {
+			scheme = stringValue(schemes[0])
+		}
+		base = scheme + "://" + host + stringValue(raw["basePath"])
+	}
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		return ""
+	}
+	return strings.TrimSuffix(base, "/")
+}
+
+func (n *apiDocNormalizer) operation(op map[string]any, common []any, v3 bool) swaggerOperation {
+	out := swaggerOperation{
+		OperationID: stringValue(op["operationId"]),
+		Summary:     stringValue(op["summary"]),
+		Description: stringValue(op["description"]),
+		Responses:   map[string]capability.SwaggerResponse{},
+	}
+	for _, t := range listOf(op["tags"]) {
+		if s, ok := t.(string); ok {
+			out.Tags = append(out.Tags, s)
+		}
+	}
+
+	// 操作级参数覆盖路径级同名参数
+	seen := map[string]bool{}
+	for _, list := range [][]any{listOf(op["parameters"]), common} {
+		for _, p := range list {
+			pm, ok := n.resolve(p, nil).(map[string]any)
+			if !ok {
+				continue
+			}
+			key := stringValue(pm["in"]) + ":" + stringValue(pm["name"])
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if pm["in"] == "body" {
+				// Swagger 2.0 body 参数展开为属性
+				schema, _ := n.resolve(pm["schema"], nil).(map[string]any)
+				out.Parameters = append(out.Parameters, bodyParams(schema, boolValue(pm["required"]))...)
+				continue
+			}
+			out.Parameters = append(out.Parameters, n.param(pm, v3))
+		}
+	}
+
+	if rb, ok := n.resolve(op["requestBody"], nil).(map[string]any); ok {
+		if schema := mediaSchema(rb["content"]); schema != nil {
+			out.Parameters = append(out.Parameters, bodyParams(schema, boolValue(rb["required"]))...)
+		}
+	}
+
+	responses, _ := op["responses"].(map[string]any)
+	for code, v := range responses {
+		rm, ok := n.resolve(v, nil).(map[string]any)
+		if !ok {
+			continue
+		}
+		resp := capability.SwaggerResponse{Description: stringValue(rm["description"])}
+		schema, _ := rm["schema"].(map[string]any)
+		if v3 {
+			schema = mediaSchema(rm["content"])
+		}
+		if schema != nil {
+			resp.Schema = toSwaggerSchema(n.resolve(schema, nil))
+		}
+		out.Responses[code] = resp
+	}
+	return out
+}
+
+// param 转换 query/path/header 等非 body 参数，OpenAPI 3 的类型在 schema 中
+func (n *apiDocNormalizer) param(pm map[string]any, v3 bool) capability.SwaggerParam {
+	p := capability.SwaggerParam{
+		Name:        stringValue(pm["name"]),
+		In:          stringValue(pm["in"]),
+		Description: stringValue(pm["description"]),
+		Required:    boolValue(pm["required"]),
+		Type:        stringValue(pm["type"]),
+		Example:     exampleValue(pm["example"]),
+	}
+	schema, _ := n.resolve(pm["schema"], nil).(map[string]any)
+	if v3 || p.Type == "" {
+		if schema == nil {
+			schema = map[string]any{}
+		}
+		if p.Type == "" {
+			p.Type = stringValue(schema["type"])
+		}
+		if p.Example == "" {
+			p.Example = exampleValue(schema["example"])
+		}
+	}
+	if schema == nil && pm["enum"] != nil {
+		schema = map[string]any{"type": p.Type, "enum": pm["enum"]}
+	}
+	if isComplexSchema(schema) {
+		p.Schema = schema
+	}
+	return p
+}
+
+// bodyParams 将请求体 schema 展开为 body 参数，对象按属性展开，其他类型作为单个 body 参数
+func bodyParams(schema map[string]any, required bool) []capability.SwaggerParam {
+	if schema == nil {
+		return nil
+	}
+	schema = mergeAllOf(schema)
+	props, _ := schema["properties"].(map[string]any)
+	if len(props) == 0 {
+		return []capability.SwaggerParam{{
+			Name: "body", In: "body", Required: required,
+			Type: stringValue(schema["type"]), Description: stringValue(schema["description"]),
+			Schema: schema,
+		}}
+	}
+	reqs := listOf(schema["required"])
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	out := make([]capability.SwaggerParam, 0, len(names))
+	for _, name := range names {
+		ps, _ := props[name].(map[string]any)
+		if ps == nil {
+			ps = map[string]any{}
+		}
+		ps = mergeAllOf(ps)
+		if boolValue(ps["readOnly"]) {
+			continue
+		}
+		p := capability.SwaggerParam{
+			Name: name, In: "body",
+			Type:        stringValue(ps["type"]),
+			Description: stringValue(ps["description"]),
+			Required:    slices.Contains(reqs, any(name)),
+			Example:     exampleValue(ps["example"]),
+		}
+		if isComplexSchema(ps) {
+			p.Schema = ps
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// resolve 返回解析了本地 $ref 的深拷贝，循环引用保留原 $ref
+func (n *apiDocNormalizer) resolve(v any, stack []string) any {
+	switch x := v.(type) {
+	case map[string]any:
+		if ref, ok := x["$ref"].(string); ok {
+			if slices.Contains(stack, ref) || len(stack) >= maxRefDepth {
+				return map[string]any{"$ref": ref, "type": "object"}
+			}
+			target, ok := n.lookup(ref)
+			if !ok {
+				return x
+			}
+			resolved, _ := n.resolve(target, append(stack, ref)).(map[string]any)
+			if len(x) == 1 || resolved == nil {
+				return resolved
+			}
+			// $ref 同级的描述等字段覆盖目标
+			out := make(map[string]any, len(resolved)+len(x))
+			for k, v := range resolved {
+				out[k] = v
+			}
+			for k, v := range x {
+				if k != "$ref" {
+					out[k] = n.resolve(v, stack)
+				}
+			}
+			return out
+		}
+		out := make(map[string]any, len(x))
+		for k, v := range x {
+			out[k] = n.resolve(v, stack)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i, v := range x {
+			out[i] = n.resolve(v, stack)
+		}
+		return out
+	}
+	return v
+}
+
+// lookup 按 JSON Pointer 查找文档内的定义，如 #/components/schemas/User
+func (n *apiDocNormalizer) lookup(ref string) (any, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var cur any = n.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// mediaSchema 从 content 中选取 schema，优先 JSON
+func mediaSchema(v any) map[string]any {
+	content, _ := v.(map[string]any)
+	if len(content) == 0 {
+		return nil
+	}
+	types := make([]string, 0, len(content))
+	for mt := range content {
+		types = append(types, mt)
+	}
+	slices.Sort(types)
+	for _, prefer := range []string{"application/json", "+json", "application/x-www-form-urlencoded", "multipart/form-data"} {
+		for _, mt := range types {
+			if strings.Contains(mt, prefer) {
+				return schemaOf(content[mt])
+			}
+		}
+	}
+	return schemaOf(content[types[0]])
+}
+
+func schemaOf(v any) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		schema, _ := m["schema"].(map[string]any)
+		return schema
+	}
+	return nil
+}
+
+// mergeAllOf 合并 allOf 的属性和必填项
+func mergeAllOf(schema map[string]any) map[string]any {
+	all := listOf(schema["allOf"])
+	if len(all) == 0 {
+		return schema
+	}
+	out := map[string]any{}
+	props := map[string]any{}
+	var reqs []any
+	for i, part := range append(all, schema) {
+		pm, ok := part.(map[string]any)
+		if !ok {
+			continue
+		}
+		if i < len(all) {
+			pm = mergeAllOf(pm)
+		}
+		for k, v := range pm {
+			switch k {
+			case "allOf":
+			case "properties":
+				if m, ok := v.(map[string]any); ok {
+					for pk, pv := range m {
+						props[pk] = pv
+					}
+				}
+			case "required":
+				reqs = append(reqs, listOf(v)...)
+			default:
+				out[k] = v
+			}
+		}
+	}
+	if len(props) > 0 {
+		out["properties"] = props
+		if out["type"] == nil {
+			out["type"] = "object"
+		}
+	}
+	if len(reqs) > 0 {
+		out["required"] = reqs
+	}
+	return out
+}
+
+func toSwaggerSchema(v any) (out capability.SwaggerSchema) {
+	b, err := json.Marshal(v)
+	if err == nil {
+		_ = json.Unmarshal(b, &out)
+	}
+	return
+}
+
+func isComplexSchema(schema map[string]any) bool {
+	if schema == nil {
+		return false
+	}
+	switch schema["type"] {
+	case "object", "array":
+		return true
+	}
+	return schema["enum"] != nil || schema["properties"] != nil || schema["items"] != nil
+}
+
+func isHTTPMethod(s string) bool {
+	switch strings.ToLower(s) {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		return true
+	}
+	return false
+}
+
+func listOf(v any) []any {
+	switch x := v.(type) {
+	case []any:
+		return x
+	case []string:
+		out := make([]any, len(x))
+		for i, s := range x {
+			out[i] = s
+		}
+		return out
+	}
+	return nil
+}
+
+func stringValue(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func boolValue(v any) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+func exampleValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// normalizeYAML 将 YAML 解码出的 map[any]any 转为 map[string]any，如响应码 200
+func normalizeYAML(v any) any {
+	switch x := v.(type) {
+	case map[any]any:
+		out := make(map[string]any, len(x))
+		for k, v := range x {
+			out[fmt.Sprint(k)] = normalizeYAML(v)
+		}
+		return out
+	case map[string]any:
+		for k, v := range x {
+			x[k] = normalizeYAML(v)
+		}
+		return x
+	case []any:
+		for i, v := range x {
+			x[i] = normalizeYAML(v)
+		}
+		return x
+	}
+	return v
+}
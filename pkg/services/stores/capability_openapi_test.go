@@ -0,0 +1,222 @@
+package stores
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/models/capability"
+)
+
+const testOpenAPI3 = `
+openapi: 3.0.3
+info:
+  title: Accounts
+paths:
+  /api/accounts/{id}:
+    parameters:
+      - $ref: '#/components/parameters/AccountID'
+    get:
+      operationId: getAccount
+      summary: Get account
+      tags: [account]
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: array
+            items: {type: string}
+      responses:
+        200:
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Account'
+    put:
+      operationId: updateAccount
+      summary: Update account
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              allOf:
+                - $ref: '#/components/schemas/AccountBasic'
+                - type: object
+                  properties:
+                    manager:
+                      $ref: '#/components/schemas/Account'
+      responses:
+        '204':
+          description: Updated
+components:
+  parameters:
+    AccountID:
+      name: id
+      in: path
+      required: true
+      description: account id
+      schema: {type: string, example: ac-1}
+  schemas:
+    AccountBasic:
+      type: object
+      required: [name]
+      properties:
+        name: {type: string, description: display name}
+        age: {type: integer}
+        status: {type: string, enum: [active, disabled]}
+    Account:
+      allOf:
+        - $ref: '#/components/schemas/AccountBasic'
+        - type: object
+          properties:
+            id: {type: string, readOnly: true}
+            parent: {$ref: '#/components/schemas/Account'}
+`
+
+func findParam(params []capability.SwaggerParam, name string) *capability.SwaggerParam {
+	for i := range params {
+		if params[i].Name == name {
+			return &params[i]
+		}
+	}
+	return nil
+}
+
+func TestDecodeOpenAPI3(t *testing.T) {
+	doc, err := decodeSwaggerDoc(strings.NewReader(testOpenAPI3))
+	require.NoError(t, err)
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, "Accounts", doc.Info.Title)
+
+	ops := doc.Paths["/api/accounts/{id}"]
+	require.Len(t, ops, 2)
+
+	get := ops["get"]
+	assert.Equal(t, "getAccount", get.OperationID)
+	assert.Equal(t, []string{"account"}, get.Tags)
+	id := findParam(get.Parameters, "id")
+	require.NotNil(t, id, "path level parameter should be merged")
+	assert.Equal(t, "path", id.In)
+	assert.Equal(t, "string", id.Type)
+	assert.True(t, id.Required)
+	assert.Equal(t, "ac-1", id.Example)
+	fields := findParam(get.Parameters, "fields")
+	require.NotNil(t, fields)
+	assert.Equal(t, "array", fields.Type)
+	assert.NotNil(t, fields.Schema)
+
+	resp := get.Responses["200"]
+	assert.Equal(t, "OK", resp.Description)
+	require.NotEmpty(t, resp.Schema.AllOf, "response schema should be resolved")
+	assert.Contains(t, resp.Schema.AllOf[0].Properties, "name")
+
+	put := ops["put"]
+	name := findParam(put.Parameters, "name")
+	require.NotNil(t, name, "request body should be flattened")
+	assert.Equal(t, "body", name.In)
+	assert.True(t, name.Required)
+	assert.Equal(t, "display name", name.Description)
+	status := findParam(put.Parameters, "status")
+	require.NotNil(t, status)
+	assert.NotNil(t, status.Schema, "enum should keep schema")
+	manager := findParam(put.Parameters, "manager")
+	require.NotNil(t, manager)
+	assert.Equal(t, "object", manager.Type)
+	assert.False(t, manager.Required)
+	assert.NotNil(t, findParam(put.Parameters, "id"), "path parameter of put")
+	assert.Equal(t, "Updated", put.Responses["204"].Description)
+}
+
+func TestDecodeOpenAPI3JSONBody(t *testing.T) {
+	doc, err := decodeSwaggerDoc(strings.NewReader(`{
+		"openapi": "3.1.0",
+		"paths": {"/api/tags": {"post": {
+			"requestBody": {"$ref": "#/components/requestBodies/Tags"},
+			"responses": {"201": {"description": "Created"}}
+		}}},
+		"components": {"requestBodies": {"Tags": {"content": {
+			"text/plain": {"schema": {"type": "string"}},
+			"application/json": {"schema": {"type": "array", "items": {"type": "string"}}}
+		}}}}
+	}`))
+	require.NoError(t, err)
+	params := doc.Paths["/api/tags"]["post"].Parameters
+	require.Len(t, params, 1)
+	assert.Equal(t, "body", params[0].Name)
+	assert.Equal(t, "array", params[0].Type)
+}
+
+func TestDecodeSwagger2(t *testing.T) {
+	f, err := os.Open("../../../docs/swagger.yaml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	doc, err := decodeSwaggerDoc(f)
+	require.NoError(t, err)
+	assert.Equal(t, "2.0", doc.Swagger)
+	require.NotEmpty(t, doc.Paths)
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			assert.True(t, isHTTPMethod(method), path)
+			for _, p := range op.Parameters {
+				assert.NotEmpty(t, p.Name, "%s %s", method, path)
+				if p.In == "body" && p.Name != "body" {
+					assert.Nil(t, findRef(p.Schema), "body schema should be resolved: %s %s", method, path)
+				}
+			}
+		}
+	}
+
+	_, err = decodeSwaggerDoc(strings.NewReader(`{"paths": {}}`))
+	assert.Error(t, err)
+	_, err = decodeSwaggerDoc(strings.NewReader(`openapi: 2.5`))
+	assert.Error(t, err)
+}
+
+// findRef 返回未解析的 $ref（循环引用除外）
+func findRef(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		if ref, ok := x["$ref"]; ok && len(x) == 1 {
+			return ref
+		}
+		for _, v := range x {
+			if r := findRef(v); r != nil {
+				return r
+			}
+		}
+	case []any:
+		for _, v := range x {
+			if r := findRef(v); r != nil {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+func TestDocBaseURL(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"openapi servers", `{"openapi":"3.0.0","servers":[{"url":"https://api.example.com/v1/"}]}`, "https://api.example.com/v1"},
+		{"openapi relative", `{"openapi":"3.0.0","servers":[{"url":"/v1"}]}`, ""},
+		{"swagger host", `{"swagger":"2.0","host":"crm.local:8080","basePath":"/api","schemes":["http"]}`, "http://crm.local:8080/api"},
+		{"swagger prefer https", `{"swagger":"2.0","host":"crm.local","schemes":["http","https"]}`, "https://crm.local"},
+		{"swagger no host", `{"swagger":"2.0","basePath":"/api"}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := decodeSwaggerDoc(strings.NewReader(tt.doc))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, doc.BaseURL)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"slices"
 	"strings"
 
@@ -21,32 +22,39 @@ import (
 // CapabilityStoreX is the capability storage extension interface
 type CapabilityStoreX interface {
 	CountCapability(ctx context.Context) (int, error)
-	GetCapabilityWith(ctx context.Context, method, endpoint string) (*capability.Capability, error)
-	ImportCapabilities(ctx context.Context, r io.Reader, lw io.Writer) error
-	SyncEmbeddingCapabilities(ctx context.Context, spec *CapCapabilitySpec) error
+	GetCapabilityWith(ctx context.Context, sourceID oid.OID, method, endpoint string) (*capability.Capability, error)
+	ImportCapabilities(ctx context.Context, source string, r io.Reader, lw io.Writer) error
+	EnsureSource(ctx context.Context, name, baseURL string, override bool) (*capability.Source, error)
+	SyncEmbeddingCapabilities(ctx context.Context, spec *CapCapabilitySpec) (*SyncReport, error)
 	MatchCapabilities(ctx context.Context, ms MatchSpec) (data capability.Capabilities, err error)
 	MatchVectorWith(ctx context.Context, vec corpus.Vector, threshold float32, limit int) (data []capability.CapabilityMatch, err error)
 	InvokerForMatch() mcps.Invoker
 	InvokerForInvoke(invoker *CapabilityInvoker) mcps.Invoker
 }
 
-// swaggerDoc represents a swagger document structure
+// swaggerDoc represents a normalized swagger/openapi document structure
 type swaggerDoc struct {
-	Swagger string `json:"swagger" yaml:"swagger"`
+	Swagger string
+	OpenAPI string
 	Info    struct {
-		Title string `json:"title" yaml:"title"`
-	} `json:"info" yaml:"info"`
-	Paths map[string]map[string]struct {
-		OperationID string                                `json:"operationId" yaml:"operationId"`
-		Summary     string                                `json:"summary" yaml:"summary"`
-		Description string                                `json:"description" yaml:"description"`
-		Parameters  []capability.SwaggerParam             `json:"parameters" yaml:"parameters"`
-		Responses   map[string]capability.SwaggerResponse `json:"responses" yaml:"responses"`
-		Tags        []string                              `json:"tags" yaml:"tags"`
-	} `json:"paths" yaml:"paths"`
+		Title string
+	}
+	// BaseURL from servers (OpenAPI 3) or schemes/host/basePath (Swagger 2.0)
+	BaseURL string
+	Paths   map[string]map[string]swaggerOperation
+}
+
+// swaggerOperation is an API operation with resolved parameters and responses
+type swaggerOperation struct {
+	OperationID string
+	Summary     string
+	Description string
+	Parameters  []capability.SwaggerParam
+	Responses   map[string]capability.SwaggerResponse
+	Tags        []string
 }
 
-// decodeSwaggerDoc decodes swagger document from JSON or YAML format
+// decodeSwaggerDoc decodes Swagger 2.0 or OpenAPI 3.x document from JSON or YAML format
 func decodeSwaggerDoc(r io.Reader) (*swaggerDoc, error) {
 	// Read all content first
 	data, err := io.ReadAll(r)
@@ -54,17 +62,16 @@ func decodeSwaggerDoc(r io.Reader) (*swaggerDoc, error) {
 		return nil, fmt.Errorf("read data: %w", err)
 	}
 
-	doc := new(swaggerDoc)
-	// Try JSON first
-	if err := json.Unmarshal(data, doc); err == nil && doc.Paths != nil {
-		return doc, nil
-	}
-
-	// Try YAML
-	if err := yaml.Unmarshal(data, doc); err != nil {
-		return nil, fmt.Errorf("decode swagger (tried JSON and YAML): %w", err)
+	var raw map[string]any
+	// Try JSON first, then YAML
+	if err := json.Unmarshal(data, &raw); err != nil || raw == nil {
+		raw = nil
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("decode swagger (tried JSON and YAML): %w", err)
+		}
+		raw, _ = normalizeYAML(raw).(map[string]any)
 	}
-	return doc, nil
+	return normalizeAPIDoc(raw)
 }
 
 func (s *capabilityStore) afterCreatedCapability(ctx context.Context, obj *capability.Capability) error {
@@ -72,6 +79,7 @@ func (s *capabilityStore) afterCreatedCapability(ctx context.Context, obj *capab
 	cvb := capability.CapabilityVectorBasic{
 		CapID:   obj.ID,
 		Subject: subject,
+		Model:   VectorSpaceFromContext(ctx).Model,
 	}
 	vec, err := GetEmbedding(ctx, cvb.Subject)
 	if err != nil {
@@ -90,19 +98,26 @@ func (s *capabilityStore) afterCreatedCapability(ctx context.Context, obj *capab
 }
 
 func (s *capabilityStore) afterUpdatedCapability(ctx context.Context, doc *capability.Capability) error {
+	_, err := s.syncCapabilityVector(ctx, doc)
+	return err
+}
+
+// syncCapabilityVector 生成或更新能力在当前向量空间中的向量，主题未变化的跳过
+func (s *capabilityStore) syncCapabilityVector(ctx context.Context, doc *capability.Capability) (bool, error) {
 	subject := doc.GetSubject()
 
-	// Check if vector already exists
+	// Check if vector already exists in the vector space
+	vs := VectorSpaceFromContext(ctx)
 	existing := new(capability.CapabilityVector)
-	err := dbGetWithUnique(ctx, s.w.db, existing, "cap_id", doc.ID)
-	if err == nil && existing.Subject == subject {
+	err := dbGetVector(ctx, s.w.db, existing, "cap_id", doc.ID, vs)
+	if err == nil && existing.Subject == subject && existing.Model == vs.Model {
 		logger().Debugw("unchange vector", "subject", subject)
-		return nil
+		return false, nil
 	}
 	vec, verr := GetEmbedding(ctx, subject)
 	if verr != nil {
-		logger().Warnw("skip capability due to embedding fail", "id", doc.ID, "err", err)
-		return verr // Skip this capability, continue with next
+		logger().Warnw("embedding capability fail", "id", doc.ID, "err", verr)
+		return false, verr
 	}
 	if err == nil {
 		// Update existing
@@ -112,9 +127,10 @@ func (s *capabilityStore) afterUpdatedCapability(ctx context.Context, doc *capab
 		existing.SetWith(capability.CapabilityVectorSet{
 			Subject: &subject,
 			Vector:  &vec,
+			Model:   &vs.Model,
 		})
 		if err = dbUpdate(ctx, s.w.db, existing); err != nil {
-			return err
+			return false, err
 		}
 	} else {
 		// Create new
@@ -122,14 +138,15 @@ func (s *capabilityStore) afterUpdatedCapability(ctx context.Context, doc *capab
 			CapID:   doc.ID,
 			Subject: subject,
 			Vector:  vec,
+			Model:   vs.Model,
 		}
 		_, err = s.CreateCapabilityVector(ctx, cvb)
 		if err != nil {
 			logger().Warnw("create capability vector fail", "capId", doc.ID, "err", err)
-			return err
+			return false, err
 		}
 	}
-	return nil
+	return true, nil
 }
 
 // afterLoadCapability implements after load hook
@@ -155,24 +172,18 @@ func (s *capabilityStore) CountCapability(ctx context.Context) (int, error) {
 	return count, err
 }
 
-func (s *capabilityStore) GetCapabilityWith(ctx context.Context, method, endpoint string) (*capability.Capability, error) {
+func (s *capabilityStore) GetCapabilityWith(ctx context.Context, sourceID oid.OID, method, endpoint string) (*capability.Capability, error) {
 	obj := new(capability.Capability)
-	err := dbGet(ctx, s.w.db, obj, "method = ? AND endpoint = ?", strings.ToUpper(method), endpoint)
+	err := dbGet(ctx, s.w.db, obj, "source_id = ? AND method = ? AND endpoint = ?", sourceID, strings.ToUpper(method), endpoint)
 	if err != nil {
 		return nil, err
 	}
 	return obj, nil
 }
 
-// MatchVectorWith matches capabilities using vector
+// MatchVectorWith matches capabilities using vector in the vector space of context
 func (s *capabilityStore) MatchVectorWith(ctx context.Context, vec corpus.Vector, threshold float32, limit int) (data []capability.CapabilityMatch, err error) {
-	if len(vec) != corpus.VectorLen {
-		logger().Infow("mismatch length of vector", "a", len(vec), "b", corpus.VectorLen)
-		return
-	}
-	logger().Debugw("match capability with", "vec", vec[0:5])
-	err = s.w.db.NewRaw("SELECT * FROM vector_match_capability_4(?, ?, ?)", vec, threshold, limit).
-		Scan(ctx, &data)
+	err = scanVectorMatch(ctx, s.w.db, "capability", vec, threshold, limit, &data)
 	if err != nil {
 		logger().Infow("match capability vector fail", "threshold", threshold, "limit", limit, "err", err)
 	} else {
@@ -205,12 +216,8 @@ func (s *capabilityStore) MatchCapabilities(ctx context.Context, ms MatchSpec) (
 		logger().Infow("GetEmbedding fail", "err", err)
 		return
 	}
-	if len(vec) != corpus.VectorLen {
-		logger().Infow("embedding length mismatch", "a", len(vec), "b", corpus.VectorLen)
-		return
-	}
 
-	matches, err := s.MatchVectorWith(ctx, vec, ms.Threshold, ms.Limit)
+	matches, err := s.MatchVectorWith(ctx, vec, ms.Threshold, rerankCandidates(RerankCapability, ms.Limit))
 	if err != nil || len(matches) == 0 {
 		logger().Infow("no match capabilities", "subj", subject)
 		return
@@ -227,42 +234,52 @@ func (s *capabilityStore) MatchCapabilities(ctx context.Context, ms MatchSpec) (
 	err = queryList(ctx, s.w.db, spec, &data).Scan(ctx)
 	if err != nil {
 		logger().Infow("list capabilities fail", "spec", spec, "err", err)
+		return
 	}
+	data = rerankSlice(ctx, RerankCapability, ms.Query, data, func(c *capability.Capability) string {
+		return c.Method + " " + c.Endpoint + " " + c.GetSubject()
+	}, ms.Limit)
 
 	return
 }
 
-// SyncEmbeddingCapabilities generates vectors for capabilities
-func (s *capabilityStore) SyncEmbeddingCapabilities(ctx context.Context, spec *CapCapabilitySpec) error {
-	data, _, err := s.ListCapability(ctx, spec)
-	if err != nil {
-		return err
-	}
-
-	for _, doc := range data {
-		_ = s.afterUpdatedCapability(ctx, &doc)
-	}
-	return nil
+// SyncEmbeddingCapabilities 分页为 spec 筛选的能力生成向量，主题未变化的跳过，spec.Limit 为每页数量
+func (s *capabilityStore) SyncEmbeddingCapabilities(ctx context.Context, spec *CapCapabilitySpec) (*SyncReport, error) {
+	return runEmbedSync(ctx, s.w.db, embedSyncJob[capability.Capability]{
+		target:   "capability",
+		spec:     spec,
+		pageSize: spec.Limit,
+		idOf:     func(c *capability.Capability) oid.OID { return c.ID },
+		sync:     s.syncCapabilityVector,
+	})
 }
 
-// ImportCapabilities imports capabilities from swagger document (supports both JSON and YAML formats)
-func (s *capabilityStore) ImportCapabilities(ctx context.Context, r io.Reader, lw io.Writer) error {
+// ImportCapabilities imports capabilities from Swagger 2.0 or OpenAPI 3.x document (supports both JSON and YAML formats)
+// into the named source, empty source means the default BusPrefix.
+// A missing source is created with the base URL declared by the document.
+func (s *capabilityStore) ImportCapabilities(ctx context.Context, source string, r io.Reader, lw io.Writer) error {
 	doc, err := decodeSwaggerDoc(r)
 	if err != nil {
 		logger().Infow("decode swagger fail", "err", err)
 		return err
 	}
 
+	var sourceID oid.OID
+	if len(source) > 0 {
+		src, err := s.EnsureSource(ctx, source, doc.BaseURL, false)
+		if err != nil {
+			return err
+		}
+		sourceID = src.ID
+	}
+
 	var imported, skipped int
 	for path, methods := range doc.Paths {
 		for method, api := range methods {
 			method = strings.ToUpper(method)
-			if method == "PARAMETERS" || method == "RESOLUTIONS" {
-				continue
-			}
 
 			// Try to find existing by method+endpoint (unique constraint)
-			existing, err := s.GetCapabilityWith(ctx, method, path)
+			existing, err := s.GetCapabilityWith(ctx, sourceID, method, path)
 			if err != nil && !errors.Is(err, ErrNoRows) {
 				logger().Warnw("check existing fail", "path", path, "method", method, "err", err)
 				continue
@@ -290,6 +307,7 @@ func (s *capabilityStore) ImportCapabilities(ctx context.Context, r io.Reader, l
 				Summary:     api.Summary,
 				Description: api.Description,
 				Tags:        api.Tags,
+				SourceID:    sourceID,
 			}
 
 			// Assign parameters and responses (filter out token header param)
@@ -340,7 +358,7 @@ func (s *capabilityStore) ImportCapabilities(ctx context.Context, r io.Reader, l
 		}
 	}
 
-	logger().Infow("import swagger", "imported", imported, "skipped", skipped)
+	logger().Infow("import swagger", "source", source, "imported", imported, "skipped", skipped)
 	return nil
 }
 
@@ -371,10 +389,12 @@ func (s *capabilityStore) InvokerForMatch() mcps.Invoker {
 		}
 		logger().Infow("matched", "caps", len(caps), "endpoints", caps.Endpoints())
 
+		names := s.sourceNames(ctx, caps)
+
 		// Build result with capability details
 		result := make([]map[string]any, 0, len(caps))
 		for _, cap := range caps {
-			result = append(result, map[string]any{
+			item := map[string]any{
 				"id":           cap.StringID(),
 				"operation_id": cap.OperationID,
 				"endpoint":     cap.Endpoint,
@@ -383,13 +403,20 @@ func (s *capabilityStore) InvokerForMatch() mcps.Invoker {
 				"description":  cap.Description,
 				"parameters":   cap.Parameters,
 				"subject":      cap.GetSubject(),
-			})
+			}
+			if name, ok := names[cap.SourceID]; ok {
+				item["source"] = name
+			}
+			result = append(result, item)
 		}
 		return mcps.BuildToolSuccessResult(result), nil
 	}
 }
 
 // InvokerForInvoke returns an invoker for invoking capabilities
+//
+// 只允许调用已导入的能力：按 method 和 endpoint 查找能力，依据其参数定义校验并转换参数，
+// 非 keeper 用户仅能使用 CapabilityMethods 中的方法。dry_run 时返回构造好的请求而不发送。
 func (s *capabilityStore) InvokerForInvoke(invoker *CapabilityInvoker) mcps.Invoker {
 
 	return func(ctx context.Context, args map[string]any) (map[string]any, error) {
@@ -397,6 +424,7 @@ func (s *capabilityStore) InvokerForInvoke(invoker *CapabilityInvoker) mcps.Invo
 		if method == "" {
 			return mcps.BuildToolErrorResult("missing required argument: method"), nil
 		}
+		method = strings.ToUpper(strings.TrimSpace(method))
 
 		endpoint, _ := args["endpoint"].(string)
 		if endpoint == "" {
@@ -408,7 +436,32 @@ func (s *capabilityStore) InvokerForInvoke(invoker *CapabilityInvoker) mcps.Invo
 			params = make(map[string]any)
 		}
 
-		resp, err := invoker.Invoke(ctx, method, endpoint, params)
+		if !methodAllowed(ctx, method) {
+			return mcps.BuildToolErrorResult(fmt.Sprintf("Permission denied: method %s is not allowed, only %s",
+				method, strings.Join(settings.Current.CapabilityMethods, ", "))), nil
+		}
+
+		src, cap, err := s.lookupCapability(ctx, mcps.StringArg(args, "source"), method, endpoint)
+		if err != nil {
+			return mcps.BuildToolErrorResult(err.Error()), nil
+		}
+
+		params, err = mcps.ValidateArgs(cap.ParamsSchema(), params)
+		if err != nil {
+			return mcps.BuildToolErrorResult(fmt.Sprintf("%s %s: %s. Check the parameters from capability_match and call again.",
+				method, endpoint, err)), nil
+		}
+
+		if dryRun, _ := args["dry_run"].(bool); dryRun {
+			preview, err := invoker.ForSource(src).Preview(ctx, method, endpoint, params)
+			if err != nil {
+				return mcps.BuildToolErrorResult(err.Error()), nil
+			}
+			preview["dry_run"] = true
+			return mcps.BuildToolSuccessResult(preview), nil
+		}
+
+		resp, err := invoker.ForSource(src).Invoke(ctx, method, endpoint, params)
 		if err != nil {
 			logger().Infow("invoke fail", "err", err)
 			return mcps.BuildToolErrorResult(err.Error()), nil
@@ -445,3 +498,177 @@ func (s *capabilityStore) InvokerForInvoke(invoker *CapabilityInvoker) mcps.Invo
 		return mcps.BuildToolSuccessResult(result), nil
 	}
 }
+
+// DefaultSourceName 指代 BusPrefix 的来源名，仅在没有同名来源时有效
+const DefaultSourceName = "default"
+
+// ErrSourceInUse the api source still has capabilities
+var ErrSourceInUse = errors.New("api source is in use")
+
+// dbBeforeSaveSource 校验基础网址并加密凭据，更新时未改动的占位值还原为原密文
+func dbBeforeSaveSource(ctx context.Context, db ormDB, obj *capability.Source) error {
+	obj.BaseURL = strings.TrimSuffix(strings.TrimSpace(obj.BaseURL), "/")
+	if u, err := url.Parse(obj.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("invalid base url: %q", obj.BaseURL)
+	}
+	if obj.IsUpdate() && obj.Credential.HasMasked() {
+		old := capability.NewSourceWithID(obj.ID)
+		if err := dbGetWithPK(ctx, db, old); err != nil {
+			return err
+		}
+		obj.Credential.Restore(old.Credential)
+	}
+	return sealCredential(&obj.Credential)
+}
+
+// dbBeforeDeleteSource 仍有能力属于该来源时拒绝删除
+func dbBeforeDeleteSource(ctx context.Context, db ormDB, obj *capability.Source) error {
+	n, err := db.NewSelect().Model((*capability.Capability)(nil)).
+		Where("source_id = ?", obj.ID).Count(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: %d capabilities", ErrSourceInUse, n)
+	}
+	return nil
+}
+
+func (s *capabilityStore) afterCreatedSource(ctx context.Context, obj *capability.Source) error {
+	obj.Credential.Mask()
+	return nil
+}
+
+func (s *capabilityStore) afterLoadSource(ctx context.Context, obj *capability.Source) error {
+	if err := openCredential(&obj.Credential); err != nil {
+		logger().Warnw("open credential fail", "source", obj.Name, "err", err)
+		obj.HeaderFunc = buildHeaderFunc(obj.Name, obj.HeaderCate, mcps.AuthTypeNone, obj.Credential)
+	} else {
+		obj.HeaderFunc = buildHeaderFunc(obj.Name, obj.HeaderCate, obj.AuthType, obj.Credential)
+	}
+	obj.Credential.Mask()
+	return nil
+}
+
+func (s *capabilityStore) afterListSource(ctx context.Context, spec *CapSourceSpec, data capability.Sources) error {
+	for i := range data {
+		_ = s.afterLoadSource(ctx, &data[i])
+	}
+	return nil
+}
+
+// EnsureSource 返回指定名称的来源，不存在时以 baseURL 创建，默认不带认证；
+// override 时已有来源的网址与 baseURL 不同则更新
+func (s *capabilityStore) EnsureSource(ctx context.Context, name, baseURL string, override bool) (*capability.Source, error) {
+	src, err := s.GetSource(ctx, name)
+	if err == nil {
+		if override && len(baseURL) > 0 && src.BaseURL != strings.TrimSuffix(baseURL, "/") {
+			logger().Infow("update api source", "name", name, "baseURL", baseURL)
+			if err = s.UpdateSource(ctx, src.StringID(), capability.SourceSet{BaseURL: &baseURL}); err != nil {
+				return nil, err
+			}
+			return s.GetSource(ctx, name)
+		}
+		return src, nil
+	}
+	if !errors.Is(err, ErrNoRows) {
+		return nil, err
+	}
+	if len(baseURL) == 0 {
+		return nil, fmt.Errorf("api source %q not found and the document declares no server url", name)
+	}
+	logger().Infow("create api source", "name", name, "baseURL", baseURL)
+	return s.CreateSource(ctx, capability.SourceBasic{Name: name, BaseURL: baseURL})
+}
+
+// sourceNames 返回能力所属来源的名称
+func (s *capabilityStore) sourceNames(ctx context.Context, caps capability.Capabilities) map[oid.OID]string {
+	var ids oid.OIDs
+	for _, cap := range caps {
+		if !cap.SourceID.IsZero() && !slices.Contains(ids, cap.SourceID) {
+			ids = append(ids, cap.SourceID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	var data capability.Sources
+	spec := &CapSourceSpec{}
+	spec.IDs = ids
+	if err := queryList(ctx, s.w.db, spec, &data).Scan(ctx); err != nil {
+		logger().Infow("list sources fail", "ids", ids, "err", err)
+		return nil
+	}
+	out := make(map[oid.OID]string, len(data))
+	for _, src := range data {
+		out[src.ID] = src.Name
+	}
+	return out
+}
+
+// lookupCapability 查找要调用的能力及其来源，来源为 nil 表示默认的 BusPrefix
+//
+// 指定来源名时只在该来源中查找，否则按 method 和 endpoint 查找已导入的能力，
+// 同一接口属于多个来源时要求模型指定来源。
+func (s *capabilityStore) lookupCapability(ctx context.Context, source, method, endpoint string) (*capability.Source, *capability.Capability, error) {
+	method = strings.ToUpper(method)
+	if len(source) > 0 {
+		var src *capability.Source
+		var sourceID oid.OID
+		if obj, err := s.GetSource(ctx, source); err == nil {
+			src, sourceID = obj, obj.ID
+		} else if source != DefaultSourceName {
+			logger().Infow("get source fail", "source", source, "err", err)
+			return nil, nil, fmt.Errorf("unknown api source: %s", source)
+		}
+		cap, err := s.GetCapabilityWith(ctx, sourceID, method, endpoint)
+		if errors.Is(err, ErrNoRows) {
+			return nil, nil, fmt.Errorf("%s %s not found in api source %s, use capability_match to find available APIs", method, endpoint, source)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return src, cap, nil
+	}
+
+	var caps capability.Capabilities
+	err := s.w.db.NewSelect().Model(&caps).
+		Where("method = ? AND endpoint = ?", method, endpoint).
+		Limit(10).Scan(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case len(caps) == 0:
+		return nil, nil, fmt.Errorf("%s %s is not a known capability, use capability_match to find available APIs", method, endpoint)
+	case len(caps) == 1 && caps[0].SourceID.IsZero():
+		return nil, &caps[0], nil
+	case len(caps) == 1:
+		src, err := s.GetSource(ctx, caps[0].SourceID.String())
+		if err != nil {
+			return nil, nil, err
+		}
+		return src, &caps[0], nil
+	}
+	names := s.sourceNames(ctx, caps)
+	list := make([]string, 0, len(caps))
+	for _, cap := range caps {
+		if name, ok := names[cap.SourceID]; ok {
+			list = append(list, name)
+		} else {
+			list = append(list, DefaultSourceName)
+		}
+	}
+	return nil, nil, fmt.Errorf("%s %s exists in multiple api sources: %s, specify the source", method, endpoint, strings.Join(list, ", "))
+}
+
+// methodAllowed 按 CapabilityMethods 检查当前用户可否使用该方法，keeper 不受限
+func methodAllowed(ctx context.Context, method string) bool {
+	if IsKeeper(ctx) {
+		return true
+	}
+	return slices.ContainsFunc(settings.Current.CapabilityMethods, func(m string) bool {
+		m = strings.TrimSpace(m)
+		return m == "*" || strings.EqualFold(m, method)
+	})
+}
@@ -0,0 +1,104 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/cupogo/andvari/models/oid"
+
+	"github.com/liut/morign/pkg/models/aigc"
+	"github.com/liut/morign/pkg/models/corpus"
+	"github.com/liut/morign/pkg/models/mcps"
+)
+
+type citationKeyType struct{}
+
+// citationTracker 记录一轮对话中检索到的知识库文档，同一文档的引用序号保持不变
+type citationTracker struct {
+	mu   sync.Mutex
+	refs map[oid.OID]int
+	list aigc.Citations
+}
+
+// ContextWithCitations 开始记录本轮对话的引用
+func ContextWithCitations(ctx context.Context) context.Context {
+	return context.WithValue(ctx, citationKeyType{}, &citationTracker{refs: make(map[oid.OID]int)})
+}
+
+// CitationsFromContext 返回本轮对话已记录的引用，按序号排列
+func CitationsFromContext(ctx context.Context) aigc.Citations {
+	ct, ok := ctx.Value(citationKeyType{}).(*citationTracker)
+	if !ok {
+		return nil
+	}
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if len(ct.list) == 0 {
+		return nil
+	}
+	return append(aigc.Citations(nil), ct.list...)
+}
+
+// citeDocuments 为文档分配引用序号，未开始记录时返回 nil
+func citeDocuments(ctx context.Context, docs corpus.Documents) []int {
+	ct, ok := ctx.Value(citationKeyType{}).(*citationTracker)
+	if !ok || len(docs) == 0 {
+		return nil
+	}
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	refs := make([]int, len(docs))
+	for i := range docs {
+		doc := &docs[i]
+		ref, ok := ct.refs[doc.ID]
+		if !ok {
+			ref = len(ct.list) + 1
+			ct.refs[doc.ID] = ref
+			ct.list = append(ct.list, aigc.Citation{
+				Ref:        ref,
+				DocID:      doc.ID,
+				Title:      doc.Title,
+				Heading:    doc.Heading,
+				Similarity: doc.Similarity,
+				URL:        doc.URL(),
+			})
+		}
+		refs[i] = ref
+	}
+	return refs
+}
+
+// CiteDocumentsText 记录引用并将文档转为带引用标记的 Markdown 文本，未开始记录时不带标记
+func CiteDocumentsText(ctx context.Context, docs corpus.Documents) string {
+	return docs.MarkdownTextWithRefs(citeDocuments(ctx, docs))
+}
+
+// searchResultKey kb_search 结果中原始匹配文档的键
+const searchResultKey = "documents"
+
+// CiteSearchResult 将 kb_search 的原始匹配转为带本轮引用标记的文本，
+// 结果可能来自缓存，此时文档已经过 JSON 编解码；其他结果原样返回
+func CiteSearchResult(ctx context.Context, result map[string]any) map[string]any {
+	sc, ok := result["structuredContent"].(map[string]any)
+	if !ok {
+		return result
+	}
+	var docs corpus.Documents
+	switch v := sc[searchResultKey].(type) {
+	case corpus.Documents:
+		docs = v
+	case nil:
+		return result
+	default:
+		b, err := json.Marshal(v)
+		if err == nil {
+			err = json.Unmarshal(b, &docs)
+		}
+		if err != nil {
+			logger().Infow("decode search result fail", "err", err)
+			return mcps.BuildToolErrorResult("invalid search result")
+		}
+	}
+	return mcps.BuildToolSuccessResult(CiteDocumentsText(ctx, docs))
+}
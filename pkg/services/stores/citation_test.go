@@ -0,0 +1,52 @@
+package stores
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cupogo/andvari/models/oid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liut/morign/pkg/models/corpus"
+)
+
+func TestCiteDocuments(t *testing.T) {
+	newDoc := func(id oid.OID, title, url string) corpus.Document {
+		doc := corpus.Document{}
+		doc.ID = id
+		doc.Title = title
+		doc.Similarity = 0.5
+		if len(url) > 0 {
+			doc.MetaSet(corpus.MetaKeyURL, url)
+		}
+		return doc
+	}
+
+	// 未开始记录时不带标记
+	ctx := context.Background()
+	docs := corpus.Documents{newDoc(11, "a", "https://example.com/a")}
+	assert.Nil(t, citeDocuments(ctx, docs))
+	assert.NotContains(t, CiteDocumentsText(ctx, docs), "[^")
+	assert.Nil(t, CitationsFromContext(ctx))
+
+	ctx = ContextWithCitations(ctx)
+	assert.Nil(t, CitationsFromContext(ctx))
+	assert.Equal(t, []int{1}, citeDocuments(ctx, docs))
+
+	// 同一文档再次检索时序号不变
+	docs = corpus.Documents{newDoc(12, "b", ""), newDoc(11, "a", "https://example.com/a")}
+	text := CiteDocumentsText(ctx, docs)
+	assert.Contains(t, text, "Marker: [^2]\nID: ")
+	assert.Contains(t, text, "Marker: [^1]\nID: ")
+
+	cites := CitationsFromContext(ctx)
+	assert.Len(t, cites, 2)
+	assert.Equal(t, 1, cites[0].Ref)
+	assert.Equal(t, oid.OID(11), cites[0].DocID)
+	assert.Equal(t, "https://example.com/a", cites[0].URL)
+	assert.Equal(t, float32(0.5), cites[0].Similarity)
+	assert.Equal(t, 2, cites[1].Ref)
+	assert.Equal(t, "b", cites[1].Title)
+	assert.Empty(t, cites[1].URL)
+	assert.Equal(t, oid.OIDs{11, 12}, cites.DocIDs())
+}
@@ -24,6 +24,8 @@ type Conversation interface {
 	GetOID() oid.OID
 	GetChannel() string
 	SetTools(names ...string)
+	PinTools(names ...string)
+	PinnedTools() []string
 	Save(ctx context.Context) error
 	CountHistory(ctx context.Context) int
 	AddHistory(ctx context.Context, item *aigc.HistoryItem) error
@@ -124,6 +126,28 @@ func (s *conversation) SetTools(names ...string) {
 	}
 }
 
+const metaKeyPinnedTools = "pinnedTools"
+
+// PinTools pins the tool names (patterns allowed) for later turns of the conversation
+func (s *conversation) PinTools(names ...string) {
+	if len(names) > 0 {
+		s.sess.MetaSet(metaKeyPinnedTools, names)
+	}
+}
+
+// PinnedTools returns the pinned tool names, empty means unrestricted
+func (s *conversation) PinnedTools() []string {
+	v, ok := s.sess.MetaGet(metaKeyPinnedTools)
+	if !ok {
+		return nil
+	}
+	if names, ok := v.([]string); ok {
+		return names
+	}
+	names, _ := s.sess.Meta.GetStringSlice(metaKeyPinnedTools)
+	return names
+}
+
 // Save saves the conversation to the database
 func (s *conversation) Save(ctx context.Context) error {
 	count := s.CountHistory(ctx)
@@ -234,6 +258,21 @@ func ContextWithConvoID(ctx context.Context, csid string) context.Context {
 	return context.WithValue(ctx, convoIDKey, csid)
 }
 
+type channelKeyType struct{}
+
+// ChannelFromContext 从 context 获取来源渠道，Web 请求为空
+func ChannelFromContext(ctx context.Context) string {
+	if s, ok := ctx.Value(channelKeyType{}).(string); ok {
+		return s
+	}
+	return ""
+}
+
+// ContextWithChannel 将来源渠道添加到 context，用于判断知识库集合是否可见
+func ContextWithChannel(ctx context.Context, channel string) context.Context {
+	return context.WithValue(ctx, channelKeyType{}, channel)
+}
+
 // LoadPreset loads preset configuration from file
 func LoadPreset() (doc aigc.Preset, err error) {
 	if len(settings.Current.PresetFile) == 0 {
@@ -323,3 +323,30 @@ func TestCountHistory(t *testing.T) {
 		t.Errorf("expected 0 after clear, got %d", count)
 	}
 }
+
+func TestPinTools(t *testing.T) {
+	mr, conv := newTestConversation(t)
+	defer mr.Close()
+
+	if got := conv.PinnedTools(); len(got) != 0 {
+		t.Fatalf("expected no pinned tools, got %v", got)
+	}
+
+	conv.PinTools()
+	if got := conv.PinnedTools(); len(got) != 0 {
+		t.Fatalf("empty pin should be ignored, got %v", got)
+	}
+
+	conv.PinTools("kb_search", "github-*")
+	got := conv.PinnedTools()
+	if len(got) != 2 || got[0] != "kb_search" || got[1] != "github-*" {
+		t.Fatalf("unexpected pinned tools: %v", got)
+	}
+
+	// 从数据库加载后 meta 中为 []any
+	conv.(*conversation).sess.MetaSet(metaKeyPinnedTools, []any{"fetch"})
+	got = conv.PinnedTools()
+	if len(got) != 1 || got[0] != "fetch" {
+		t.Fatalf("unexpected pinned tools after load: %v", got)
+	}
+}
@@ -11,13 +11,14 @@ import (
 type ConvoUser = convo.User
 
 // type ConvoMemory = convo.Memory
+// type ConvoMemoryVector = convo.MemoryVector
 // type ConvoMessage = convo.Message
 // type ConvoSession = convo.Session
 // type ConvoThirdUser = convo.ThirdUser
 // type ConvoUsageRecord = convo.UsageRecord
 
 func init() {
-	RegisterModel((*convo.Session)(nil), (*convo.Message)(nil), (*convo.UsageRecord)(nil), (*convo.User)(nil), (*convo.ThirdUser)(nil), (*convo.Memory)(nil))
+	RegisterModel((*convo.Session)(nil), (*convo.Message)(nil), (*convo.UsageRecord)(nil), (*convo.User)(nil), (*convo.ThirdUser)(nil), (*convo.Memory)(nil), (*convo.MemoryVector)(nil), (*convo.ToolCall)(nil))
 }
 
 type ConvoStore interface {
@@ -48,10 +49,18 @@ type ConvoStore interface {
 	UpdateMemory(ctx context.Context, id string, in convo.MemorySet) error
 	DeleteMemory(ctx context.Context, id string) error
 
+	ListMemoryVector(ctx context.Context, spec *ConvoMemoryVectorSpec) (data convo.MemoryVectors, total int, err error)
+	GetMemoryVector(ctx context.Context, id string) (obj *convo.MemoryVector, err error)
+	CreateMemoryVector(ctx context.Context, in convo.MemoryVectorBasic) (obj *convo.MemoryVector, err error)
+
 	ListUsageRecord(ctx context.Context, spec *ConvoUsageRecordSpec) (data convo.UsageRecords, total int, err error)
 	GetUsageRecord(ctx context.Context, id string) (obj *convo.UsageRecord, err error)
 	CreateUsageRecord(ctx context.Context, in convo.UsageRecordBasic) (obj *convo.UsageRecord, err error)
 	DeleteUsageRecord(ctx context.Context, id string) error
+
+	ListToolCall(ctx context.Context, spec *ConvoToolCallSpec) (data convo.ToolCalls, total int, err error)
+	GetToolCall(ctx context.Context, id string) (obj *convo.ToolCall, err error)
+	CreateToolCall(ctx context.Context, in convo.ToolCallBasic) (obj *convo.ToolCall, err error)
 }
 
 type ConvoSessionSpec struct {
@@ -170,6 +179,27 @@ func (spec *ConvoMemorySpec) Sift(q *ormQuery) *ormQuery {
 	return q
 }
 
+type ConvoMemoryVectorSpec struct {
+	PageSpec
+	ModelSpec
+
+	// 记忆编号
+	MemID string `extensions:"x-order=A" form:"memID" json:"memID"`
+	// 所有人编号
+	OwnerID string `extensions:"x-order=B" form:"ownerID" json:"ownerID"`
+	// 嵌入模型
+	Model string `extensions:"x-order=C" form:"model" json:"model"`
+}
+
+func (spec *ConvoMemoryVectorSpec) Sift(q *ormQuery) *ormQuery {
+	q = spec.ModelSpec.Sift(q)
+	q, _ = siftOID(q, "mem_id", spec.MemID, false)
+	q, _ = siftOID(q, "owner_id", spec.OwnerID, false)
+	q, _ = siftEqual(q, "model", spec.Model, false)
+
+	return q
+}
+
 type ConvoUsageRecordSpec struct {
 	PageSpec
 	ModelSpec
@@ -185,6 +215,36 @@ func (spec *ConvoUsageRecordSpec) Sift(q *ormQuery) *ormQuery {
 	return q
 }
 
+type ConvoToolCallSpec struct {
+	PageSpec
+	ModelSpec
+
+	// 会话编号
+	SessionID string `extensions:"x-order=A" form:"session" json:"session"`
+	// 所有人编号
+	OwnerID string `extensions:"x-order=B" form:"ownerID" json:"ownerID"`
+	// 工具名
+	ToolName string `extensions:"x-order=C" form:"toolName" json:"toolName"`
+	// 来源 builtin, mcp:{server}, capability
+	Source string `extensions:"x-order=D" form:"source" json:"source"`
+	// 状态
+	//  * `ok` - 成功
+	//  * `failed` - 失败
+	//  * `error` - 异常
+	Status convo.ToolCallStatus `extensions:"x-order=E" form:"status" json:"status" swaggertype:"string"`
+}
+
+func (spec *ConvoToolCallSpec) Sift(q *ormQuery) *ormQuery {
+	q = spec.ModelSpec.Sift(q)
+	q, _ = siftOID(q, "session_id", spec.SessionID, false)
+	q, _ = siftOID(q, "owner_id", spec.OwnerID, false)
+	q, _ = siftMatch(q, "tool_name", spec.ToolName, false)
+	q, _ = siftEqual(q, "source", spec.Source, false)
+	q, _ = siftEqual(q, "status", spec.Status, false)
+
+	return q
+}
+
 type convoStore struct {
 	w *Wrap
 }
@@ -319,11 +379,40 @@ func (s *convoStore) UpdateMemory(ctx context.Context, id string, in convo.Memor
 	exist.SetIsUpdate(true)
 	exist.SetWith(in)
 	dbMetaUp(ctx, s.w.db, exist)
-	return dbUpdate(ctx, s.w.db, exist)
+	if err := dbUpdate(ctx, s.w.db, exist); err != nil {
+		return err
+	}
+	return s.afterUpdatedMemory(ctx, exist)
 }
 func (s *convoStore) DeleteMemory(ctx context.Context, id string) error {
 	obj := new(convo.Memory)
-	return s.w.db.DeleteModel(ctx, obj, id)
+	if err := dbGetWithPKID(ctx, s.w.db, obj, id); err != nil {
+		return err
+	}
+	return s.w.db.RunInTx(ctx, nil, func(ctx context.Context, tx pgTx) (err error) {
+		err = dbDeleteM(ctx, tx, s.w.db.Schema(), s.w.db.SchemaCrap(), obj)
+		if err != nil {
+			return
+		}
+		return dbAfterDeleteMemory(ctx, tx, obj)
+	})
+}
+
+func (s *convoStore) ListMemoryVector(ctx context.Context, spec *ConvoMemoryVectorSpec) (data convo.MemoryVectors, total int, err error) {
+	total, err = s.w.db.ListModel(ctx, spec, &data)
+	return
+}
+func (s *convoStore) GetMemoryVector(ctx context.Context, id string) (obj *convo.MemoryVector, err error) {
+	obj = new(convo.MemoryVector)
+	err = dbGetWithPKID(ctx, s.w.db, obj, id)
+
+	return
+}
+func (s *convoStore) CreateMemoryVector(ctx context.Context, in convo.MemoryVectorBasic) (obj *convo.MemoryVector, err error) {
+	obj = convo.NewMemoryVectorWithBasic(in)
+	dbMetaUp(ctx, s.w.db, obj)
+	err = dbInsert(ctx, s.w.db, obj)
+	return
 }
 
 func (s *convoStore) ListUsageRecord(ctx context.Context, spec *ConvoUsageRecordSpec) (data convo.UsageRecords, total int, err error) {
@@ -347,6 +436,23 @@ func (s *convoStore) DeleteUsageRecord(ctx context.Context, id string) error {
 	return s.w.db.DeleteModel(ctx, obj, id)
 }
 
+func (s *convoStore) ListToolCall(ctx context.Context, spec *ConvoToolCallSpec) (data convo.ToolCalls, total int, err error) {
+	total, err = s.w.db.ListModel(ctx, spec, &data)
+	return
+}
+func (s *convoStore) GetToolCall(ctx context.Context, id string) (obj *convo.ToolCall, err error) {
+	obj = new(convo.ToolCall)
+	err = dbGetWithPKID(ctx, s.w.db, obj, id)
+
+	return
+}
+func (s *convoStore) CreateToolCall(ctx context.Context, in convo.ToolCallBasic) (obj *convo.ToolCall, err error) {
+	obj = convo.NewToolCallWithBasic(in)
+	dbMetaUp(ctx, s.w.db, obj)
+	err = dbInsert(ctx, s.w.db, obj)
+	return
+}
+
 func GetUser(ctx context.Context, db ormDB, id string, cols ...string) (obj *convo.User, err error) {
 	obj = new(convo.User)
 	if err = dbGetWith(ctx, db, obj, "username", "ILIKE", id, cols...); err != nil && obj.SetID(id) {
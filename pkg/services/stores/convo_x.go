@@ -23,7 +23,7 @@ type ConvoStoreX interface {
 	GetMyMemoryWithKey(ctx context.Context, key string) (*convo.Memory, error)
 	ListMyMomory(ctx context.Context, spec *ConvoMemorySpec) (convo.Memories, error)
 	MatchMemories(ctx context.Context, ms MatchSpec) (data convo.Memories, err error)
-	SyncEmbeddingMemories(ctx context.Context, spec *ConvoMemorySpec) error
+	SyncEmbeddingMemories(ctx context.Context, spec *ConvoMemorySpec) (*SyncReport, error)
 
 	InvokerForMemoryList() mcps.Invoker
 	InvokerForMemoryRecall() mcps.Invoker
@@ -166,27 +166,56 @@ func (s *convoStore) GetMyMemoryWithKey(ctx context.Context, key string) (*convo
 }
 
 // afterCreatedMemory generates vector after memory creation
-// Uses the same DocVector table as CobDocument
 func (s *convoStore) afterCreatedMemory(ctx context.Context, obj *convo.Memory) error {
+	_, err := s.syncMemoryVector(ctx, obj)
+	return err
+}
+
+// afterUpdatedMemory regenerates vector when the subject changed
+func (s *convoStore) afterUpdatedMemory(ctx context.Context, obj *convo.Memory) error {
+	_, err := s.syncMemoryVector(ctx, obj)
+	return err
+}
+
+// syncMemoryVector 生成或更新记忆在当前向量空间中的向量，主题未变化的跳过
+func (s *convoStore) syncMemoryVector(ctx context.Context, obj *convo.Memory) (bool, error) {
 	subject := obj.GetSubject()
-	dvb := corpus.DocVectorBasic{
-		DocID:   obj.ID,
-		Subject: subject,
+	vs := VectorSpaceFromContext(ctx)
+	exist := new(convo.MemoryVector)
+	err := dbGetVector(ctx, s.w.db, exist, "mem_id", obj.ID, vs)
+	if err == nil && exist.Subject == subject && exist.Model == vs.Model {
+		return false, nil
 	}
-	vec, err := GetEmbedding(ctx, dvb.Subject)
-	if err != nil {
-		return err
+	vec, verr := GetEmbedding(ctx, subject)
+	if verr != nil {
+		return false, verr
 	}
-	if len(vec) > 0 {
-		dvb.Vector = vec
+	if err == nil {
+		exist.SetWith(convo.MemoryVectorSet{
+			Subject: &subject,
+			Vector:  &vec,
+			Model:   &vs.Model,
+		})
+		return true, dbUpdate(ctx, s.w.db, exist)
 	}
-
-	_, err = s.w.Corpus().CreateDocVector(ctx, dvb)
+	_, err = s.CreateMemoryVector(ctx, convo.MemoryVectorBasic{
+		MemID:   obj.ID,
+		OwnerID: obj.OwnerID,
+		Subject: subject,
+		Vector:  vec,
+		Model:   vs.Model,
+	})
 	if err != nil {
-		logger().Infow("create memory vector fail", "dvb", &dvb, "err", err)
-		return err
+		logger().Infow("create memory vector fail", "mem", obj.ID, "err", err)
+		return false, err
 	}
-	return nil
+	return true, nil
+}
+
+// dbAfterDeleteMemory cleans up vectors of the memory in all vector spaces
+func dbAfterDeleteMemory(ctx context.Context, db ormDB, obj *convo.Memory) error {
+	_, err := dbBatchDeleteWithKeyID(ctx, db, convo.MemoryVectorTable, "mem_id", obj.ID)
+	return err
 }
 
 func (spec *ConvoMemorySpec) SiftX(ctx context.Context, q *ormQuery) *ormQuery {
@@ -220,8 +249,12 @@ func (s *convoStore) ListMyMomory(ctx context.Context, spec *ConvoMemorySpec) (c
 	return data, err
 }
 
-// MatchMemories matches memories using vector similarity
+// MatchMemories matches memories of the current user using vector similarity
 func (s *convoStore) MatchMemories(ctx context.Context, ms MatchSpec) (data convo.Memories, err error) {
+	user, uok := UserFromContext(ctx)
+	if !uok {
+		return nil, errors.New("need login")
+	}
 	ms.setDefaults()
 
 	// Get embedding for the query
@@ -231,11 +264,11 @@ func (s *convoStore) MatchMemories(ctx context.Context, ms MatchSpec) (data conv
 		return
 	}
 
-	// Match vectors
+	// Match vectors of the owner
 	var ps corpus.DocMatches
-	ps, err = s.w.Corpus().MatchVectorWith(ctx, vec, ms.Threshold, ms.Limit)
+	err = scanVectorMatch(ctx, s.w.db, "memory", vec, ms.Threshold, rerankCandidates(RerankMemory, ms.Limit), &ps, oid.Cast(user.OID))
 	if err != nil || len(ps) == 0 {
-		logger().Infow("no match memories", "query", ms.Query)
+		logger().Infow("no match memories", "query", ms.Query, "err", err)
 		return
 	}
 
@@ -243,51 +276,29 @@ func (s *convoStore) MatchMemories(ctx context.Context, ms MatchSpec) (data conv
 
 	// Fetch memories by IDs
 	spec := &ConvoMemorySpec{IsFull: true}
+	spec.OwnerID = user.OID
 	spec.IDs = ps.DocumentIDs()
 	err = queryList(ctx, s.w.db, spec, &data).Scan(ctx)
 	if err != nil {
 		logger().Infow("list memories fail", "spec", spec, "err", err)
+		return
 	}
+	data = rerankSlice(ctx, RerankMemory, ms.Query, data, func(m *convo.Memory) string {
+		return m.Key + " " + m.Cate + " " + m.Content
+	}, ms.Limit)
 	return
 }
 
-// SyncEmbeddingMemories generates vectors for all memories
-func (s *convoStore) SyncEmbeddingMemories(ctx context.Context, spec *ConvoMemorySpec) error {
+// SyncEmbeddingMemories 分页为 spec 筛选的记忆生成向量，主题未变化的跳过，spec.Limit 为每页数量
+func (s *convoStore) SyncEmbeddingMemories(ctx context.Context, spec *ConvoMemorySpec) (*SyncReport, error) {
 	spec.IsFull = true
-	data, _, err := s.ListMemory(ctx, spec)
-	if err != nil {
-		return err
-	}
-
-	for _, mem := range data {
-		subject := mem.GetSubject()
-		vec, err := GetEmbedding(ctx, subject)
-		if err != nil {
-			return err
-		}
-
-		exist := new(corpus.DocVector)
-		err = dbGetWithUnique(ctx, s.w.db, exist, "doc_id", mem.ID)
-		if err == nil {
-			exist.SetWith(corpus.DocVectorSet{
-				Subject: &subject,
-				Vector:  &vec,
-			})
-			if err = dbUpdate(ctx, s.w.db, exist); err != nil {
-				return err
-			}
-		} else {
-			dv := corpus.NewDocVectorWithBasic(corpus.DocVectorBasic{
-				DocID:   mem.ID,
-				Subject: subject,
-				Vector:  vec,
-			})
-			if err = dbInsert(ctx, s.w.db, dv); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
+	return runEmbedSync(ctx, s.w.db, embedSyncJob[convo.Memory]{
+		target:   "mem",
+		spec:     spec,
+		pageSize: spec.Limit,
+		idOf:     func(m *convo.Memory) oid.OID { return m.ID },
+		sync:     s.syncMemoryVector,
+	})
 }
 
 // InvokerForMemoryList returns an invoker for listing memories
@@ -417,6 +428,9 @@ func (s *convoStore) InvokerForMemoryStore() mcps.Invoker {
 			if err := dbUpdate(ctx, s.w.db, existing); err != nil {
 				return mcps.BuildToolErrorResult(err.Error()), nil
 			}
+			if err := s.afterUpdatedMemory(ctx, existing); err != nil {
+				logger().Infow("update memory vector fail", "key", key, "err", err)
+			}
 			return mcps.BuildToolSuccessResult(map[string]any{
 				"action":   "updated",
 				"key":      key,
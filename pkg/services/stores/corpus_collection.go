@@ -0,0 +1,165 @@
+package stores
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cupogo/andvari/models/oid"
+
+	"github.com/liut/morign/pkg/models/corpus"
+)
+
+// ErrCollectionInUse the collection still has documents
+var ErrCollectionInUse = errors.New("collection is in use")
+
+// 按集合过滤向量匹配结果时多取的候选倍数
+const collectionFetchFactor = 4
+
+// docFilter 文档（别名 cd）的集合过滤条件，cond 为空时不限制
+type docFilter struct {
+	cond string
+	args []any
+}
+
+// docFilterOf 返回调用者可见集合的过滤条件，给出 collection 时只在该集合中检索
+func (s *corpuStore) docFilterOf(ctx context.Context, collection string) (f docFilter, err error) {
+	f.cond, f.args = collectionCond(ctx, "cd.collection_id")
+	if len(collection) > 0 {
+		var cid oid.OID
+		if cid, err = s.collectionIDOf(ctx, collection); err != nil {
+			return
+		}
+		if len(f.cond) > 0 {
+			f.cond += " AND "
+		}
+		f.cond += "cd.collection_id = ?"
+		f.args = append(f.args, cid)
+	}
+	return
+}
+
+// collectionRules 返回调用者可见集合的条件（别名 cc），all 为真时不限制。
+// 管理员及标记为受信任的调用（命令行、后台任务）不受限制；
+// 其余调用者可见未设置规则的集合，以及角色、用户或渠道任一规则匹配的集合，
+// 既无用户也无渠道的匿名调用只可见未设置规则的集合
+func collectionRules(ctx context.Context) (rules string, args []any, all bool) {
+	if IsKeeper(ctx) || IsTrusted(ctx) {
+		return "", nil, true
+	}
+	user, ok := UserFromContext(ctx)
+	channel := ChannelFromContext(ctx)
+	conds := []string{"(cc.roles = '[]' AND cc.users = '[]' AND cc.channels = '[]')"}
+	if ok && len(user.UID) > 0 {
+		conds = append(conds, "cc.users @> ?::jsonb")
+		args = append(args, jsonStrings(user.UID))
+	}
+	if ok && len(user.Roles) > 0 {
+		conds = append(conds, "EXISTS (SELECT 1 FROM jsonb_array_elements_text(cc.roles) r WHERE ?::jsonb @> to_jsonb(r))")
+		args = append(args, jsonStrings(user.Roles...))
+	}
+	if len(channel) > 0 {
+		conds = append(conds, "cc.channels @> ?::jsonb")
+		args = append(args, jsonStrings(channel))
+	}
+	return strings.Join(conds, " OR "), args, false
+}
+
+// collectionCond 返回限定文档集合列 col 为可见集合的条件，默认集合（编号为零）总是可见，不限制时为空
+func collectionCond(ctx context.Context, col string) (cond string, args []any) {
+	rules, args, all := collectionRules(ctx)
+	if all {
+		return "", nil
+	}
+	return "(" + col + " = 0 OR " + col + " IN (SELECT cc.id FROM " + corpus.CollectionTable +
+		" cc WHERE " + rules + "))", args
+}
+
+func jsonStrings(s ...string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// SiftX 非管理员只列出可见集合中的文档
+func (spec *CobDocumentSpec) SiftX(ctx context.Context, q *ormQuery) *ormQuery {
+	if cond, args := collectionCond(ctx, "cd.collection_id"); len(cond) > 0 {
+		q = q.Where(cond, args...)
+	}
+	return q
+}
+
+// afterLoadCobDocument 不可见集合中的文档视为不存在
+func (s *corpuStore) afterLoadCobDocument(ctx context.Context, obj *corpus.Document) error {
+	return s.checkCollectionVisible(ctx, obj.CollectionID)
+}
+
+// checkCollectionVisible 集合对调用者不可见时返回 ErrNotFound
+func (s *corpuStore) checkCollectionVisible(ctx context.Context, cid oid.OID) error {
+	if cid.IsZero() {
+		return nil
+	}
+	rules, args, all := collectionRules(ctx)
+	if all {
+		return nil
+	}
+	var visible bool
+	err := s.w.db.NewRaw("SELECT EXISTS (SELECT 1 FROM "+corpus.CollectionTable+" cc WHERE cc.id = ? AND ("+rules+"))",
+		append([]any{cid}, args...)...).Scan(ctx, &visible)
+	if err == nil && !visible {
+		err = ErrNotFound
+	}
+	return err
+}
+
+// CollectionScope 返回调用者可见集合的摘要，可见范围相同的调用者得到相同的值，不受限制时为 all
+func (s *corpuStore) CollectionScope(ctx context.Context) (string, error) {
+	rules, args, all := collectionRules(ctx)
+	if all {
+		return "all", nil
+	}
+	var ids []int64
+	err := s.w.db.NewRaw("SELECT cc.id FROM "+corpus.CollectionTable+" cc WHERE "+rules+" ORDER BY cc.id", args...).Scan(ctx, &ids)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(fmt.Append(nil, ids))
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// SiftX 非管理员只列出可见的集合
+func (spec *CobCollectionSpec) SiftX(ctx context.Context, q *ormQuery) *ormQuery {
+	if rules, args, all := collectionRules(ctx); !all {
+		q = q.Where("("+rules+")", args...)
+	}
+	return q
+}
+
+// collectionIDOf 按名称或编号查找集合，为空时为默认集合
+func (s *corpuStore) collectionIDOf(ctx context.Context, name string) (oid.OID, error) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return 0, nil
+	}
+	obj, err := s.GetCollection(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("collection %q: %w", name, err)
+	}
+	return obj.ID, nil
+}
+
+// dbBeforeDeleteCobCollection 集合中仍有文档时拒绝删除
+func dbBeforeDeleteCobCollection(ctx context.Context, db ormDB, obj *corpus.Collection) error {
+	n, err := db.NewSelect().Model((*corpus.Document)(nil)).
+		Where("collection_id = ?", obj.ID).Count(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: %d documents", ErrCollectionInUse, n)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package stores
+
+import (
+	"context"
+	"testing"
+
+	auth "github.com/liut/simpauth"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liut/morign/pkg/settings"
+)
+
+func TestCollectionRules(t *testing.T) {
+	orig := *settings.Current
+	defer func() { *settings.Current = orig }()
+	settings.Current.KeeperRole = "keeper"
+	settings.Current.KeeperUIDs = nil
+
+	// 受信任的命令行、后台任务不受限制
+	ctx := context.Background()
+	cond, args := collectionCond(ContextWithTrusted(ctx), "cd.collection_id")
+	assert.Empty(t, cond)
+	assert.Nil(t, args)
+
+	// 匿名调用只可见未设置规则的集合
+	rules, args, all := collectionRules(ctx)
+	assert.False(t, all)
+	assert.Equal(t, "(cc.roles = '[]' AND cc.users = '[]' AND cc.channels = '[]')", rules)
+	assert.Empty(t, args)
+
+	kctx := auth.ContextWithUser(ctx, &User{OID: "1", UID: "boss", Roles: []string{"keeper"}})
+	_, _, all = collectionRules(kctx)
+	assert.True(t, all)
+
+	cctx := ContextWithChannel(ctx, "wecom")
+	assert.Equal(t, "wecom", ChannelFromContext(cctx))
+	rules, args, all = collectionRules(cctx)
+	assert.False(t, all)
+	assert.Equal(t, "(cc.roles = '[]' AND cc.users = '[]' AND cc.channels = '[]') OR cc.channels @> ?::jsonb", rules)
+	assert.Equal(t, []any{`["wecom"]`}, args)
+
+	uctx := auth.ContextWithUser(ctx, &User{OID: "2", UID: "bob", Roles: []string{"contractor", "dev"}})
+	cond, args = collectionCond(uctx, "cd.collection_id")
+	assert.Contains(t, cond, "(cd.collection_id = 0 OR cd.collection_id IN (SELECT cc.id FROM corpus_collection cc WHERE ")
+	assert.Contains(t, cond, "OR cc.users @> ?::jsonb OR EXISTS (SELECT 1 FROM jsonb_array_elements_text(cc.roles) r")
+	assert.NotContains(t, cond, "cc.channels @>")
+	assert.Equal(t, []any{`["bob"]`, `["contractor","dev"]`}, args)
+}
@@ -9,13 +9,16 @@ import (
 )
 
 // type ChatLog = corpus.ChatLog
+// type CobCollection = corpus.Collection
 // type DocMatch = corpus.DocMatch
 // type DocMatches = corpus.DocMatches
 // type CobDocVector = corpus.DocVector
 // type CobDocument = corpus.Document
+// type CobRevision = corpus.Revision
+// type CobSource = corpus.Source
 
 func init() {
-	RegisterModel((*corpus.Document)(nil), (*corpus.DocVector)(nil), (*corpus.ChatLog)(nil))
+	RegisterModel((*corpus.Document)(nil), (*corpus.Source)(nil), (*corpus.Collection)(nil), (*corpus.Revision)(nil), (*corpus.DocVector)(nil), (*corpus.ChatLog)(nil))
 }
 
 type CorpuStore interface {
@@ -27,6 +30,21 @@ type CorpuStore interface {
 	UpdateDocument(ctx context.Context, id string, in corpus.DocumentSet) error
 	DeleteDocument(ctx context.Context, id string) error
 
+	ListSource(ctx context.Context, spec *CobSourceSpec) (data corpus.Sources, total int, err error)
+	GetSource(ctx context.Context, id string) (obj *corpus.Source, err error)
+	CreateSource(ctx context.Context, in corpus.SourceBasic) (obj *corpus.Source, err error)
+	UpdateSource(ctx context.Context, id string, in corpus.SourceSet) error
+	DeleteSource(ctx context.Context, id string) error
+
+	ListCollection(ctx context.Context, spec *CobCollectionSpec) (data corpus.Collections, total int, err error)
+	GetCollection(ctx context.Context, id string) (obj *corpus.Collection, err error)
+	CreateCollection(ctx context.Context, in corpus.CollectionBasic) (obj *corpus.Collection, err error)
+	UpdateCollection(ctx context.Context, id string, in corpus.CollectionSet) error
+	DeleteCollection(ctx context.Context, id string) error
+
+	ListRevision(ctx context.Context, spec *CobRevisionSpec) (data corpus.Revisions, total int, err error)
+	GetRevision(ctx context.Context, id string) (obj *corpus.Revision, err error)
+
 	GetDocVector(ctx context.Context, id string) (obj *corpus.DocVector, err error)
 	CreateDocVector(ctx context.Context, in corpus.DocVectorBasic) (obj *corpus.DocVector, err error)
 	DeleteDocVector(ctx context.Context, id string) error
@@ -47,6 +65,10 @@ type CobDocumentSpec struct {
 	Heading string `extensions:"x-order=B" form:"heading" json:"heading"`
 	// 内容 值
 	Content string `extensions:"x-order=C" form:"content" json:"content"`
+	// 来源文档编号 分块导入时有值
+	SourceID string `extensions:"x-order=D" form:"sourceID" json:"sourceID"`
+	// 所属集合编号 为零时属于公开的默认集合
+	CollectionID string `extensions:"x-order=E" form:"collectionID" json:"collectionID"`
 }
 
 func (spec *CobDocumentSpec) Sift(q *ormQuery) *ormQuery {
@@ -54,6 +76,8 @@ func (spec *CobDocumentSpec) Sift(q *ormQuery) *ormQuery {
 	q, _ = siftMatch(q, "title", spec.Title, false)
 	q, _ = siftMatch(q, "heading", spec.Heading, false)
 	q, _ = siftMatch(q, "content", spec.Content, false)
+	q, _ = siftOID(q, "source_id", spec.SourceID, false)
+	q, _ = siftOID(q, "collection_id", spec.CollectionID, false)
 
 	return q
 }
@@ -66,6 +90,66 @@ func (spec *CobDocumentSpec) CanSort(k string) bool {
 	}
 }
 
+type CobSourceSpec struct {
+	PageSpec
+	ModelSpec
+
+	// 名称 通常为文件名，同名再次导入时替换其分块
+	Name string `extensions:"x-order=A" form:"name" json:"name"`
+	// 标题 取自文档一级标题或文件名
+	Title string `extensions:"x-order=B" form:"title" json:"title"`
+	// 格式 md, html, pdf, docx, txt
+	Format string `extensions:"x-order=C" form:"format" json:"format"`
+}
+
+func (spec *CobSourceSpec) Sift(q *ormQuery) *ormQuery {
+	q = spec.ModelSpec.Sift(q)
+	q, _ = siftEqual(q, "name", spec.Name, false)
+	q, _ = siftMatch(q, "title", spec.Title, false)
+	q, _ = siftEqual(q, "format", spec.Format, false)
+
+	return q
+}
+
+type CobCollectionSpec struct {
+	PageSpec
+	ModelSpec
+
+	// 名称 唯一，导入导出时用于指定集合
+	Name string `extensions:"x-order=A" form:"name" json:"name"`
+	// 标题
+	Title string `extensions:"x-order=B" form:"title" json:"title"`
+}
+
+func (spec *CobCollectionSpec) Sift(q *ormQuery) *ormQuery {
+	q = spec.ModelSpec.Sift(q)
+	q, _ = siftEqual(q, "name", spec.Name, false)
+	q, _ = siftMatch(q, "title", spec.Title, false)
+
+	return q
+}
+
+type CobRevisionSpec struct {
+	PageSpec
+	ModelSpec
+
+	// 文档编号
+	DocID string `extensions:"x-order=A" form:"docID" json:"docID"`
+	// 作者 修改者的用户名，命令行导入时为空
+	Author string `extensions:"x-order=B" form:"author" json:"author"`
+	// 来源 import, api, tool, restore, baseline
+	Source string `extensions:"x-order=C" form:"source" json:"source"`
+}
+
+func (spec *CobRevisionSpec) Sift(q *ormQuery) *ormQuery {
+	q = spec.ModelSpec.Sift(q)
+	q, _ = siftOID(q, "doc_id", spec.DocID, false)
+	q, _ = siftEqual(q, "author", spec.Author, false)
+	q, _ = siftEqual(q, "source", spec.Source, false)
+
+	return q
+}
+
 type ChatLogSpec struct {
 	PageSpec
 	ModelSpec
@@ -92,7 +176,9 @@ func (s *corpuStore) ListDocument(ctx context.Context, spec *CobDocumentSpec) (d
 func (s *corpuStore) GetDocument(ctx context.Context, id string) (obj *corpus.Document, err error) {
 	obj = new(corpus.Document)
 	err = dbGetWithPKID(ctx, s.w.db, obj, id)
-
+	if err == nil {
+		err = s.afterLoadCobDocument(ctx, obj)
+	}
 	return
 }
 func (s *corpuStore) CreateDocument(ctx context.Context, in corpus.DocumentBasic) (obj *corpus.Document, err error) {
@@ -112,7 +198,10 @@ func (s *corpuStore) UpdateDocument(ctx context.Context, id string, in corpus.Do
 	exist.SetIsUpdate(true)
 	exist.SetWith(in)
 	dbMetaUp(ctx, s.w.db, exist)
-	return dbUpdate(ctx, s.w.db, exist)
+	if err := dbUpdate(ctx, s.w.db, exist); err != nil {
+		return err
+	}
+	return s.afterUpdatedCobDocument(ctx, exist)
 }
 func (s *corpuStore) DeleteDocument(ctx context.Context, id string) error {
 	obj := new(corpus.Document)
@@ -128,6 +217,111 @@ func (s *corpuStore) DeleteDocument(ctx context.Context, id string) error {
 	})
 }
 
+func (s *corpuStore) ListSource(ctx context.Context, spec *CobSourceSpec) (data corpus.Sources, total int, err error) {
+	total, err = s.w.db.ListModel(ctx, spec, &data)
+	return
+}
+func (s *corpuStore) GetSource(ctx context.Context, id string) (obj *corpus.Source, err error) {
+	obj = new(corpus.Source)
+	if err = dbGetWith(ctx, s.w.db, obj, "name", "=", id); err != nil && obj.SetID(id) {
+		err = dbGetWithPK(ctx, s.w.db, obj)
+	}
+
+	return
+}
+func (s *corpuStore) CreateSource(ctx context.Context, in corpus.SourceBasic) (obj *corpus.Source, err error) {
+	obj = corpus.NewSourceWithBasic(in)
+	if obj.Name == "" {
+		err = ErrEmptyKey
+		return
+	}
+	dbMetaUp(ctx, s.w.db, obj)
+	err = dbInsert(ctx, s.w.db, obj, "name")
+	return
+}
+func (s *corpuStore) UpdateSource(ctx context.Context, id string, in corpus.SourceSet) error {
+	exist := new(corpus.Source)
+	if err := dbGetWithPKID(ctx, s.w.db, exist, id); err != nil {
+		return err
+	}
+	exist.SetIsUpdate(true)
+	exist.SetWith(in)
+	dbMetaUp(ctx, s.w.db, exist)
+	return dbUpdate(ctx, s.w.db, exist)
+}
+func (s *corpuStore) DeleteSource(ctx context.Context, id string) error {
+	obj := new(corpus.Source)
+	if err := dbGetWithPKID(ctx, s.w.db, obj, id); err != nil {
+		return err
+	}
+	return s.w.db.RunInTx(ctx, nil, func(ctx context.Context, tx pgTx) (err error) {
+		if err = dbBeforeDeleteCobSource(ctx, tx, obj); err != nil {
+			return
+		}
+		err = dbDeleteM(ctx, tx, s.w.db.Schema(), s.w.db.SchemaCrap(), obj)
+		return
+	})
+}
+
+func (s *corpuStore) ListCollection(ctx context.Context, spec *CobCollectionSpec) (data corpus.Collections, total int, err error) {
+	total, err = s.w.db.ListModel(ctx, spec, &data)
+	return
+}
+func (s *corpuStore) GetCollection(ctx context.Context, id string) (obj *corpus.Collection, err error) {
+	obj = new(corpus.Collection)
+	if err = dbGetWith(ctx, s.w.db, obj, "name", "=", id); err != nil && obj.SetID(id) {
+		err = dbGetWithPK(ctx, s.w.db, obj)
+	}
+
+	return
+}
+func (s *corpuStore) CreateCollection(ctx context.Context, in corpus.CollectionBasic) (obj *corpus.Collection, err error) {
+	obj = corpus.NewCollectionWithBasic(in)
+	if obj.Name == "" {
+		err = ErrEmptyKey
+		return
+	}
+	dbMetaUp(ctx, s.w.db, obj)
+	err = dbInsert(ctx, s.w.db, obj, "name")
+	return
+}
+func (s *corpuStore) UpdateCollection(ctx context.Context, id string, in corpus.CollectionSet) error {
+	exist := new(corpus.Collection)
+	if err := dbGetWithPKID(ctx, s.w.db, exist, id); err != nil {
+		return err
+	}
+	exist.SetIsUpdate(true)
+	exist.SetWith(in)
+	dbMetaUp(ctx, s.w.db, exist)
+	return dbUpdate(ctx, s.w.db, exist)
+}
+func (s *corpuStore) DeleteCollection(ctx context.Context, id string) error {
+	obj := new(corpus.Collection)
+	if err := dbGetWithPKID(ctx, s.w.db, obj, id); err != nil {
+		return err
+	}
+	return s.w.db.RunInTx(ctx, nil, func(ctx context.Context, tx pgTx) (err error) {
+		if err = dbBeforeDeleteCobCollection(ctx, tx, obj); err != nil {
+			return
+		}
+		err = dbDeleteM(ctx, tx, s.w.db.Schema(), s.w.db.SchemaCrap(), obj)
+		return
+	})
+}
+
+func (s *corpuStore) ListRevision(ctx context.Context, spec *CobRevisionSpec) (data corpus.Revisions, total int, err error) {
+	total, err = s.w.db.ListModel(ctx, spec, &data)
+	return
+}
+func (s *corpuStore) GetRevision(ctx context.Context, id string) (obj *corpus.Revision, err error) {
+	obj = new(corpus.Revision)
+	err = dbGetWithPKID(ctx, s.w.db, obj, id)
+	if err == nil {
+		err = s.afterLoadCobRevision(ctx, obj)
+	}
+	return
+}
+
 func (s *corpuStore) GetDocVector(ctx context.Context, id string) (obj *corpus.DocVector, err error) {
 	obj = new(corpus.DocVector)
 	err = dbGetWithPKID(ctx, s.w.db, obj, id)
@@ -0,0 +1,117 @@
+package stores
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/cupogo/andvari/models/oid"
+
+	"github.com/liut/morign/pkg/models/corpus"
+)
+
+// 未配置 PgTSConfig 或配置无效时，以 simple 配置即时生成 tsvector，
+// 可以匹配错误码、型号、接口名等按空白和标点分隔的词
+const docTextExpr = "cd.title || ' ' || cd.heading || ' ' || cd.content"
+
+// MatchTextWith 以全文检索匹配调用者可见集合中的文档，按 ts_rank_cd 排序
+func (s *corpuStore) MatchTextWith(ctx context.Context, query string, limit int) (data corpus.DocMatches, err error) {
+	f, err := s.docFilterOf(ctx, "")
+	if err != nil {
+		return
+	}
+	return s.matchText(ctx, query, limit, f)
+}
+
+func (s *corpuStore) matchText(ctx context.Context, query string, limit int, f docFilter) (data corpus.DocMatches, err error) {
+	query = strings.TrimSpace(query)
+	if len(query) == 0 {
+		return
+	}
+	cfg, ok := s.w.db.GetTsCfg()
+	vec := "cd.ts_vec"
+	if !ok {
+		cfg = "simple"
+		vec = "to_tsvector('simple', " + docTextExpr + ")"
+	}
+	where := vec + " @@ tq"
+	args := []any{cfg, query}
+	if len(f.cond) > 0 {
+		where += " AND " + f.cond
+		args = append(args, f.args...)
+	}
+	err = s.w.db.NewRaw("SELECT cd.id AS doc_id, cd.title || ' ' || cd.heading AS subject, "+
+		"ts_rank_cd("+vec+", tq) AS similarity "+
+		"FROM "+corpus.DocumentTable+" cd, websearch_to_tsquery(?::regconfig, ?) tq "+
+		"WHERE "+where+" ORDER BY similarity DESC LIMIT ?", append(args, limit)...).
+		Scan(ctx, &data)
+	if err != nil {
+		logger().Infow("match text fail", "cfg", cfg, "q", query, "err", err)
+	} else {
+		logger().Debugw("match text ok", "cfg", cfg, "q", query, "data", data)
+	}
+	return
+}
+
+// refreshTextVector 重新生成文档的 ts_vec，用于补齐启用全文检索前已存在的文档
+func (s *corpuStore) refreshTextVector(ctx context.Context, ids oid.OIDs) error {
+	cfg, ok := s.w.db.GetTsCfg()
+	if !ok || len(ids) == 0 {
+		return nil
+	}
+	_, err := s.w.db.NewRaw("UPDATE "+corpus.DocumentTable+" cd SET ts_cfg = ?, ts_vec = to_tsvector(?::regconfig, "+
+		docTextExpr+") WHERE cd.id IN (?)", cfg, cfg, pgIn(ids)).Exec(ctx)
+	return err
+}
+
+type rankedMatches struct {
+	matches corpus.DocMatches
+	weight  float32
+}
+
+// fuseRRF 以加权倒数排名融合（Reciprocal Rank Fusion）合并多路检索结果，
+// 得分为 Σ weight / (k + rank)，rank 从 1 开始，结果的 Similarity 为融合得分
+func fuseRRF(k, limit int, lists ...rankedMatches) (out corpus.DocMatches) {
+	if k <= 0 {
+		k = 60
+	}
+	idx := make(map[oid.OID]int)
+	for _, rm := range lists {
+		if rm.weight <= 0 {
+			continue
+		}
+		for rank, m := range rm.matches {
+			score := rm.weight / float32(k+rank+1)
+			if i, ok := idx[m.DocID]; ok {
+				out[i].Similarity += score
+				continue
+			}
+			idx[m.DocID] = len(out)
+			out = append(out, corpus.DocMatch{DocID: m.DocID, Subject: m.Subject, Similarity: score})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Similarity > out[j].Similarity
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return
+}
+
+// sortDocumentsBy 按匹配结果的顺序排列文档，并带上匹配得分
+func sortDocumentsBy(data corpus.Documents, ps corpus.DocMatches) corpus.Documents {
+	order := make(map[oid.OID]int, len(ps))
+	for i, p := range ps {
+		order[p.DocID] = i
+	}
+	for i := range data {
+		if j, ok := order[data[i].ID]; ok {
+			data[i].Similarity = ps[j].Similarity
+		}
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		return order[data[i].ID] < order[data[j].ID]
+	})
+	return data
+}
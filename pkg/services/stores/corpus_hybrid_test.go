@@ -0,0 +1,73 @@
+package stores
+
+import (
+	"testing"
+
+	"github.com/cupogo/andvari/models/oid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liut/morign/pkg/models/corpus"
+	"github.com/liut/morign/pkg/settings"
+)
+
+func TestFuseRRF(t *testing.T) {
+	a, b, c, d := oid.OID(1), oid.OID(2), oid.OID(3), oid.OID(4)
+	vms := corpus.DocMatches{{DocID: a, Subject: "a"}, {DocID: b, Subject: "b"}, {DocID: c, Subject: "c"}}
+	tms := corpus.DocMatches{{DocID: d, Subject: "d"}, {DocID: b, Subject: "b"}}
+
+	out := fuseRRF(60, 0, rankedMatches{vms, 1}, rankedMatches{tms, 1})
+	assert.Len(t, out, 4)
+	// b 在两路中都出现，排在最前
+	assert.Equal(t, b, out[0].DocID)
+	assert.InDelta(t, 1.0/62+1.0/62, out[0].Similarity, 1e-6)
+	// a 与 d 同为第一名，得分相同时保持原有顺序
+	assert.Equal(t, a, out[1].DocID)
+	assert.Equal(t, d, out[2].DocID)
+	assert.Equal(t, c, out[3].DocID)
+
+	// 全文检索权重更高时，d 排在 a 之前
+	out = fuseRRF(60, 2, rankedMatches{vms, 1}, rankedMatches{tms, 2})
+	assert.Len(t, out, 2)
+	assert.Equal(t, b, out[0].DocID)
+	assert.Equal(t, d, out[1].DocID)
+
+	// 权重为 0 的一路被忽略
+	out = fuseRRF(60, 0, rankedMatches{vms, 0}, rankedMatches{tms, 1})
+	assert.Len(t, out, 2)
+	assert.Equal(t, d, out[0].DocID)
+	assert.Equal(t, b, out[1].DocID)
+
+	assert.Empty(t, fuseRRF(60, 5))
+}
+
+func TestSortDocumentsBy(t *testing.T) {
+	var data corpus.Documents
+	for _, id := range []oid.OID{1, 2, 3} {
+		doc := corpus.Document{}
+		doc.ID = id
+		data = append(data, doc)
+	}
+	ps := corpus.DocMatches{{DocID: 3, Similarity: 0.3}, {DocID: 1, Similarity: 0.2}, {DocID: 2, Similarity: 0.1}}
+	data = sortDocumentsBy(data, ps)
+	assert.Equal(t, oid.OIDs{3, 1, 2}, data.IDs())
+	assert.Equal(t, float32(0.3), data[0].Similarity)
+	assert.Equal(t, float32(0.1), data[2].Similarity)
+}
+
+func TestMatchSpecDefaults(t *testing.T) {
+	orig := *settings.Current
+	defer func() { *settings.Current = orig }()
+	settings.Current.HybridVectorWeight = 1
+	settings.Current.HybridTextWeight = 0.5
+
+	ms := MatchSpec{Query: "E1024"}
+	ms.setDefaults()
+	assert.Equal(t, float32(1), ms.VectorWeight)
+	assert.Equal(t, float32(0.5), ms.TextWeight)
+
+	// 指定任一权重时不使用默认值，另一路权重为 0 即不参与检索
+	ms = MatchSpec{Query: "E1024", TextWeight: 1}
+	ms.setDefaults()
+	assert.Equal(t, float32(0), ms.VectorWeight)
+	assert.Equal(t, float32(1), ms.TextWeight)
+}
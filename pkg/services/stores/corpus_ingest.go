@@ -0,0 +1,192 @@
+package stores
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/liut/morign/pkg/models/corpus"
+	"github.com/liut/morign/pkg/settings"
+	"github.com/liut/morign/pkg/utils/doctext"
+)
+
+// IngestArg is the arguments for document ingestion
+type IngestArg struct {
+	// 名称 通常为文件名，同名再次导入时替换其分块
+	Name string
+	// 标题 为空时取文档唯一的一级标题或文件名
+	Title string
+	// 格式 为空时按名称扩展名判断
+	Format string
+	Data   []byte
+	// 分块大小和重叠字符数，默认 IngestChunkSize 和 IngestChunkOverlap
+	ChunkSize int
+	Overlap   int
+	// 集合名称或编号，为空时为默认集合
+	Collection string
+	// 来源地址 可选，记入分块元数据供回答引用时链接
+	URL string
+}
+
+// IngestResult is the summary of a document ingestion
+type IngestResult struct {
+	Source *corpus.Source `json:"source"`
+	// 内容未变化，跳过
+	Unchanged bool `json:"unchanged,omitempty"`
+}
+
+// IngestDocument 解析文档并按标题切分为分块，分块作为知识库文档关联到来源文档。
+// 同名来源已存在且内容未变化时跳过，否则替换其全部分块。
+func (s *corpuStore) IngestDocument(ctx context.Context, ia IngestArg) (*IngestResult, error) {
+	ia.Name = strings.TrimSpace(ia.Name)
+	if len(ia.Name) == 0 {
+		return nil, ErrEmptyKey
+	}
+	if len(ia.Data) == 0 {
+		return nil, ErrEmptyParam
+	}
+	if len(ia.Format) == 0 {
+		ia.Format = doctext.FormatOf(ia.Name)
+	}
+	if ia.ChunkSize <= 0 {
+		ia.ChunkSize = settings.Current.IngestChunkSize
+	}
+	if ia.Overlap <= 0 {
+		ia.Overlap = settings.Current.IngestChunkOverlap
+	}
+
+	cid, err := s.collectionIDOf(ctx, ia.Collection)
+	if err != nil {
+		return nil, err
+	}
+	ctx = ContextWithRevisionSource(ctx, corpus.RevisionSourceImport)
+
+	md, err := doctext.ToMarkdown(ia.Format, ia.Data)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum(ia.Data)
+	hash := hex.EncodeToString(sum[:])
+
+	src, err := s.GetSource(ctx, ia.Name)
+	if err != nil && !errors.Is(err, ErrNoRows) {
+		return nil, err
+	}
+	if err == nil && src.Hash == hash && (len(ia.Title) == 0 || ia.Title == src.Title) && sourceURL(src) == ia.URL {
+		// 换到其他集合时仍需重新导入
+		moved, err := dbExists(ctx, s.w.db, (*corpus.Document)(nil), "source_id = ? AND collection_id <> ?", src.ID, cid)
+		if err != nil {
+			return nil, err
+		}
+		if !moved {
+			logger().Infow("ingest unchanged, skip", "name", ia.Name, "hash", hash)
+			return &IngestResult{Source: src, Unchanged: true}, nil
+		}
+	}
+
+	h1, chunks := doctext.SplitMarkdown(md, ia.ChunkSize, ia.Overlap)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: no content in %s", ErrEmptyParam, ia.Name)
+	}
+	title := ia.Title
+	if len(title) == 0 {
+		title = h1
+	}
+	if len(title) == 0 {
+		title = strings.TrimSuffix(filepath.Base(ia.Name), filepath.Ext(ia.Name))
+	}
+
+	// 摘要在全部分块写入后才记下，中途失败时下次导入不会被当作未变化而跳过
+	size := len(ia.Data)
+	if src == nil {
+		sb := corpus.SourceBasic{
+			Name: ia.Name, Title: title, Format: ia.Format, Size: size,
+		}
+		if len(ia.URL) > 0 {
+			sb.MetaAddKVs(corpus.MetaKeyURL, ia.URL)
+		}
+		src, err = s.CreateSource(ctx, sb)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var noHash string
+		set := corpus.SourceSet{
+			Title: &title, Format: &ia.Format, Size: &size, Hash: &noHash,
+		}
+		set.MetaAddKVs(corpus.MetaKeyURL, ia.URL)
+		err = s.UpdateSource(ctx, src.StringID(), set)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.w.db.RunInTx(ctx, nil, func(ctx context.Context, tx pgTx) error {
+			return dbBeforeDeleteCobSource(ctx, tx, src)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	seen := make(map[string]int, len(chunks))
+	for _, c := range chunks {
+		heading := c.Heading()
+		if n := seen[heading]; n > 0 {
+			seen[heading] = n + 1
+			heading += " #" + strconv.Itoa(n+1)
+		} else {
+			seen[heading] = 1
+		}
+		doc := corpus.DocumentBasic{
+			Title:        title,
+			Heading:      heading,
+			Content:      c.Text,
+			SourceID:     src.ID,
+			CollectionID: cid,
+		}
+		if len(ia.URL) > 0 {
+			doc.MetaAddKVs(corpus.MetaKeyURL, ia.URL)
+		}
+		_, err = s.CreateDocument(ctx, doc)
+		if err != nil {
+			logger().Infow("ingest chunk fail", "name", ia.Name, "heading", heading, "err", err)
+			return nil, err
+		}
+	}
+
+	count := len(chunks)
+	if err = s.UpdateSource(ctx, src.StringID(), corpus.SourceSet{Chunks: &count, Hash: &hash}); err != nil {
+		return nil, err
+	}
+	logger().Infow("ingested", "name", ia.Name, "title", title, "format", ia.Format, "chunks", count)
+
+	src, err = s.GetSource(ctx, src.StringID())
+	if err != nil {
+		return nil, err
+	}
+	return &IngestResult{Source: src}, nil
+}
+
+// sourceURL 返回来源文档记录的来源地址
+func sourceURL(src *corpus.Source) string {
+	if v, ok := src.MetaGet(corpus.MetaKeyURL); ok {
+		s, _ := v.(string)
+		return s
+	}
+	return ""
+}
+
+// dbBeforeDeleteCobSource 删除来源文档的全部分块及其向量，修订保留
+func dbBeforeDeleteCobSource(ctx context.Context, db ormDB, obj *corpus.Source) error {
+	_, err := db.NewDelete().Model((*corpus.DocVector)(nil)).
+		Where("doc_id IN (SELECT id FROM "+corpus.DocumentTable+" WHERE source_id = ?)", obj.ID).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = db.NewDelete().Model((*corpus.Document)(nil)).
+		Where("source_id = ?", obj.ID).Exec(ctx)
+	return err
+}
@@ -0,0 +1,141 @@
+package stores
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cupogo/andvari/models/oid"
+
+	"github.com/liut/morign/pkg/models/corpus"
+)
+
+// ErrRevisionMismatch the revisions belong to different documents
+var ErrRevisionMismatch = errors.New("revisions of different documents")
+
+type revisionKeyType struct{}
+
+// revisionOrigin 文档修订的来源，恢复时带有被恢复的修订编号
+type revisionOrigin struct {
+	source string
+	from   oid.OID
+}
+
+// ContextWithRevisionSource 设置此后文档修订的来源，未设置时为 api
+func ContextWithRevisionSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, revisionKeyType{}, revisionOrigin{source: source})
+}
+
+func revisionOriginFromContext(ctx context.Context) revisionOrigin {
+	if ro, ok := ctx.Value(revisionKeyType{}).(revisionOrigin); ok && len(ro.source) > 0 {
+		return ro
+	}
+	return revisionOrigin{source: corpus.RevisionSourceAPI}
+}
+
+// RevisionDiff 修订与另一修订或文档当前内容之间的差异
+type RevisionDiff struct {
+	DocID oid.OID `json:"docID" swaggertype:"string"`
+	// 修订编号
+	From string `json:"from"`
+	// 对比的修订编号，为 current 时是文档当前内容
+	To string `json:"to"`
+	// unified diff，无差异时为空
+	Diff string `json:"diff"`
+} // @name corpusRevisionDiff
+
+// SiftX 非管理员只列出可见集合中文档的修订
+func (spec *CobRevisionSpec) SiftX(ctx context.Context, q *ormQuery) *ormQuery {
+	if cond, args := collectionCond(ctx, "cd.collection_id"); len(cond) > 0 {
+		q = q.Where("doc_id IN (SELECT cd.id FROM "+corpus.DocumentTable+" cd WHERE "+cond+")", args...)
+	}
+	return q
+}
+
+// afterLoadCobRevision 文档不可见或已删除时，非管理员读不到其修订
+func (s *corpuStore) afterLoadCobRevision(ctx context.Context, obj *corpus.Revision) error {
+	if _, _, all := collectionRules(ctx); all {
+		return nil
+	}
+	_, err := s.GetDocument(ctx, obj.DocID.String())
+	return err
+}
+
+// recordRevision 文档创建，或标题、小节、内容变化时记下快照
+func (s *corpuStore) recordRevision(ctx context.Context, doc *corpus.Document) error {
+	if doc.IsUpdate() && !doc.HasChange("title") && !doc.HasChange("heading") && !doc.HasChange("content") {
+		return nil
+	}
+	var author string
+	if user, ok := UserFromContext(ctx); ok {
+		author = user.UID
+	}
+	ro := revisionOriginFromContext(ctx)
+	rev := corpus.NewRevisionOf(doc, author, ro.source)
+	if !ro.from.IsZero() {
+		rev.MetaSet("restoredFrom", ro.from.String())
+	}
+	if err := dbInsert(ctx, s.w.db, rev); err != nil {
+		logger().Infow("record revision fail", "doc", doc.ID, "err", err)
+		return err
+	}
+	return nil
+}
+
+// DiffRevision 对比修订与 to 指定的修订，to 为空时与文档当前内容对比
+func (s *corpuStore) DiffRevision(ctx context.Context, id, to string) (*RevisionDiff, error) {
+	rev, err := s.GetRevision(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rd := &RevisionDiff{DocID: rev.DocID, From: rev.StringID()}
+	var text string
+	if len(to) == 0 {
+		doc, err := s.GetDocument(ctx, rev.DocID.String())
+		if err != nil {
+			return nil, err
+		}
+		rd.To, text = "current", doc.GetFullText()
+	} else {
+		other, err := s.GetRevision(ctx, to)
+		if err != nil {
+			return nil, err
+		}
+		if other.DocID != rev.DocID {
+			return nil, ErrRevisionMismatch
+		}
+		rd.To, text = other.StringID(), other.GetFullText()
+	}
+	if from := rev.GetFullText(); from != text {
+		rd.Diff = diff2(from, text)
+	}
+	return rd, nil
+}
+
+// RestoreRevision 将文档恢复为修订的标题、小节和内容，向量随更新重新生成
+func (s *corpuStore) RestoreRevision(ctx context.Context, id string) (*corpus.Document, error) {
+	rev, err := s.GetRevision(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.GetDocument(ctx, rev.DocID.String())
+	if err != nil {
+		return nil, err
+	}
+	if rev.SameAs(doc) {
+		return doc, nil
+	}
+	ctx = context.WithValue(ctx, revisionKeyType{}, revisionOrigin{source: corpus.RevisionSourceRestore, from: rev.ID})
+	err = s.UpdateDocument(ctx, doc.StringID(), corpus.DocumentSet{
+		Title:   &rev.Title,
+		Heading: &rev.Heading,
+		Content: &rev.Content,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = s.GetDocument(ctx, doc.StringID()); err != nil {
+		return nil, err
+	}
+	logger().Infow("restored", "doc", doc.ID, "revision", rev.ID)
+	return doc, nil
+}
@@ -0,0 +1,32 @@
+package stores
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liut/morign/pkg/models/corpus"
+)
+
+func TestRevisionOrigin(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, corpus.RevisionSourceAPI, revisionOriginFromContext(ctx).source)
+
+	ctx = ContextWithRevisionSource(ctx, corpus.RevisionSourceImport)
+	ro := revisionOriginFromContext(ctx)
+	assert.Equal(t, corpus.RevisionSourceImport, ro.source)
+	assert.True(t, ro.from.IsZero())
+
+	doc := &corpus.Document{}
+	doc.ID = 7
+	doc.Title, doc.Heading, doc.Content = "t", "h", "c"
+	rev := corpus.NewRevisionOf(doc, "alice", ro.source)
+	assert.Equal(t, doc.ID, rev.DocID)
+	assert.Equal(t, doc.GetFullText(), rev.GetFullText())
+	assert.True(t, rev.SameAs(doc))
+
+	doc.Content = "c2"
+	assert.False(t, rev.SameAs(doc))
+	assert.Contains(t, diff2(rev.GetFullText(), doc.GetFullText()), "+c2")
+}
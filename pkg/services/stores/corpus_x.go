@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
+	"github.com/cupogo/andvari/models/oid"
 	"github.com/pmezard/go-difflib/difflib"
 	"github.com/spf13/cast"
 
@@ -33,13 +35,23 @@ var (
 
 // MatchSpec defines the document matching specification
 type MatchSpec struct {
-	Query        string
-	Threshold    float32
-	Limit        int
-	SkipKeywords bool
+	// 查询语句
+	Query string `form:"q" json:"q"`
+	// 相似度阈值，默认 VectorThreshold
+	Threshold float32 `form:"threshold" json:"threshold,omitempty"`
+	// 匹配数量，默认 VectorLimit
+	Limit int `form:"limit" json:"limit,omitempty"`
+	// 跳过关键词提取，直接以查询语句生成向量
+	SkipKeywords bool `form:"skipKeywords" json:"skipKeywords,omitempty"`
+	// 向量检索权重，与 TextWeight 均为 0 时使用 HybridVectorWeight，仅用于文档
+	VectorWeight float32 `form:"vw" json:"vw,omitempty"`
+	// 全文检索权重，与 VectorWeight 均为 0 时使用 HybridTextWeight，仅用于文档
+	TextWeight float32 `form:"tw" json:"tw,omitempty"`
+	// 集合名称或编号，只在该集合中检索，仅用于文档
+	Collection string `form:"collection" json:"collection,omitempty"`
 }
 
-// setDefaults sets default threshold and limit
+// setDefaults sets default threshold, limit and weights
 func (ms *MatchSpec) setDefaults() {
 	if ms.Threshold == 0 {
 		ms.Threshold = settings.Current.VectorThreshold
@@ -47,6 +59,10 @@ func (ms *MatchSpec) setDefaults() {
 	if ms.Limit == 0 {
 		ms.Limit = settings.Current.VectorLimit
 	}
+	if ms.VectorWeight == 0 && ms.TextWeight == 0 {
+		ms.VectorWeight = settings.Current.HybridVectorWeight
+		ms.TextWeight = settings.Current.HybridTextWeight
+	}
 }
 
 // ExportArg is the arguments for document export
@@ -54,6 +70,8 @@ type ExportArg struct {
 	Spec   *CobDocumentSpec
 	Out    io.Writer
 	Format string // csv,jsonl
+	// 集合名称或编号，为空时不限
+	Collection string
 }
 
 // validHead validates if CSV header is valid
@@ -73,17 +91,26 @@ func validHead(rec []string) bool {
 
 // CorpuStoreX is the knowledge base storage extension interface
 type CorpuStoreX interface {
-	ImportDocs(ctx context.Context, r io.Reader, lw io.Writer) error
+	ImportDocs(ctx context.Context, collection string, r io.Reader, lw io.Writer) error
 	ExportDocs(ctx context.Context, ea ExportArg) error
-	SyncEmbeddingDocments(ctx context.Context, spec *CobDocumentSpec) error
+	SyncEmbeddingDocments(ctx context.Context, spec *CobDocumentSpec) (*SyncReport, error)
+	IngestDocument(ctx context.Context, ia IngestArg) (*IngestResult, error)
+	DiffRevision(ctx context.Context, id, to string) (*RevisionDiff, error)
+	RestoreRevision(ctx context.Context, id string) (*corpus.Document, error)
 	MatchDocments(ctx context.Context, ms MatchSpec) (data corpus.Documents, err error)
 	MatchVectorWith(ctx context.Context, vec corpus.Vector, threshold float32, limit int) (data corpus.DocMatches, err error)
+	MatchTextWith(ctx context.Context, query string, limit int) (data corpus.DocMatches, err error)
 	InvokerForSearch() mcps.Invoker
+	CollectionScope(ctx context.Context) (string, error)
 	InvokerForCreate() mcps.Invoker
 }
 
-// ImportDocs imports documents from CSV
-func (s *corpuStore) ImportDocs(ctx context.Context, r io.Reader, lw io.Writer) error {
+// ImportDocs imports documents from CSV into the collection, empty means the default collection
+func (s *corpuStore) ImportDocs(ctx context.Context, collection string, r io.Reader, lw io.Writer) error {
+	cid, err := s.collectionIDOf(ctx, collection)
+	if err != nil {
+		return err
+	}
 	rd := csv.NewReader(r)
 	rec, err := rd.Read()
 	if err != nil {
@@ -93,6 +120,7 @@ func (s *corpuStore) ImportDocs(ctx context.Context, r io.Reader, lw io.Writer)
 	if !validHead(rec) {
 		return fmt.Errorf("invalid csv head: %+v", rec)
 	}
+	ctx = ContextWithRevisionSource(ctx, corpus.RevisionSourceImport)
 
 	var idx int
 	var valid int
@@ -112,9 +140,10 @@ func (s *corpuStore) ImportDocs(ctx context.Context, r io.Reader, lw io.Writer)
 			continue
 		}
 		err = s.importLine(ctx, corpus.DocumentBasic{
-			Title:   row[0],
-			Heading: row[1],
-			Content: row[2],
+			Title:        row[0],
+			Heading:      row[1],
+			Content:      row[2],
+			CollectionID: cid,
 		}, lw)
 		if err != nil {
 			return err
@@ -127,7 +156,8 @@ func (s *corpuStore) ImportDocs(ctx context.Context, r io.Reader, lw io.Writer)
 func (s *corpuStore) importLine(ctx context.Context, basic corpus.DocumentBasic, lw io.Writer) error {
 	doc := new(corpus.Document)
 	basic.Content = replText.Replace(basic.Content)
-	err := dbGet(ctx, s.w.db, doc, "title = ? AND heading = ?", basic.Title, basic.Heading)
+	err := dbGet(ctx, s.w.db, doc, "collection_id = ? AND title = ? AND heading = ?",
+		basic.CollectionID, basic.Title, basic.Heading)
 	if err != nil {
 		doc, err = s.CreateDocument(ctx, basic)
 	} else {
@@ -166,11 +196,25 @@ func diff2(text1, text2 string) string {
 	return text
 }
 
-// afterCreatedCobDocument generates vector after document creation
+// afterCreatedCobDocument records the first revision and generates vector after document creation.
+// 文档已经保存，两者失败都只记日志，缺少向量的文档在同步时生成
 func (s *corpuStore) afterCreatedCobDocument(ctx context.Context, obj *corpus.Document) error {
+	if err := s.recordRevision(ctx, obj); err != nil {
+		logger().Warnw("skip revision of created document", "id", obj.ID, "err", err)
+	}
+	if err := s.createDocVector(ctx, obj); err != nil {
+		logger().Warnw("embed created document fail, left to sync", "id", obj.ID, "err", err)
+	}
+	return nil
+}
+
+// createDocVector 以标题和小节生成文档向量，记下内容摘要，同步时不再重复生成
+func (s *corpuStore) createDocVector(ctx context.Context, obj *corpus.Document) error {
 	dvb := corpus.DocVectorBasic{
-		DocID:   obj.ID,
-		Subject: obj.GetSubject(),
+		DocID:       obj.ID,
+		Subject:     obj.GetSubject(),
+		Model:       VectorSpaceFromContext(ctx).Model,
+		ContentHash: obj.GetContentHash(),
 	}
 	vec, err := GetEmbedding(ctx, dvb.Subject)
 	if err != nil {
@@ -188,20 +232,40 @@ func (s *corpuStore) afterCreatedCobDocument(ctx context.Context, obj *corpus.Do
 	return nil
 }
 
-// GetEmbedding gets the vector representation of text
+// afterUpdatedCobDocument 内容变化时记下修订，标题、小节或内容变化时重新生成向量。
+// 文档已经保存，两者失败都只记日志，向量的内容摘要未更新，同步时会再次生成
+func (s *corpuStore) afterUpdatedCobDocument(ctx context.Context, obj *corpus.Document) error {
+	if err := s.recordRevision(ctx, obj); err != nil {
+		logger().Warnw("skip revision of updated document", "id", obj.ID, "err", err)
+	}
+	if !obj.HasChange("title") && !obj.HasChange("heading") && !obj.HasChange("content") {
+		return nil
+	}
+	if _, err := s.embedDocument(ctx, obj); err != nil {
+		logger().Warnw("embed updated document fail, left to sync", "id", obj.ID, "err", err)
+	}
+	return nil
+}
+
+// GetEmbedding gets the vector representation of text in the vector space of context
 func GetEmbedding(ctx context.Context, text string) (vec corpus.Vector, err error) {
 	if len(text) == 0 {
 		err = ErrEmptyParam
 		return
 	}
 
-	// 使用 embedding client
-	embedding, err := GetLLMEmbeddingClient().Embedding(ctx, []string{text})
+	vs := VectorSpaceFromContext(ctx)
+	embedding, err := vs.embeddingClient().Embedding(ctx, []string{text})
 	if err != nil {
 		logger().Infow("embedding fail", "text", text, "err", err)
 		return
 	}
 	if len(embedding) > 0 {
+		if len(embedding) != vs.Dim {
+			err = fmt.Errorf("%w: %s returns %d", ErrVectorDim, vs, len(embedding))
+			logger().Infow("embedding fail", "text", words.TakeHead(text, 60, ".."), "err", err)
+			return
+		}
 		// 转换 []float64 到 []float32
 		vec = make(corpus.Vector, len(embedding))
 		for i, v := range embedding {
@@ -214,63 +278,113 @@ func GetEmbedding(ctx context.Context, text string) (vec corpus.Vector, err erro
 	return
 }
 
-// MatchDocments matches documents
+// MatchDocments matches documents with vector and full-text search in parallel,
+// the results are fused with weighted reciprocal rank fusion
 func (s *corpuStore) MatchDocments(ctx context.Context, ms MatchSpec) (data corpus.Documents, err error) {
 	ms.setDefaults()
-	var subject string
-	if ms.SkipKeywords {
-		subject = ms.Query
-	} else {
-		subject, err = GetSummary(ctx, ms.Query, GetTemplateForKeyword())
-		if err != nil {
-			return
-		}
+	f, err := s.docFilterOf(ctx, ms.Collection)
+	if err != nil {
+		return nil, err
 	}
+	// 启用重排序时多取候选，融合后取 n 个再重排；每路检索多取一些，融合后再截取
+	n := rerankCandidates(RerankDoc, ms.Limit)
+	fetch := n * 2
 
-	if len(subject) == 0 {
-		logger().Infow("empty subject", "spec", ms)
-		return
+	var wg sync.WaitGroup
+	var vms, tms corpus.DocMatches
+	var verr, terr error
+	if ms.VectorWeight > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vms, verr = s.matchVector(ctx, ms, fetch, f)
+		}()
 	}
-	var vec corpus.Vector
-	vec, err = GetEmbedding(ctx, subject)
-	if err != nil {
-		logger().Infow("GetEmbedding fail", "err", err)
-		return
+	if ms.TextWeight > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tms, terr = s.matchText(ctx, ms.Query, fetch, f)
+		}()
+	}
+	wg.Wait()
+
+	if verr != nil && (terr != nil || ms.TextWeight <= 0) {
+		return nil, verr
+	}
+	if terr != nil && (verr != nil || ms.VectorWeight <= 0) {
+		return nil, terr
+	}
+	if verr != nil || terr != nil {
+		logger().Infow("hybrid match partly fail", "verr", verr, "terr", terr)
 	}
-	var ps corpus.DocMatches
-	ps, err = s.MatchVectorWith(ctx, vec, ms.Threshold, ms.Limit)
-	if err != nil || len(ps) == 0 {
-		logger().Infow("no match docs", "subj", subject)
+
+	ps := fuseRRF(settings.Current.HybridRRFK, n,
+		rankedMatches{vms, ms.VectorWeight}, rankedMatches{tms, ms.TextWeight})
+	if len(ps) == 0 {
+		logger().Infow("no match docs", "q", ms.Query)
 		return
 	}
-	logger().Infow("matched", "docs", ps.Subjects(30), "err", err)
+	logger().Infow("matched", "docs", ps.Subjects(30), "vector", len(vms), "text", len(tms))
 	spec := &CobDocumentSpec{}
 	spec.IDs = ps.DocumentIDs()
 	err = queryList(ctx, s.w.db, spec, &data).Scan(ctx)
 	if err != nil {
 		logger().Infow("list docs fail", "spec", spec, "err", err)
-	} else {
-		logger().Infow("list docs", "ids", spec.IDs, "matches", data.Headings())
+		return
 	}
+	data = sortDocumentsBy(data, ps)
+	data = rerankSlice(ctx, RerankDoc, ms.Query, data, (*corpus.Document).GetFullText, ms.Limit)
+	logger().Infow("list docs", "ids", spec.IDs, "matches", data.Headings())
 	return
 }
 
-// MatchVectorWith matches documents using vector
+// matchVector 提取关键词（可跳过）后以向量相似度匹配
+func (s *corpuStore) matchVector(ctx context.Context, ms MatchSpec, limit int, f docFilter) (corpus.DocMatches, error) {
+	subject := ms.Query
+	if !ms.SkipKeywords {
+		var err error
+		subject, err = GetSummary(ctx, ms.Query, GetTemplateForKeyword())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(subject) == 0 {
+		logger().Infow("empty subject", "spec", ms)
+		return nil, nil
+	}
+	vec, err := GetEmbedding(ctx, subject)
+	if err != nil {
+		logger().Infow("GetEmbedding fail", "err", err)
+		return nil, err
+	}
+	return s.matchVectorIn(ctx, vec, ms.Threshold, limit, f)
+}
+
+// MatchVectorWith matches documents using vector in the vector space of context,
+// only the collections visible to the caller are matched
 func (s *corpuStore) MatchVectorWith(ctx context.Context, vec corpus.Vector, threshold float32, limit int) (data corpus.DocMatches, err error) {
-	if len(vec) != corpus.VectorLen {
-		logger().Infow("mismatch length of vector", "a", len(vec), "b", corpus.VectorLen)
+	f, err := s.docFilterOf(ctx, "")
+	if err != nil {
 		return
 	}
-	logger().Debugw("match with", "vec", vec[0:5])
-	err = s.w.db.NewRaw("SELECT * FROM vector_match_docs_4(?, ?, ?)", vec, threshold, limit).
-		Scan(ctx, &data)
-	// err = s.w.db.NewSelect().
-	// 	Table(corpus.DocVectorTable).
-	// 	Column("doc_id", "subject").
-	// 	ColumnExpr("(embedding <=> ?) as similarity", vec).
-	// 	Where("(embedding <=> ?) < ?", vec, threshold).
-	// 	OrderExpr("embedding <=> ?", vec).
-	// 	Limit(limit).Scan(ctx, &data)
+	return s.matchVectorIn(ctx, vec, threshold, limit, f)
+}
+
+// matchVectorIn 以向量匹配并按集合过滤，过滤时多取候选以免结果过少
+func (s *corpuStore) matchVectorIn(ctx context.Context, vec corpus.Vector, threshold float32, limit int, f docFilter) (data corpus.DocMatches, err error) {
+	if len(f.cond) == 0 {
+		err = scanVectorMatch(ctx, s.w.db, "docs", vec, threshold, limit, &data)
+	} else {
+		var query string
+		var args []any
+		query, args, err = vectorMatchQuery(ctx, "docs", vec, threshold, limit*collectionFetchFactor)
+		if err == nil {
+			args = append(append(args, f.args...), limit)
+			err = s.w.db.NewRaw("SELECT m.* FROM ("+query+") m JOIN "+corpus.DocumentTable+" cd ON cd.id = m.doc_id "+
+				"WHERE "+f.cond+" ORDER BY m.similarity LIMIT ?", args...).Scan(ctx, &data)
+		}
+	}
 	if err != nil {
 		logger().Infow("match vector fail", "threshold", threshold, "limit", limit, "err", err)
 	} else {
@@ -281,6 +395,13 @@ func (s *corpuStore) MatchVectorWith(ctx context.Context, vec corpus.Vector, thr
 
 // ExportDocs exports documents
 func (s *corpuStore) ExportDocs(ctx context.Context, ea ExportArg) error {
+	if len(ea.Collection) > 0 {
+		cid, err := s.collectionIDOf(ctx, ea.Collection)
+		if err != nil {
+			return err
+		}
+		ea.Spec.CollectionID = cid.String()
+	}
 	data, _, err := s.ListDocument(ctx, ea.Spec)
 	if err != nil {
 		return err
@@ -315,52 +436,60 @@ func documentsToCSV(data corpus.Documents, w io.Writer) error {
 	return cw.Error()
 }
 
-// SyncEmbeddingDocments generates vectors for documents
-func (s *corpuStore) SyncEmbeddingDocments(ctx context.Context, spec *CobDocumentSpec) error {
-	data, _, err := s.ListDocument(ctx, spec)
+// SyncEmbeddingDocments 分页为 spec 筛选的文档生成向量，内容未变化的跳过，spec.Limit 为每页数量
+func (s *corpuStore) SyncEmbeddingDocments(ctx context.Context, spec *CobDocumentSpec) (*SyncReport, error) {
+	return runEmbedSync(ctx, s.w.db, embedSyncJob[corpus.Document]{
+		target:    "doc",
+		spec:      spec,
+		pageSize:  spec.Limit,
+		idOf:      func(doc *corpus.Document) oid.OID { return doc.ID },
+		sync:      s.embedDocument,
+		afterPage: s.refreshTextVector,
+	})
+}
+
+// embedDocument 以标题、小节和内容关键词生成文档向量，内容未变化的跳过
+func (s *corpuStore) embedDocument(ctx context.Context, doc *corpus.Document) (bool, error) {
+	vs := VectorSpaceFromContext(ctx)
+	hash := doc.GetContentHash()
+	exist := new(corpus.DocVector)
+	found := dbGetVector(ctx, s.w.db, exist, "doc_id", doc.ID, vs) == nil
+	if found && exist.Model == vs.Model && exist.ContentHash == hash {
+		return false, nil
+	}
+	subject := doc.GetSubject()
+	contentKeys, err := GetSummary(ctx, doc.Content, GetTemplateForKeyword())
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	for _, doc := range data {
-		subject := doc.GetSubject()
-		contentKeys, err := GetSummary(ctx, doc.Content, GetTemplateForKeyword())
-		if err != nil {
-			return err
-		}
-		subject += " " + contentKeys
-		vec, err := GetEmbedding(ctx, subject)
-		if err != nil {
-			return err
-		}
-		exist := new(corpus.DocVector)
-		err = dbGetWithUnique(ctx, s.w.db, exist, "doc_id", doc.ID)
-		if err == nil {
-			if exist.Subject != subject {
-				logger().Infow("changed", "sub1", exist.Subject, "sub2", subject)
-			}
-			exist.SetWith(corpus.DocVectorSet{
-				Subject: &subject,
-				Vector:  &vec,
-			})
-			if err = dbUpdate(ctx, s.w.db, exist); err != nil {
-				return err
-			}
-		} else {
-			dv := corpus.NewDocVectorWithBasic(corpus.DocVectorBasic{
-				DocID:   doc.ID,
-				Subject: subject,
-				Vector:  vec,
-			})
-			if err = dbInsert(ctx, s.w.db, dv); err != nil {
-				return err
-			}
-		}
+	subject += " " + contentKeys
+	vec, err := GetEmbedding(ctx, subject)
+	if err != nil {
+		return false, err
 	}
-	return nil
+	if found {
+		if exist.Subject != subject {
+			logger().Infow("changed", "sub1", exist.Subject, "sub2", subject)
+		}
+		exist.SetWith(corpus.DocVectorSet{
+			Subject:     &subject,
+			Vector:      &vec,
+			Model:       &vs.Model,
+			ContentHash: &hash,
+		})
+		return true, dbUpdate(ctx, s.w.db, exist)
+	}
+	dv := corpus.NewDocVectorWithBasic(corpus.DocVectorBasic{
+		DocID:       doc.ID,
+		Subject:     subject,
+		Vector:      vec,
+		Model:       vs.Model,
+		ContentHash: hash,
+	})
+	return true, dbInsert(ctx, s.w.db, dv)
 }
 
-// dbAfterDeleteCobDocument cleans up related vector data after document deletion
+// dbAfterDeleteCobDocument cleans up related vector data after document deletion, revisions are kept
 func dbAfterDeleteCobDocument(ctx context.Context, db ormDB, obj *corpus.Document) error {
 	_, err := dbBatchDeleteWithKeyID(ctx, db, corpus.DocVectorTable, "doc_id", obj.ID)
 	return err
@@ -376,10 +505,12 @@ func (s *corpuStore) InvokerForSearch() mcps.Invoker {
 			return mcps.BuildToolErrorResult("missing required argument: subject"), nil
 		}
 
+		collection, _ := cast.ToStringE(args["collection"])
 		docs, err := s.MatchDocments(ctx, MatchSpec{
 			Query:        subject,
 			Limit:        5,
 			SkipKeywords: true,
+			Collection:   collection,
 		})
 		if err != nil {
 			return mcps.BuildToolErrorResult(err.Error()), nil
@@ -390,7 +521,8 @@ func (s *corpuStore) InvokerForSearch() mcps.Invoker {
 		}
 		logger().Infow("matched", "docs", len(docs))
 
-		return mcps.BuildToolSuccessResult(docs.MarkdownText()), nil
+		// 返回原始匹配以便缓存，引用序号由 CiteSearchResult 按会话分配
+		return mcps.BuildToolSuccessResult(map[string]any{searchResultKey: docs}), nil
 
 	}
 }
@@ -424,6 +556,7 @@ func (s *corpuStore) InvokerForCreate() mcps.Invoker {
 			Content: content,
 		}
 		docBasic.MetaAddKVs("creator", user.Name)
+		ctx = ContextWithRevisionSource(ctx, corpus.RevisionSourceTool)
 		obj, err := s.CreateDocument(ctx, docBasic)
 		if err != nil {
 			logger().Infow("create document fail", "title", docBasic.Title, "heading", docBasic.Heading,
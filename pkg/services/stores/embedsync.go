@@ -0,0 +1,185 @@
+package stores
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cupogo/andvari/models/oid"
+	"github.com/cupogo/andvari/stores/pgx"
+
+	"github.com/liut/morign/pkg/settings"
+)
+
+// SyncReport 一次向量同步的汇总
+type SyncReport struct {
+	// 同步目标 doc, mem, capability
+	Target string `json:"target"`
+	// 嵌入模型
+	Model string `json:"model"`
+	// 从此编号之后继续，为空时从头开始
+	ResumedAfter oid.OID `json:"resumedAfter,omitempty" swaggertype:"string"`
+	// 处理的数量
+	Scanned int `json:"scanned"`
+	// 生成或更新了向量的数量
+	Embedded int `json:"embedded"`
+	// 未变化而跳过的数量
+	Unchanged int `json:"unchanged"`
+	// 重试后仍失败的编号
+	Failed oid.OIDs `json:"failed,omitempty" swaggertype:"array,string"`
+	// 耗时
+	Elapsed time.Duration `json:"elapsed" swaggertype:"integer"`
+} // @name storesSyncReport
+
+func (r *SyncReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s [%s]: scanned %d, embedded %d, unchanged %d, failed %d in %s",
+		r.Target, r.Model, r.Scanned, r.Embedded, r.Unchanged, len(r.Failed), r.Elapsed.Round(time.Millisecond))
+	if !r.ResumedAfter.IsZero() {
+		fmt.Fprintf(&sb, " (resumed after %s)", r.ResumedAfter)
+	}
+	return sb.String()
+}
+
+// embedSyncJob 分页同步一类目标的向量
+type embedSyncJob[T any] struct {
+	target string
+	spec   pgx.Sifter
+	// 每页数量，不大于 0 时使用配置
+	pageSize int
+	idOf     func(*T) oid.OID
+	// sync 生成单项的向量，未变化而跳过时返回 false
+	sync func(context.Context, *T) (bool, error)
+	// afterPage 可选，每页处理后以生成了向量的编号调用
+	afterPage func(context.Context, oid.OIDs) error
+}
+
+// runEmbedSync 按编号顺序逐页处理 spec 筛选出的全部目标，每页后记下断点，
+// 中断后再次运行时从断点继续，全部完成后清除断点。
+// 单项失败时退避重试，仍失败的记入报告并继续，列表查询失败或 ctx 取消时中止。
+// 断点停在首个失败的编号之前，中断后再次运行会重试此前失败的项，其后未变化的项按摘要跳过。
+func runEmbedSync[T any](ctx context.Context, db ormDB, job embedSyncJob[T]) (*SyncReport, error) {
+	started := time.Now()
+	vs := VectorSpaceFromContext(ctx)
+	rep := &SyncReport{Target: job.target, Model: vs.Model}
+	defer func() { rep.Elapsed = time.Since(started) }()
+
+	size := job.pageSize
+	if size <= 0 {
+		size = settings.Current.EmbedSyncPageSize
+	}
+	key := embedSyncKey(job.target, vs, queryList(ctx, db, job.spec, new([]T)).String())
+	after := loadEmbedSyncCheckpoint(ctx, key)
+	rep.ResumedAfter = after
+	// mark 为断点，出现失败后不再前移，saved 为已保存的断点
+	mark, saved := after, after
+	if !after.IsZero() {
+		logger().Infow("resume embedding sync", "target", job.target, "after", after)
+	}
+
+	for {
+		var data []T
+		q := queryList(ctx, db, job.spec, &data).OrderExpr("?TableAlias.id").Limit(size)
+		if !after.IsZero() {
+			q.Where("?TableAlias.id > ?", after)
+		}
+		if err := q.Scan(ctx); err != nil {
+			logger().Infow("list for embedding fail", "target", job.target, "after", after, "err", err)
+			return rep, err
+		}
+		var embedded oid.OIDs
+		for i := range data {
+			obj := &data[i]
+			rep.Scanned++
+			ok, err := retryEmbed(ctx, func() (bool, error) { return job.sync(ctx, obj) })
+			if err == nil && len(rep.Failed) == 0 {
+				mark = job.idOf(obj)
+			}
+			switch {
+			case err != nil:
+				if ctx.Err() != nil {
+					return rep, ctx.Err()
+				}
+				logger().Warnw("embedding fail", "target", job.target, "id", job.idOf(obj), "err", err)
+				rep.Failed = append(rep.Failed, job.idOf(obj))
+			case ok:
+				rep.Embedded++
+				embedded = append(embedded, job.idOf(obj))
+			default:
+				rep.Unchanged++
+			}
+		}
+		if job.afterPage != nil && len(embedded) > 0 {
+			if err := job.afterPage(ctx, embedded); err != nil {
+				return rep, err
+			}
+		}
+		if len(data) > 0 {
+			after = job.idOf(&data[len(data)-1])
+		}
+		if mark != saved {
+			saveEmbedSyncCheckpoint(ctx, key, mark)
+			saved = mark
+		}
+		if len(data) < size {
+			break
+		}
+	}
+	clearEmbedSyncCheckpoint(ctx, key)
+	logger().Infow("embedding synced", "report", rep.String())
+	return rep, nil
+}
+
+// retryEmbed 失败时按配置的次数退避重试，ctx 取消时立即返回
+func retryEmbed(ctx context.Context, fn func() (bool, error)) (ok bool, err error) {
+	for n := 0; ; n++ {
+		if ok, err = fn(); err == nil || n >= settings.Current.EmbedSyncRetries {
+			return
+		}
+		wait := backoffDelay(settings.Current.EmbedSyncBackoff, n)
+		logger().Debugw("retry embedding", "attempt", n+1, "wait", wait, "err", err)
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoffDelay 第 n 次（从 0 开始）重试前的等待时间，每次倍增
+func backoffDelay(base time.Duration, n int) time.Duration {
+	if n > 16 {
+		n = 16
+	}
+	return base << n
+}
+
+// embedSyncKey 断点按目标、向量空间和筛选条件区分
+func embedSyncKey(target string, vs VectorSpace, filter string) string {
+	sum := sha1.Sum([]byte(filter))
+	return fmt.Sprintf("embed-sync-%s-%s-%d-%s", target, vs.Model, vs.Dim, hex.EncodeToString(sum[:6]))
+}
+
+func loadEmbedSyncCheckpoint(ctx context.Context, key string) oid.OID {
+	s, err := SgtRC().Get(ctx, key).Result()
+	if err != nil {
+		return 0
+	}
+	return oid.Cast(s)
+}
+
+// saveEmbedSyncCheckpoint 断点保存失败时只记日志，不影响同步
+func saveEmbedSyncCheckpoint(ctx context.Context, key string, id oid.OID) {
+	if err := SgtRC().Set(ctx, key, id.String(), settings.Current.EmbedSyncCheckpointTTL).Err(); err != nil {
+		logger().Infow("save embedding checkpoint fail", "key", key, "err", err)
+	}
+}
+
+func clearEmbedSyncCheckpoint(ctx context.Context, key string) {
+	if err := SgtRC().Del(ctx, key).Err(); err != nil {
+		logger().Infow("clear embedding checkpoint fail", "key", key, "err", err)
+	}
+}
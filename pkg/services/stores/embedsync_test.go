@@ -0,0 +1,72 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cupogo/andvari/models/oid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liut/morign/pkg/settings"
+)
+
+func TestRetryEmbed(t *testing.T) {
+	retries, backoff := settings.Current.EmbedSyncRetries, settings.Current.EmbedSyncBackoff
+	defer func() {
+		settings.Current.EmbedSyncRetries, settings.Current.EmbedSyncBackoff = retries, backoff
+	}()
+	settings.Current.EmbedSyncRetries = 2
+	settings.Current.EmbedSyncBackoff = time.Millisecond
+
+	ctx := context.Background()
+	errFlaky := errors.New("flaky")
+	var calls int
+	ok, err := retryEmbed(ctx, func() (bool, error) {
+		if calls++; calls < 3 {
+			return false, errFlaky
+		}
+		return true, nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	_, err = retryEmbed(ctx, func() (bool, error) {
+		calls++
+		return false, errFlaky
+	})
+	assert.ErrorIs(t, err, errFlaky)
+	assert.Equal(t, 3, calls)
+
+	settings.Current.EmbedSyncBackoff = time.Hour
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = retryEmbed(cctx, func() (bool, error) { return false, errFlaky })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBackoffDelay(t *testing.T) {
+	assert.Equal(t, 2*time.Second, backoffDelay(2*time.Second, 0))
+	assert.Equal(t, 8*time.Second, backoffDelay(2*time.Second, 2))
+	assert.Equal(t, backoffDelay(time.Second, 16), backoffDelay(time.Second, 99))
+}
+
+func TestSyncReport(t *testing.T) {
+	rep := &SyncReport{Target: "doc", Model: "bge-m3", Scanned: 5, Embedded: 2, Unchanged: 2,
+		Failed: oid.OIDs{1}, Elapsed: 1500 * time.Millisecond}
+	assert.Equal(t, "doc [bge-m3]: scanned 5, embedded 2, unchanged 2, failed 1 in 1.5s", rep.String())
+	rep.ResumedAfter = 42
+	assert.Contains(t, rep.String(), "(resumed after ")
+}
+
+func TestEmbedSyncKey(t *testing.T) {
+	vs := VectorSpace{Model: "bge-m3", Dim: 1024}
+	k1 := embedSyncKey("doc", vs, "SELECT 1")
+	assert.Equal(t, k1, embedSyncKey("doc", vs, "SELECT 1"))
+	assert.NotEqual(t, k1, embedSyncKey("doc", vs, "SELECT 2"))
+	assert.NotEqual(t, k1, embedSyncKey("mem", vs, "SELECT 1"))
+	assert.NotEqual(t, k1, embedSyncKey("doc", VectorSpace{Model: "bge-m3", Dim: 512}, "SELECT 1"))
+}
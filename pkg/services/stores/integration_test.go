@@ -26,12 +26,16 @@ package stores
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/cupogo/andvari/models/oid"
+	auth "github.com/liut/simpauth"
+
 	"github.com/liut/morign/pkg/models/convo"
 	"github.com/liut/morign/pkg/models/corpus"
 	"github.com/liut/morign/pkg/services/llm"
@@ -54,8 +58,8 @@ func (m *mockEmbeddingClient) Generate(ctx context.Context, prompt string) (stri
 }
 
 func (m *mockEmbeddingClient) Embedding(ctx context.Context, texts []string) ([]float64, error) {
-	// Return random vectors (same dimension as corpus.VectorLen)
-	dim := corpus.VectorLen
+	// Return random vectors (same dimension as the active vector space)
+	dim := VectorSpaceFromContext(ctx).Dim
 	result := make([]float64, len(texts)*dim)
 	for i := range result {
 		result[i] = float64(rand.Float32())
@@ -117,9 +121,9 @@ func testDocTitle() string {
 }
 
 func TestIntegration_DocumentCRUD(t *testing.T) {
-	// 检查是否有 Embedding API_KEY（CreateDocument 需要 embedding）
-	if settings.Current.Embedding.APIKey == "" {
-		t.Skip("Embedding.APIKey not set, skipping document CRUD test (requires embedding)")
+	// 检查是否有 Embedding 和 Summarize API_KEY（更新后以内容关键词重新生成向量）
+	if settings.Current.Embedding.APIKey == "" || settings.Current.Summarize.APIKey == "" {
+		t.Skip("Embedding.APIKey or Summarize.APIKey not set, skipping document CRUD test (requires embedding)")
 	}
 
 	sto := Sgt()
@@ -148,6 +152,41 @@ func TestIntegration_DocumentCRUD(t *testing.T) {
 	if found == nil {
 		t.Fatal("GetDocument returned nil")
 	}
+	// 新文档的向量带内容摘要，同步时跳过
+	dv := new(corpus.DocVector)
+	if err := dbGetWithUnique(ctx, sto.db, dv, "doc_id", doc.ID); err != nil {
+		t.Fatalf("get doc vector failed: %v", err)
+	}
+	if dv.ContentHash != found.GetContentHash() {
+		t.Errorf("vector of a new document has content hash %q", dv.ContentHash)
+	}
+
+	// Update heading, vector subject should follow
+	heading := "Test Heading Updated"
+	if err := sto.Corpus().UpdateDocument(ctx, doc.ID.String(), corpus.DocumentSet{Heading: &heading}); err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+	dv = new(corpus.DocVector)
+	if err := dbGetWithUnique(ctx, sto.db, dv, "doc_id", doc.ID); err != nil {
+		t.Fatalf("get doc vector failed: %v", err)
+	}
+	if !strings.Contains(dv.Subject, heading) {
+		t.Errorf("vector subject not updated: %q", dv.Subject)
+	}
+
+	// Update content only, vector should be regenerated
+	content := "Test Content Updated"
+	if err := sto.Corpus().UpdateDocument(ctx, doc.ID.String(), corpus.DocumentSet{Content: &content}); err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+	found.Heading, found.Content = heading, content
+	dv = new(corpus.DocVector)
+	if err := dbGetWithUnique(ctx, sto.db, dv, "doc_id", doc.ID); err != nil {
+		t.Fatalf("get doc vector failed: %v", err)
+	}
+	if dv.ContentHash != found.GetContentHash() {
+		t.Errorf("vector not regenerated after content update: %q", dv.ContentHash)
+	}
 
 	// Delete
 	if err := sto.Corpus().DeleteDocument(ctx, doc.ID.String()); err != nil {
@@ -155,6 +194,228 @@ func TestIntegration_DocumentCRUD(t *testing.T) {
 	}
 }
 
+func TestIntegration_IngestDocument(t *testing.T) {
+	if settings.Current.Embedding.APIKey == "" {
+		t.Skip("Embedding.APIKey not set, skipping ingest test (requires embedding)")
+	}
+
+	sto := Sgt()
+	ctx := context.Background()
+
+	title := testDocTitle()
+	name := title + ".md"
+	md := "# " + title + "\n\nIntro.\n\n## Install\n\nRun it.\n\n## Usage\n\nCall it.\n"
+	res, err := sto.Corpus().IngestDocument(ctx, IngestArg{Name: name, Data: []byte(md)})
+	if err != nil {
+		t.Fatalf("IngestDocument failed: %v", err)
+	}
+	src := res.Source
+	if src.Title != title || src.Format != "md" || src.Chunks != 3 {
+		t.Errorf("unexpected source: %+v", src)
+	}
+
+	spec := &CobDocumentSpec{SourceID: src.StringID()}
+	data, total, err := sto.Corpus().ListDocument(ctx, spec)
+	if err != nil {
+		t.Fatalf("ListDocument failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected 3 chunks, got %d: %v", total, data.Headings())
+	}
+
+	// same content is skipped
+	res, err = sto.Corpus().IngestDocument(ctx, IngestArg{Name: name, Data: []byte(md)})
+	if err != nil || !res.Unchanged {
+		t.Errorf("expected unchanged, got %+v, err %v", res, err)
+	}
+
+	// changed content replaces chunks
+	res, err = sto.Corpus().IngestDocument(ctx, IngestArg{Name: name, Data: []byte(md + "\n## FAQ\n\nAsk it.\n")})
+	if err != nil {
+		t.Fatalf("IngestDocument again failed: %v", err)
+	}
+	if res.Source.Chunks != 4 {
+		t.Errorf("expected 4 chunks, got %d", res.Source.Chunks)
+	}
+
+	if err := sto.Corpus().DeleteSource(ctx, src.StringID()); err != nil {
+		t.Fatalf("DeleteSource failed: %v", err)
+	}
+	_, total, err = sto.Corpus().ListDocument(ctx, spec)
+	if err != nil || total != 0 {
+		t.Errorf("chunks remain after delete: %d, err %v", total, err)
+	}
+}
+
+func TestIntegration_MatchText(t *testing.T) {
+	if settings.Current.Embedding.APIKey == "" {
+		t.Skip("Embedding.APIKey not set, skipping text match test (requires embedding)")
+	}
+
+	sto := Sgt()
+	ctx := context.Background()
+
+	doc, err := sto.Corpus().CreateDocument(ctx, corpus.DocumentBasic{
+		Title:   testDocTitle(),
+		Heading: "Error codes",
+		Content: "Error ERR-7731 means the license server is unreachable.",
+	})
+	if err != nil {
+		t.Fatalf("CreateDocument failed: %v", err)
+	}
+	defer func() { _ = sto.Corpus().DeleteDocument(ctx, doc.StringID()) }()
+
+	data, err := sto.Corpus().MatchTextWith(ctx, "ERR-7731", 5)
+	if err != nil {
+		t.Fatalf("MatchTextWith failed: %v", err)
+	}
+	if !data.DocumentIDs().Has(doc.ID) {
+		t.Errorf("document not matched by identifier: %+v", data)
+	}
+}
+
+func TestIntegration_CollectionVisibility(t *testing.T) {
+	if settings.Current.Embedding.APIKey == "" {
+		t.Skip("Embedding.APIKey not set, skipping collection test (requires embedding)")
+	}
+
+	sto := Sgt()
+	ctx := ContextWithTrusted(context.Background())
+
+	name := testDocTitle() + "-hr"
+	coll, err := sto.Corpus().CreateCollection(ctx, corpus.CollectionBasic{Name: name, Roles: []string{"staff"}})
+	if err != nil {
+		t.Fatalf("CreateCollection failed: %v", err)
+	}
+	defer func() { _ = sto.Corpus().DeleteCollection(ctx, coll.StringID()) }()
+
+	doc, err := sto.Corpus().CreateDocument(ctx, corpus.DocumentBasic{
+		Title:        testDocTitle(),
+		Heading:      "Leave policy",
+		Content:      "Policy HRX-5521 grants twenty days of annual leave.",
+		CollectionID: coll.ID,
+	})
+	if err != nil {
+		t.Fatalf("CreateDocument failed: %v", err)
+	}
+	defer func() { _ = sto.Corpus().DeleteDocument(ctx, doc.StringID()) }()
+
+	if err = sto.Corpus().DeleteCollection(ctx, coll.StringID()); !errors.Is(err, ErrCollectionInUse) {
+		t.Errorf("expected ErrCollectionInUse, got %v", err)
+	}
+
+	staff := auth.ContextWithUser(context.Background(), &User{OID: "1", UID: "alice", Roles: []string{"staff"}})
+	contractor := auth.ContextWithUser(context.Background(), &User{OID: "2", UID: "bob", Roles: []string{"contractor"}})
+	anonymous := context.Background()
+
+	data, err := sto.Corpus().MatchTextWith(staff, "HRX-5521", 5)
+	if err != nil || !data.DocumentIDs().Has(doc.ID) {
+		t.Errorf("staff should match the document: %+v, err %v", data, err)
+	}
+	data, err = sto.Corpus().MatchTextWith(contractor, "HRX-5521", 5)
+	if err != nil || data.DocumentIDs().Has(doc.ID) {
+		t.Errorf("contractor should not match the document: %+v, err %v", data, err)
+	}
+
+	spec := &CobDocumentSpec{CollectionID: coll.StringID()}
+	if _, total, err := sto.Corpus().ListDocument(contractor, spec); err != nil || total != 0 {
+		t.Errorf("contractor lists %d documents, err %v", total, err)
+	}
+	if _, total, err := sto.Corpus().ListDocument(staff, spec); err != nil || total != 1 {
+		t.Errorf("staff lists %d documents, err %v", total, err)
+	}
+	if _, err = sto.Corpus().GetDocument(contractor, doc.StringID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("contractor should not read the document, got %v", err)
+	}
+	if got, err := sto.Corpus().GetDocument(staff, doc.StringID()); err != nil || got.ID != doc.ID {
+		t.Errorf("staff should read the document: %+v, err %v", got, err)
+	}
+	revs, _, err := sto.Corpus().ListRevision(ctx, &CobRevisionSpec{DocID: doc.StringID()})
+	if err != nil || len(revs) == 0 {
+		t.Fatalf("expected revisions, got %d, err %v", len(revs), err)
+	}
+	if _, err = sto.Corpus().GetRevision(contractor, revs[0].StringID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("contractor should not read the revision, got %v", err)
+	}
+	if _, err = sto.Corpus().DiffRevision(contractor, revs[0].StringID(), ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("contractor should not diff the revision, got %v", err)
+	}
+	if _, err = sto.Corpus().GetRevision(staff, revs[0].StringID()); err != nil {
+		t.Errorf("staff should read the revision, got %v", err)
+	}
+	colls, _, err := sto.Corpus().ListCollection(contractor, &CobCollectionSpec{Name: name})
+	if err != nil || len(colls) != 0 {
+		t.Errorf("contractor lists collections %+v, err %v", colls, err)
+	}
+
+	// 匿名调用只可见未设置规则的集合
+	data, err = sto.Corpus().MatchTextWith(anonymous, "HRX-5521", 5)
+	if err != nil || data.DocumentIDs().Has(doc.ID) {
+		t.Errorf("anonymous should not match the document: %+v, err %v", data, err)
+	}
+	if _, total, err := sto.Corpus().ListDocument(anonymous, spec); err != nil || total != 0 {
+		t.Errorf("anonymous lists %d documents, err %v", total, err)
+	}
+	if _, err = sto.Corpus().GetDocument(anonymous, doc.StringID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("anonymous should not read the document, got %v", err)
+	}
+	if _, err = sto.Corpus().GetRevision(anonymous, revs[0].StringID()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("anonymous should not read the revision, got %v", err)
+	}
+}
+
+func TestIntegration_DocumentRevisions(t *testing.T) {
+	if settings.Current.Embedding.APIKey == "" || settings.Current.Summarize.APIKey == "" {
+		t.Skip("Embedding.APIKey or Summarize.APIKey not set, skipping revision test (requires embedding)")
+	}
+
+	sto := Sgt()
+	ctx := auth.ContextWithUser(context.Background(), &User{OID: "1", UID: "alice"})
+
+	doc, err := sto.Corpus().CreateDocument(ctx, corpus.DocumentBasic{
+		Title:   testDocTitle(),
+		Heading: "Revision",
+		Content: "first version",
+	})
+	if err != nil {
+		t.Fatalf("CreateDocument failed: %v", err)
+	}
+	defer func() { _ = sto.Corpus().DeleteDocument(ctx, doc.StringID()) }()
+
+	content := "second version"
+	if err = sto.Corpus().UpdateDocument(ctx, doc.StringID(), corpus.DocumentSet{Content: &content}); err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+	// 内容未变化时不记修订
+	if err = sto.Corpus().UpdateDocument(ctx, doc.StringID(), corpus.DocumentSet{Content: &content}); err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+
+	spec := &CobRevisionSpec{DocID: doc.StringID()}
+	spec.Sort = "id"
+	revs, total, err := sto.Corpus().ListRevision(ctx, spec)
+	if err != nil || total != 2 {
+		t.Fatalf("expected 2 revisions, got %d, err %v", total, err)
+	}
+	if revs[0].Content != "first version" || revs[0].Author != "alice" || revs[0].Source != corpus.RevisionSourceAPI {
+		t.Errorf("unexpected first revision: %+v", revs[0])
+	}
+
+	rd, err := sto.Corpus().DiffRevision(ctx, revs[0].StringID(), "")
+	if err != nil || !strings.Contains(rd.Diff, "+second version") {
+		t.Errorf("unexpected diff %+v, err %v", rd, err)
+	}
+
+	restored, err := sto.Corpus().RestoreRevision(ctx, revs[0].StringID())
+	if err != nil || restored.Content != "first version" {
+		t.Fatalf("RestoreRevision failed: %+v, err %v", restored, err)
+	}
+	revs, _, err = sto.Corpus().ListRevision(ctx, spec)
+	if err != nil || len(revs) != 3 || revs[2].Source != corpus.RevisionSourceRestore {
+		t.Errorf("expected a restore revision: %+v, err %v", revs, err)
+	}
+}
+
 func TestIntegration_ListDocuments(t *testing.T) {
 	sto := Sgt()
 	ctx := context.Background()
@@ -195,7 +456,8 @@ func TestIntegration_DocVectorCRUD(t *testing.T) {
 	vec, err := sto.Corpus().CreateDocVector(ctx, corpus.DocVectorBasic{
 		DocID:   doc.ID,
 		Subject: "test-subject",
-		Vector:  make(corpus.Vector, 1024),
+		Vector:  make(corpus.Vector, settings.Current.EmbeddingDim),
+		Model:   ActiveSpace().Model,
 	})
 	if err != nil {
 		t.Fatalf("CreateDocVector failed: %v", err)
@@ -220,6 +482,58 @@ func TestIntegration_DocVectorCRUD(t *testing.T) {
 	}
 }
 
+func TestIntegration_VectorSpace(t *testing.T) {
+	sto := Sgt()
+	ctx := context.Background()
+
+	vs := VectorSpace{Model: fmt.Sprintf("test-embed-%d", os.Getpid()), Dim: 8}
+	if err := EnsureVectorSpace(ctx, sto.db, vs); err != nil {
+		t.Fatalf("EnsureVectorSpace failed: %v", err)
+	}
+	defer DropVectorSpace(ctx, sto.db, vs.Model)
+
+	vec := corpus.Vector{1, 0, 0, 0, 0, 0, 0, 0}
+	docID := oid.NewID(oid.OtEvent)
+	if _, err := sto.Corpus().CreateDocVector(ctx, corpus.DocVectorBasic{
+		DocID: docID, Subject: "vector space", Vector: vec, Model: vs.Model,
+	}); err != nil {
+		t.Fatalf("CreateDocVector failed: %v", err)
+	}
+
+	sctx := ContextWithVectorSpace(ctx, vs)
+	matches, err := sto.Corpus().MatchVectorWith(sctx, vec, 0.1, 5)
+	if err != nil {
+		t.Fatalf("MatchVectorWith failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].DocID != docID {
+		t.Errorf("unexpected matches %v", matches)
+	}
+	if _, err = sto.Corpus().MatchVectorWith(sctx, vec[:4], 0.1, 5); !errors.Is(err, ErrVectorDim) {
+		t.Errorf("expected ErrVectorDim, got %v", err)
+	}
+
+	stats, err := VectorStats(ctx, sto.db)
+	if err != nil {
+		t.Fatalf("VectorStats failed: %v", err)
+	}
+	var found bool
+	for _, st := range stats {
+		if st.Model == vs.Model {
+			found = st.Target == "docs" && st.Dim == vs.Dim && st.Count == 1
+		}
+	}
+	if !found {
+		t.Errorf("space not found in stats %v", stats)
+	}
+
+	if _, err = DropVectorSpace(ctx, sto.db, ActiveSpace().Model); err == nil {
+		t.Error("expected error when dropping the active model")
+	}
+	if n, err := DropVectorSpace(ctx, sto.db, vs.Model); err != nil || n != 1 {
+		t.Errorf("DropVectorSpace got %d, %v", n, err)
+	}
+}
+
 func TestIntegration_ChatLogCRUD(t *testing.T) {
 	sto := Sgt()
 	ctx := context.Background()
@@ -294,6 +608,28 @@ func TestIntegration_MemoryCRUD(t *testing.T) {
 		t.Errorf("expected content 'Test memory content', got %q", found.Content)
 	}
 
+	// 记忆向量单独存放，带有所有人
+	mv := new(convo.MemoryVector)
+	if err := dbGetWithUnique(ctx, sto.db, mv, "mem_id", mem.ID); err != nil {
+		t.Fatalf("get memory vector failed: %v", err)
+	}
+	if mv.OwnerID != testOwnerID {
+		t.Errorf("expected owner %s, got %s", testOwnerID, mv.OwnerID)
+	}
+	if exists, _ := dbExists(ctx, sto.db, new(corpus.DocVector), "doc_id = ?", mem.ID); exists {
+		t.Error("memory vector should not be stored with documents")
+	}
+
+	// 只能匹配到自己的记忆
+	other := auth.ContextWithUser(ctx, &User{OID: oid.NewID(oid.OtEvent).String(), UID: "other"})
+	if data, _ := sto.Convo().MatchMemories(other, MatchSpec{Query: testKey, Threshold: 2}); len(data) > 0 {
+		t.Errorf("matched memories of others: %v", data.Keys())
+	}
+	mine := auth.ContextWithUser(ctx, &User{OID: testOwnerID.String(), UID: "mine"})
+	if data, err := sto.Convo().MatchMemories(mine, MatchSpec{Query: testKey, Threshold: 2}); err != nil || len(data) != 1 {
+		t.Errorf("MatchMemories got %v, %v", data.Keys(), err)
+	}
+
 	// Update Memory
 	newContent := "Updated memory content"
 	err = sto.Convo().UpdateMemory(ctx, mem.ID.String(), convo.MemorySet{
@@ -323,6 +659,9 @@ func TestIntegration_MemoryCRUD(t *testing.T) {
 	if err == nil && deleted != nil {
 		t.Error("Memory should have been deleted")
 	}
+	if exists, _ := dbExists(ctx, sto.db, new(convo.MemoryVector), "mem_id = ?", mem.ID); exists {
+		t.Error("memory vector should have been deleted")
+	}
 }
 
 func TestIntegration_ListMemories(t *testing.T) {
@@ -337,3 +676,66 @@ func TestIntegration_ListMemories(t *testing.T) {
 
 	t.Logf("Total memories: %d, returned: %d", total, len(data))
 }
+
+func TestIntegration_CapabilitySource(t *testing.T) {
+	sto := Sgt().Capability()
+	ctx := context.Background()
+	name := fmt.Sprintf("test-src-%d", os.Getpid())
+
+	doc := `{"openapi":"3.0.0","servers":[{"url":"https://crm.example.com/"}],
+		"paths":{"/api/test-contacts":{"get":{"summary":"List test contacts","tags":["contact"]}}}}`
+	if err := sto.ImportCapabilities(ctx, name, strings.NewReader(doc), nil); err != nil {
+		t.Fatalf("ImportCapabilities failed: %v", err)
+	}
+	src, err := sto.GetSource(ctx, name)
+	if err != nil {
+		t.Fatalf("GetSource failed: %v", err)
+	}
+	if src.BaseURL != "https://crm.example.com" {
+		t.Errorf("unexpected base url %q", src.BaseURL)
+	}
+	if same, err := sto.EnsureSource(ctx, name, "https://other.example.com", false); err != nil || same.BaseURL != src.BaseURL {
+		t.Errorf("EnsureSource should keep the base url: %+v, err %v", same, err)
+	}
+	if moved, err := sto.EnsureSource(ctx, name, "https://crm2.example.com/", true); err != nil || moved.BaseURL != "https://crm2.example.com" {
+		t.Errorf("EnsureSource should update the base url: %+v, err %v", moved, err)
+	}
+	if _, err := sto.EnsureSource(ctx, name, src.BaseURL, true); err != nil {
+		t.Fatalf("EnsureSource failed: %v", err)
+	}
+
+	cap, err := sto.GetCapabilityWith(ctx, src.ID, "GET", "/api/test-contacts")
+	if err != nil {
+		t.Fatalf("GetCapabilityWith failed: %v", err)
+	}
+
+	cs := sto.(*capabilityStore)
+	routed, found, err := cs.lookupCapability(ctx, "", "GET", "/api/test-contacts")
+	if err != nil || routed == nil || routed.ID != src.ID || found.ID != cap.ID {
+		t.Errorf("lookupCapability got %v, %v, %v", routed, found, err)
+	}
+	if _, _, err := cs.lookupCapability(ctx, name, "POST", "/api/test-contacts"); err == nil {
+		t.Error("expected error for unknown capability")
+	}
+
+	res, err := sto.InvokerForInvoke(NewCapabilityInvoker(""))(ctx, map[string]any{
+		"method": "get", "endpoint": "/api/test-contacts", "source": name,
+		"params": map[string]any{"q": "x"}, "dry_run": true,
+	})
+	if err != nil || res["isError"] == true {
+		t.Fatalf("dry run failed: %v, %v", res, err)
+	}
+	if text := fmt.Sprint(res); !strings.Contains(text, "https://crm.example.com/api/test-contacts?q=x") {
+		t.Errorf("unexpected preview %s", text)
+	}
+
+	if err := sto.DeleteSource(ctx, src.StringID()); !errors.Is(err, ErrSourceInUse) {
+		t.Errorf("expected ErrSourceInUse, got %v", err)
+	}
+	if err := sto.DeleteCapability(ctx, cap.StringID()); err != nil {
+		t.Fatalf("DeleteCapability failed: %v", err)
+	}
+	if err := sto.DeleteSource(ctx, src.StringID()); err != nil {
+		t.Errorf("DeleteSource failed: %v", err)
+	}
+}
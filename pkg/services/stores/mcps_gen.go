@@ -9,17 +9,24 @@ import (
 )
 
 // type MCPServer = mcps.Server
+// type MCPToolVector = mcps.ToolVector
 
 func init() {
-	RegisterModel((*mcps.Server)(nil))
+	RegisterModel((*mcps.Server)(nil), (*mcps.ToolVector)(nil))
 }
 
 type MCPStore interface {
+	MCPStoreX
+
 	ListServer(ctx context.Context, spec *MCPServerSpec) (data mcps.Servers, total int, err error)
 	GetServer(ctx context.Context, id string) (obj *mcps.Server, err error)
 	CreateServer(ctx context.Context, in mcps.ServerBasic) (obj *mcps.Server, err error)
 	UpdateServer(ctx context.Context, id string, in mcps.ServerSet) error
 	DeleteServer(ctx context.Context, id string) error
+
+	ListToolVector(ctx context.Context, spec *MCPToolVectorSpec) (data mcps.ToolVectors, total int, err error)
+	GetToolVector(ctx context.Context, id string) (obj *mcps.ToolVector, err error)
+	CreateToolVector(ctx context.Context, in mcps.ToolVectorBasic) (obj *mcps.ToolVector, err error)
 }
 
 type MCPServerSpec struct {
@@ -58,6 +65,24 @@ func (spec *MCPServerSpec) Sift(q *ormQuery) *ormQuery {
 	return q
 }
 
+type MCPToolVectorSpec struct {
+	PageSpec
+	ModelSpec
+
+	// 工具名 MCP 工具为 server-tool 形式
+	Name string `extensions:"x-order=A" form:"name" json:"name"`
+	// 嵌入模型
+	Model string `extensions:"x-order=B" form:"model" json:"model"`
+}
+
+func (spec *MCPToolVectorSpec) Sift(q *ormQuery) *ormQuery {
+	q = spec.ModelSpec.Sift(q)
+	q, _ = siftEqual(q, "name", spec.Name, false)
+	q, _ = siftEqual(q, "model", spec.Model, false)
+
+	return q
+}
+
 type mcpStore struct {
 	w *Wrap
 }
@@ -85,8 +110,14 @@ func (s *mcpStore) CreateServer(ctx context.Context, in mcps.ServerBasic) (obj *
 		err = ErrEmptyKey
 		return
 	}
+	if err = dbBeforeSaveServer(ctx, s.w.db, obj); err != nil {
+		return
+	}
 	dbMetaUp(ctx, s.w.db, obj)
 	err = dbInsert(ctx, s.w.db, obj, "name")
+	if err == nil {
+		err = s.afterCreatedServer(ctx, obj)
+	}
 	return
 }
 func (s *mcpStore) UpdateServer(ctx context.Context, id string, in mcps.ServerSet) error {
@@ -96,6 +127,9 @@ func (s *mcpStore) UpdateServer(ctx context.Context, id string, in mcps.ServerSe
 	}
 	exist.SetIsUpdate(true)
 	exist.SetWith(in)
+	if err := dbBeforeSaveServer(ctx, s.w.db, exist); err != nil {
+		return err
+	}
 	dbMetaUp(ctx, s.w.db, exist)
 	return dbUpdate(ctx, s.w.db, exist)
 }
@@ -103,3 +137,20 @@ func (s *mcpStore) DeleteServer(ctx context.Context, id string) error {
 	obj := new(mcps.Server)
 	return s.w.db.DeleteModel(ctx, obj, id)
 }
+
+func (s *mcpStore) ListToolVector(ctx context.Context, spec *MCPToolVectorSpec) (data mcps.ToolVectors, total int, err error) {
+	total, err = s.w.db.ListModel(ctx, spec, &data)
+	return
+}
+func (s *mcpStore) GetToolVector(ctx context.Context, id string) (obj *mcps.ToolVector, err error) {
+	obj = new(mcps.ToolVector)
+	err = dbGetWithPKID(ctx, s.w.db, obj, id)
+
+	return
+}
+func (s *mcpStore) CreateToolVector(ctx context.Context, in mcps.ToolVectorBasic) (obj *mcps.ToolVector, err error) {
+	obj = mcps.NewToolVectorWithBasic(in)
+	dbMetaUp(ctx, s.w.db, obj)
+	err = dbInsert(ctx, s.w.db, obj)
+	return
+}
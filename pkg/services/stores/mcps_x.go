@@ -2,20 +2,38 @@ package stores
 
 import (
 	"context"
+	"maps"
+
+	"golang.org/x/oauth2/clientcredentials"
 
 	"github.com/liut/morign/pkg/models/mcps"
 )
 
+// MCPStoreX 工具向量等扩展
+type MCPStoreX interface {
+	SyncToolVectors(ctx context.Context, tools []mcps.ToolDescriptor) error
+	MatchTools(ctx context.Context, ms MatchSpec) (data []mcps.ToolMatch, err error)
+}
+
+// PatchMCPServer 根据 HeaderCate 和认证凭据设置 HeaderFunc，凭据须为明文
 func PatchMCPServer(obj *mcps.Server) {
-	if obj.HeaderCate.HasAuthorization() {
-		obj.HeaderFunc = func(ctx context.Context) map[string]string {
+	if hf := buildHeaderFunc(obj.Name, obj.HeaderCate, obj.AuthType, obj.Credential); hf != nil {
+		obj.HeaderFunc = hf
+	}
+}
+
+// buildHeaderFunc 组合 HeaderCate 转发的头和自身凭据的头，凭据须为明文
+func buildHeaderFunc(name string, hc mcps.HeaderCate, at mcps.AuthType, cred mcps.Credential) mcps.HeaderFunc {
+	var funcs []mcps.HeaderFunc
+	if hc.HasAuthorization() {
+		funcs = append(funcs, func(ctx context.Context) map[string]string {
 			if tk := OAuthTokenFromContext(ctx); len(tk) > 0 {
 				return map[string]string{"Authorization": "Bearer " + tk}
 			}
 			return nil
-		}
-	} else if obj.HeaderCate.HasOwnerSession() {
-		obj.HeaderFunc = func(ctx context.Context) map[string]string {
+		})
+	} else if hc.HasOwnerSession() {
+		funcs = append(funcs, func(ctx context.Context) map[string]string {
 			csid := ConvoIDFromContext(ctx)
 			if user, ok := UserFromContext(ctx); ok && len(csid) > 0 {
 				logger().Debugw("got scarf", "uid", user.OID, "csid", csid)
@@ -26,12 +44,100 @@ func PatchMCPServer(obj *mcps.Server) {
 			}
 
 			return nil
+		})
+	}
+	// 自身的凭据在后，同名头以其为准
+	if hf := credentialHeaderFunc(name, at, cred); hf != nil {
+		funcs = append(funcs, hf)
+	}
+
+	switch len(funcs) {
+	case 0:
+		return nil
+	case 1:
+		return funcs[0]
+	}
+	return func(ctx context.Context) map[string]string {
+		out := make(map[string]string)
+		for _, fn := range funcs {
+			maps.Copy(out, fn(ctx))
+		}
+		return out
+	}
+}
+
+func credentialHeaderFunc(name string, at mcps.AuthType, cred mcps.Credential) mcps.HeaderFunc {
+	switch at {
+	case mcps.AuthTypeHeader:
+		if len(cred.Headers) == 0 {
+			return nil
+		}
+		headers := maps.Clone(cred.Headers)
+		return func(ctx context.Context) map[string]string {
+			return maps.Clone(headers)
+		}
+	case mcps.AuthTypeBearer:
+		if len(cred.Token) == 0 {
+			return nil
+		}
+		token := cred.Token
+		return func(ctx context.Context) map[string]string {
+			return map[string]string{"Authorization": "Bearer " + token}
+		}
+	case mcps.AuthTypeOAuth2:
+		if len(cred.TokenURL) == 0 || len(cred.ClientID) == 0 {
+			logger().Infow("incomplete oauth2 credential", "server", name)
+			return nil
+		}
+		cc := &clientcredentials.Config{
+			ClientID:     cred.ClientID,
+			ClientSecret: cred.ClientSecret,
+			TokenURL:     cred.TokenURL,
+			Scopes:       cred.Scopes,
+		}
+		// 令牌在过期前复用，过期后自动重新获取
+		ts := cc.TokenSource(context.Background())
+		return func(ctx context.Context) map[string]string {
+			tok, err := ts.Token()
+			if err != nil {
+				logger().Warnw("fetch oauth2 token fail", "server", name, "err", err)
+				return nil
+			}
+			return map[string]string{"Authorization": tok.Type() + " " + tok.AccessToken}
+		}
+	}
+	return nil
+}
+
+// dbBeforeSaveServer 加密凭据，更新时未改动的占位值还原为原密文
+func dbBeforeSaveServer(ctx context.Context, db ormDB, obj *mcps.Server) error {
+	if obj.IsUpdate() && obj.Credential.HasMasked() {
+		old := mcps.NewServerWithID(obj.ID)
+		if err := dbGetWithPK(ctx, db, old); err != nil {
+			return err
 		}
+		obj.Credential.Restore(old.Credential)
 	}
+	return sealCredential(&obj.Credential)
+}
+
+func (s *mcpStore) afterCreatedServer(ctx context.Context, obj *mcps.Server) error {
+	obj.Credential.Mask()
+	return nil
 }
 
 func (s *mcpStore) afterLoadServer(ctx context.Context, obj *mcps.Server) error {
-	PatchMCPServer(obj)
+	if err := openCredential(&obj.Credential); err != nil {
+		logger().Warnw("open credential fail", "server", obj.Name, "err", err)
+		// 凭据不可用时仅按 HeaderCate 设置
+		at := obj.AuthType
+		obj.AuthType = mcps.AuthTypeNone
+		PatchMCPServer(obj)
+		obj.AuthType = at
+	} else {
+		PatchMCPServer(obj)
+	}
+	obj.Credential.Mask()
 	return nil
 }
 
@@ -41,3 +147,70 @@ func (s *mcpStore) afterListServer(ctx context.Context, spec *MCPServerSpec, dat
 	}
 	return nil
 }
+
+// SyncToolVectors 为工具描述生成向量，主题未变化的跳过
+func (s *mcpStore) SyncToolVectors(ctx context.Context, tools []mcps.ToolDescriptor) error {
+	var count int
+	vs := VectorSpaceFromContext(ctx)
+	for _, td := range tools {
+		subject := td.GetSubject()
+		exist := new(mcps.ToolVector)
+		err := dbGetVector(ctx, s.w.db, exist, "name", td.Name, vs)
+		if err == nil && exist.Subject == subject && exist.Model == vs.Model {
+			continue
+		}
+		vec, verr := GetEmbedding(ctx, subject)
+		if verr != nil {
+			logger().Infow("skip tool due to embedding fail", "name", td.Name, "err", verr)
+			continue
+		}
+		if err == nil {
+			exist.SetWith(mcps.ToolVectorSet{
+				Subject: &subject,
+				Vector:  &vec,
+				Model:   &vs.Model,
+			})
+			err = dbUpdate(ctx, s.w.db, exist)
+		} else {
+			_, err = s.CreateToolVector(ctx, mcps.ToolVectorBasic{
+				Name:    td.Name,
+				Subject: subject,
+				Vector:  vec,
+				Model:   vs.Model,
+			})
+		}
+		if err != nil {
+			return err
+		}
+		count++
+	}
+	logger().Infow("synced tool vectors", "tools", len(tools), "embedded", count)
+	return nil
+}
+
+// MatchTools 按语义匹配工具，不做关键词摘要时直接嵌入查询
+func (s *mcpStore) MatchTools(ctx context.Context, ms MatchSpec) (data []mcps.ToolMatch, err error) {
+	ms.setDefaults()
+
+	subject := ms.Query
+	if !ms.SkipKeywords {
+		if subject, err = GetSummary(ctx, ms.Query, GetTemplateForKeyword()); err != nil {
+			return
+		}
+	}
+	if len(subject) == 0 {
+		return
+	}
+
+	vec, err := GetEmbedding(ctx, subject)
+	if err != nil {
+		logger().Infow("GetEmbedding fail", "err", err)
+		return
+	}
+
+	err = scanVectorMatch(ctx, s.w.db, "tool", vec, ms.Threshold, ms.Limit, &data)
+	if err != nil {
+		logger().Infow("match tool vector fail", "threshold", ms.Threshold, "limit", ms.Limit, "err", err)
+	}
+	return
+}
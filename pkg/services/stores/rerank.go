@@ -0,0 +1,247 @@
+package stores
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/liut/morign/pkg/services/llm"
+	"github.com/liut/morign/pkg/settings"
+	"github.com/liut/morign/pkg/utils/words"
+)
+
+// 重排序目标，对应 Rerank.Targets 的键
+const (
+	RerankDoc        = "doc"
+	RerankCapability = "capability"
+	RerankMemory     = "memory"
+)
+
+// ErrUnknownRerankBackend 不支持的重排序后端
+var ErrUnknownRerankBackend = errors.New("unknown rerank backend")
+
+// RerankResult 重排结果，Index 为候选的下标
+type RerankResult struct {
+	Index int     `json:"index"`
+	Score float32 `json:"score"`
+}
+
+// Reranker 按与查询的相关性对候选文本重新打分，返回按得分从高到低排列的结果
+type Reranker interface {
+	Name() string
+	Rerank(ctx context.Context, query string, docs []string, topN int) ([]RerankResult, error)
+}
+
+// NewReranker 按配置创建重排序后端，Backend 为空时返回 nil
+func NewReranker(cfg settings.Rerank) (Reranker, error) {
+	switch strings.ToLower(cfg.Backend) {
+	case "":
+		return nil, nil
+	case "http":
+		if len(cfg.URL) == 0 {
+			return nil, fmt.Errorf("rerank http: url is required")
+		}
+		return &httpReranker{
+			url: cfg.URL, apiKey: cfg.APIKey, model: cfg.Model,
+			client: &http.Client{Timeout: cfg.Timeout},
+		}, nil
+	case "llm":
+		return &llmReranker{client: GetLLMSummarizeClient()}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownRerankBackend, cfg.Backend)
+}
+
+var (
+	rrOnce sync.Once
+	rrInst Reranker
+)
+
+func getReranker() Reranker {
+	rrOnce.Do(func() {
+		var err error
+		if rrInst, err = NewReranker(settings.Current.Rerank); err != nil {
+			logger().Warnw("create reranker fail", "err", err)
+		}
+	})
+	return rrInst
+}
+
+// rerankCandidates 返回目标需要检索的候选数量，未启用重排序时为 limit
+func rerankCandidates(target string, limit int) int {
+	if getReranker() == nil {
+		return limit
+	}
+	return max(settings.Current.Rerank.Targets[target], limit)
+}
+
+// rerankSlice 对检索结果重排并截取前 limit 个，未启用或失败时按原顺序截取
+func rerankSlice[T any](ctx context.Context, target, query string, data []T, text func(*T) string, limit int) []T {
+	rr := getReranker()
+	if rr == nil || settings.Current.Rerank.Targets[target] <= 0 || len(data) == 0 {
+		return headOf(data, limit)
+	}
+	docs := make([]string, len(data))
+	for i := range data {
+		docs[i] = text(&data[i])
+	}
+	res, err := rr.Rerank(ctx, query, docs, limit)
+	if err != nil {
+		logger().Infow("rerank fail", "target", target, "backend", rr.Name(), "err", err)
+		return headOf(data, limit)
+	}
+	out := make([]T, 0, limit)
+	for _, r := range res {
+		if r.Index < 0 || r.Index >= len(data) || len(out) >= limit {
+			continue
+		}
+		if minScore := settings.Current.Rerank.MinScore; minScore > 0 && r.Score < minScore {
+			continue
+		}
+		out = append(out, data[r.Index])
+	}
+	logger().Infow("reranked", "target", target, "backend", rr.Name(), "candidates", len(data), "out", len(out))
+	return out
+}
+
+func headOf[T any](data []T, limit int) []T {
+	if limit > 0 && len(data) > limit {
+		return data[:limit]
+	}
+	return data
+}
+
+// httpReranker 交叉编码器重排接口，兼容 Jina、Cohere 及 BGE 等同格式服务
+//
+//	POST {"model", "query", "documents": [...], "top_n"}
+//	=> {"results": [{"index", "relevance_score"}]}
+type httpReranker struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+func (r *httpReranker) Name() string { return "http" }
+
+func (r *httpReranker) Rerank(ctx context.Context, query string, docs []string, topN int) ([]RerankResult, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":     r.model,
+		"query":     query,
+		"documents": docs,
+		"top_n":     topN,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if len(r.apiKey) > 0 {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank http status %d: %s", resp.StatusCode, words.TakeHead(string(b), 200, ".."))
+	}
+
+	type item struct {
+		Index          int      `json:"index"`
+		RelevanceScore *float32 `json:"relevance_score"`
+		Score          float32  `json:"score"`
+	}
+	var items []item
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		err = json.Unmarshal(b, &items)
+	} else {
+		var res struct {
+			Results []item `json:"results"`
+		}
+		err = json.Unmarshal(b, &res)
+		items = res.Results
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rerank http decode: %w", err)
+	}
+
+	out := make([]RerankResult, len(items))
+	for i, it := range items {
+		out[i] = RerankResult{Index: it.Index, Score: it.Score}
+		if it.RelevanceScore != nil {
+			out[i].Score = *it.RelevanceScore
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return headOf(out, topN), nil
+}
+
+const llmRerankTpl = "Rate how relevant each passage is to the query, from 0 (irrelevant) to 10 (answers it directly).\n" +
+	"Return only a JSON array of numbers, one score per passage in the given order.\n\nQuery: %s\n\nPassages:\n%s\nscores:\n"
+
+// llmReranker 以 Summarize 模型为候选打分，适用于没有专用重排服务的部署
+type llmReranker struct {
+	client llm.Client
+}
+
+func (r *llmReranker) Name() string { return "llm" }
+
+func (r *llmReranker) Rerank(ctx context.Context, query string, docs []string, topN int) ([]RerankResult, error) {
+	var sb strings.Builder
+	for i, doc := range docs {
+		sb.WriteString("[" + strconv.Itoa(i+1) + "] ")
+		sb.WriteString(strings.ReplaceAll(words.TakeHead(doc, 500, ".."), "\n", " "))
+		sb.WriteString("\n")
+	}
+	text, _, err := r.client.Generate(ctx, fmt.Sprintf(llmRerankTpl, query, sb.String()))
+	if err != nil {
+		return nil, err
+	}
+	scores, err := parseScores(text, len(docs))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]RerankResult, len(scores))
+	for i, s := range scores {
+		// 归一化到 0-1，与交叉编码器的得分范围一致
+		out[i] = RerankResult{Index: i, Score: s / 10}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return headOf(out, topN), nil
+}
+
+// parseScores 从模型输出中提取 JSON 数组形式的得分
+func parseScores(text string, n int) ([]float32, error) {
+	if _, b, ok := strings.Cut(text, "</think>"); ok {
+		text = b
+	}
+	start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("rerank llm: no scores in %q", words.TakeHead(text, 100, ".."))
+	}
+	var scores []float32
+	if err := json.Unmarshal([]byte(text[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("rerank llm: %w", err)
+	}
+	if len(scores) != n {
+		return nil, fmt.Errorf("rerank llm: got %d scores for %d passages", len(scores), n)
+	}
+	return scores, nil
+}
@@ -0,0 +1,100 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/settings"
+)
+
+func TestHTTPReranker(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		if r.URL.Path == "/tei" {
+			_, _ = w.Write([]byte(`[{"index":1,"score":0.2},{"index":0,"score":0.9}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"results":[{"index":2,"relevance_score":0.8},{"index":0,"relevance_score":0.1}]}`))
+	}))
+	defer srv.Close()
+
+	rr, err := NewReranker(settings.Rerank{Backend: "http", URL: srv.URL + "/v1/rerank", APIKey: "sk-test", Model: "bge-reranker", Timeout: time.Second})
+	require.NoError(t, err)
+	res, err := rr.Rerank(context.Background(), "q", []string{"a", "b", "c"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []RerankResult{{Index: 2, Score: 0.8}, {Index: 0, Score: 0.1}}, res)
+	assert.Equal(t, "bge-reranker", got["model"])
+	assert.Equal(t, []any{"a", "b", "c"}, got["documents"])
+	assert.EqualValues(t, 2, got["top_n"])
+
+	rr, err = NewReranker(settings.Rerank{Backend: "http", URL: srv.URL + "/tei", APIKey: "sk-test", Timeout: time.Second})
+	require.NoError(t, err)
+	res, err = rr.Rerank(context.Background(), "q", []string{"a", "b"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []RerankResult{{Index: 0, Score: 0.9}}, res)
+
+	_, err = NewReranker(settings.Rerank{Backend: "http"})
+	assert.Error(t, err)
+	_, err = NewReranker(settings.Rerank{Backend: "nope"})
+	assert.ErrorIs(t, err, ErrUnknownRerankBackend)
+	rr, err = NewReranker(settings.Rerank{})
+	assert.NoError(t, err)
+	assert.Nil(t, rr)
+}
+
+func TestParseScores(t *testing.T) {
+	scores, err := parseScores("<think>hmm</think>\nscores: [3, 9.5, 0]", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []float32{3, 9.5, 0}, scores)
+
+	_, err = parseScores("[1, 2]", 3)
+	assert.Error(t, err)
+	_, err = parseScores("none", 1)
+	assert.Error(t, err)
+}
+
+type fakeReranker struct {
+	res []RerankResult
+}
+
+func (f *fakeReranker) Name() string { return "fake" }
+
+func (f *fakeReranker) Rerank(ctx context.Context, query string, docs []string, topN int) ([]RerankResult, error) {
+	return f.res, nil
+}
+
+func TestRerankSlice(t *testing.T) {
+	orig := *settings.Current
+	defer func() { *settings.Current = orig }()
+	rrOnce.Do(func() {})
+	defer func() { rrInst = nil }()
+
+	ctx := context.Background()
+	data := []string{"a", "b", "c", "d"}
+	text := func(s *string) string { return *s }
+
+	// 未启用时按原顺序截取
+	rrInst = nil
+	assert.Equal(t, 4, rerankCandidates(RerankDoc, 4))
+	assert.Equal(t, []string{"a", "b"}, rerankSlice(ctx, RerankDoc, "q", data, text, 2))
+
+	rrInst = &fakeReranker{res: []RerankResult{{Index: 3, Score: 0.9}, {Index: 1, Score: 0.5}, {Index: 0, Score: 0.1}}}
+	settings.Current.Rerank.Targets = map[string]int{RerankDoc: 20}
+	assert.Equal(t, 20, rerankCandidates(RerankDoc, 4))
+	assert.Equal(t, []string{"d", "b"}, rerankSlice(ctx, RerankDoc, "q", data, text, 2))
+
+	settings.Current.Rerank.MinScore = 0.3
+	assert.Equal(t, []string{"d", "b"}, rerankSlice(ctx, RerankDoc, "q", data, text, 3))
+
+	// 未配置的目标不重排
+	assert.Equal(t, []string{"a", "b"}, rerankSlice(ctx, RerankMemory, "q", data, text, 2))
+}
@@ -0,0 +1,95 @@
+package stores
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/settings"
+)
+
+// secretPrefix 标记已加密的值
+const secretPrefix = "enc:"
+
+var (
+	ErrNoSecretKey   = errors.New("secret key not configured")
+	ErrInvalidSecret = errors.New("invalid secret")
+)
+
+func secretAEAD(key string) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, ErrNoSecretKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSecret 使用 AES-GCM 加密，已加密的值原样返回。
+// 只有带前缀且能以 key 解密的值才视为已加密，恰好以前缀开头的明文照常加密
+func encryptSecret(key, s string) (string, error) {
+	if len(s) == 0 {
+		return s, nil
+	}
+	if strings.HasPrefix(s, secretPrefix) {
+		if _, err := decryptSecret(key, s); err == nil {
+			return s, nil
+		}
+	}
+	aead, err := secretAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(s), nil)
+	return secretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret 解密 encryptSecret 的结果，未加密的值原样返回
+func decryptSecret(key, s string) (string, error) {
+	if !strings.HasPrefix(s, secretPrefix) {
+		return s, nil
+	}
+	aead, err := secretAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawStdEncoding.DecodeString(s[len(secretPrefix):])
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+	ns := aead.NonceSize()
+	if len(data) < ns {
+		return "", ErrInvalidSecret
+	}
+	plain, err := aead.Open(nil, data[:ns], data[ns:], nil)
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+	return string(plain), nil
+}
+
+func sealCredential(c *mcps.Credential) error {
+	key := settings.Current.SecretKey
+	return c.WalkSecrets(func(s string) (string, error) {
+		return encryptSecret(key, s)
+	})
+}
+
+func openCredential(c *mcps.Credential) error {
+	key := settings.Current.SecretKey
+	return c.WalkSecrets(func(s string) (string, error) {
+		return decryptSecret(key, s)
+	})
+}
@@ -0,0 +1,115 @@
+package stores
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/models/mcps"
+)
+
+func TestEncryptDecryptSecret(t *testing.T) {
+	enc, err := encryptSecret("k1", "sk-abc")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enc, secretPrefix))
+	assert.NotContains(t, enc, "sk-abc")
+
+	again, err := encryptSecret("k1", enc)
+	require.NoError(t, err)
+	assert.Equal(t, enc, again, "should not encrypt twice")
+
+	dec, err := decryptSecret("k1", enc)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-abc", dec)
+
+	_, err = decryptSecret("k2", enc)
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+
+	// 以前缀开头的明文仍会加密
+	enc, err = encryptSecret("k1", "enc:plain-token")
+	require.NoError(t, err)
+	assert.NotEqual(t, "enc:plain-token", enc)
+	dec, err = decryptSecret("k1", enc)
+	require.NoError(t, err)
+	assert.Equal(t, "enc:plain-token", dec)
+
+	_, err = encryptSecret("", "sk-abc")
+	assert.ErrorIs(t, err, ErrNoSecretKey)
+
+	plain, err := decryptSecret("", "plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", plain)
+}
+
+func TestCredentialMaskRestore(t *testing.T) {
+	old := mcps.Credential{
+		Headers:      map[string]string{"X-Api-Key": "enc:a"},
+		Token:        "enc:b",
+		ClientID:     "cid",
+		ClientSecret: "enc:c",
+	}
+	cred := old
+	cred.Headers = map[string]string{"X-Api-Key": "k"}
+	cred.Mask()
+	assert.Equal(t, mcps.SecretMask, cred.Headers["X-Api-Key"])
+	assert.Equal(t, mcps.SecretMask, cred.Token)
+	assert.Equal(t, mcps.SecretMask, cred.ClientSecret)
+	assert.Equal(t, "cid", cred.ClientID)
+	assert.True(t, cred.HasMasked())
+
+	cred.Token = "new-token"
+	cred.Restore(old)
+	assert.Equal(t, "enc:a", cred.Headers["X-Api-Key"])
+	assert.Equal(t, "new-token", cred.Token)
+	assert.Equal(t, "enc:c", cred.ClientSecret)
+	assert.False(t, cred.HasMasked())
+}
+
+func TestPatchMCPServerCredential(t *testing.T) {
+	obj := &mcps.Server{ServerBasic: mcps.ServerBasic{
+		Name:       "s1",
+		AuthType:   mcps.AuthTypeHeader,
+		Credential: mcps.Credential{Headers: map[string]string{"X-Api-Key": "k"}},
+	}}
+	PatchMCPServer(obj)
+	require.NotNil(t, obj.HeaderFunc)
+	assert.Equal(t, map[string]string{"X-Api-Key": "k"}, obj.HeaderFunc(context.Background()))
+
+	obj = &mcps.Server{ServerBasic: mcps.ServerBasic{
+		Name:       "s2",
+		HeaderCate: mcps.HeaderCateAuthorization,
+		AuthType:   mcps.AuthTypeBearer,
+		Credential: mcps.Credential{Token: "t2"},
+	}}
+	PatchMCPServer(obj)
+	require.NotNil(t, obj.HeaderFunc)
+	assert.Equal(t, "Bearer t2", obj.HeaderFunc(context.Background())["Authorization"])
+
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_ = r.ParseForm()
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"at3","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	obj = &mcps.Server{ServerBasic: mcps.ServerBasic{
+		Name:     "s3",
+		AuthType: mcps.AuthTypeOAuth2,
+		Credential: mcps.Credential{
+			TokenURL: ts.URL, ClientID: "cid", ClientSecret: "cs",
+		},
+	}}
+	PatchMCPServer(obj)
+	require.NotNil(t, obj.HeaderFunc)
+	assert.Equal(t, "Bearer at3", obj.HeaderFunc(context.Background())["Authorization"])
+	assert.Equal(t, "Bearer at3", obj.HeaderFunc(context.Background())["Authorization"])
+	assert.Equal(t, 1, hits, "token should be reused before expiry")
+}
@@ -0,0 +1,292 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/cupogo/andvari/models/oid"
+
+	"github.com/liut/morign/pkg/models/capability"
+	"github.com/liut/morign/pkg/models/convo"
+	"github.com/liut/morign/pkg/models/corpus"
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/services/llm"
+	"github.com/liut/morign/pkg/settings"
+)
+
+// ErrVectorDim 向量维度与向量空间不一致
+var ErrVectorDim = errors.New("mismatch dimension of vector")
+
+// hnsw 索引支持的最大维度，超出时只能顺序扫描
+const maxIndexDim = 2000
+
+// VectorSpace 向量空间，由嵌入模型和维度确定。
+// 不同空间的向量并存于同一张表，以 model 列区分，匹配函数和索引按维度生成。
+type VectorSpace struct {
+	Model string `json:"model"`
+	Dim   int    `json:"dim"`
+
+	client llm.Client
+}
+
+// ActiveSpace 当前配置的向量空间，用于写入和匹配
+func ActiveSpace() VectorSpace {
+	return VectorSpace{Model: settings.Current.Embedding.Model, Dim: settings.Current.EmbeddingDim}
+}
+
+// NewVectorSpace 以指定的嵌入服务创建向量空间，用于迁移到新的嵌入模型
+func NewVectorSpace(p settings.Provider, dim int) (VectorSpace, error) {
+	vs := VectorSpace{Model: p.Model, Dim: dim}
+	if len(vs.Model) == 0 || dim <= 0 {
+		return vs, fmt.Errorf("%w: invalid vector space %s/%d", ErrEmptyParam, vs.Model, dim)
+	}
+	var err error
+	vs.client, err = llm.NewClient(
+		llm.WithProvider(p.Type),
+		llm.WithAPIKey(p.APIKey),
+		llm.WithBaseURL(p.URL),
+		llm.WithModel(p.Model),
+		llm.WithDebug(p.Debug),
+		llm.WithLogDir(p.LogDir),
+	)
+	return vs, err
+}
+
+func (vs VectorSpace) String() string { return vs.Model + "/" + strconv.Itoa(vs.Dim) }
+
+// Suffix 维度的十六进制表示，与 corpus_vector_400 的命名一致，400=1024, 600=1536
+func (vs VectorSpace) Suffix() string { return strconv.FormatInt(int64(vs.Dim), 16) }
+
+func (vs VectorSpace) embeddingClient() llm.Client {
+	if vs.client != nil {
+		return vs.client
+	}
+	return GetLLMEmbeddingClient()
+}
+
+type ctxVectorSpaceKey struct{}
+
+// ContextWithVectorSpace 指定上下文中生成和匹配向量使用的向量空间
+func ContextWithVectorSpace(ctx context.Context, vs VectorSpace) context.Context {
+	return context.WithValue(ctx, ctxVectorSpaceKey{}, vs)
+}
+
+// VectorSpaceFromContext 返回上下文中的向量空间，未指定时为 ActiveSpace
+func VectorSpaceFromContext(ctx context.Context) VectorSpace {
+	if vs, ok := ctx.Value(ctxVectorSpaceKey{}).(VectorSpace); ok {
+		return vs
+	}
+	return ActiveSpace()
+}
+
+// vectorTarget 存放向量的表，匹配函数为 vector_match_{name}_{suffix}
+type vectorTarget struct {
+	name  string
+	table string
+	// 关联对象的列及匹配结果中的列名和类型
+	key, col, typ string
+	// 限定范围的列，非空时匹配函数多一个 scope_id 参数，如记忆的所有人
+	scope string
+}
+
+var vectorTargets = []vectorTarget{
+	{"docs", corpus.DocVectorTable, "doc_id", "doc_id", "bigint", ""},
+	{"memory", convo.MemoryVectorTable, "mem_id", "doc_id", "bigint", "owner_id"},
+	{"capability", capability.CapabilityVectorTable, "cap_id", "doc_id", "bigint", ""},
+	{"tool", mcps.ToolVectorTable, "name", "name", "varchar", ""},
+}
+
+func vectorTargetOf(name string) vectorTarget {
+	for _, vt := range vectorTargets {
+		if vt.name == name {
+			return vt
+		}
+	}
+	panic("unknown vector target " + name)
+}
+
+func (vt vectorTarget) funcName(vs VectorSpace) string {
+	return "vector_match_" + vt.name + "_" + vs.Suffix()
+}
+
+// matchSQL 匹配查询，参数依次为向量、模型、范围（如有）、阈值和数量
+func (vt vectorTarget) matchSQL(vs VectorSpace) string {
+	if len(vt.scope) > 0 {
+		return "SELECT * FROM " + vt.funcName(vs) + "(?, ?, ?, ?, ?)"
+	}
+	return "SELECT * FROM " + vt.funcName(vs) + "(?, ?, ?, ?)"
+}
+
+// funcDDL 按维度生成的匹配函数，未记录模型的旧向量视为属于任一模型
+func (vt vectorTarget) funcDDL(vs VectorSpace) string {
+	var param, cond string
+	if len(vt.scope) > 0 {
+		param = "\n  scope_id bigint,"
+		cond = " AND t." + vt.scope + " = scope_id"
+	}
+	return fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s (
+  query_embedding vector(%[2]d),
+  model_name text,%[7]s
+  similarity_threshold float,
+  match_count int
+)
+RETURNS TABLE (%[3]s %[4]s, subject text, similarity float)
+AS $$
+BEGIN
+  RETURN QUERY
+  SELECT t.%[5]s, t.subject, (t.embedding::vector(%[2]d) <=> query_embedding) AS similarity
+  FROM %[6]s t
+  WHERE vector_dims(t.embedding) = %[2]d AND t.model IN (model_name, '')%[8]s
+    AND (t.embedding::vector(%[2]d) <=> query_embedding) < similarity_threshold
+  ORDER BY t.embedding::vector(%[2]d) <=> query_embedding
+  LIMIT match_count;
+END;
+$$ LANGUAGE plpgsql`, vt.funcName(vs), vs.Dim, vt.col, vt.typ, vt.key, vt.table, param, cond)
+}
+
+// indexDDL 按维度的部分表达式索引。限定范围的目标每个范围的向量不多，
+// 近似索引先取近邻再按范围过滤会漏掉结果，改为在范围列上建普通索引
+func (vt vectorTarget) indexDDL(vs VectorSpace) string {
+	if len(vt.scope) > 0 {
+		return fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_%[2]s ON %[1]s (%[2]s, model)", vt.table, vt.scope)
+	}
+	return fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%[1]s_embedding_%[2]s ON %[1]s
+  USING hnsw ((embedding::vector(%[3]d)) vector_cosine_ops) WHERE vector_dims(embedding) = %[3]d`,
+		vt.table, vs.Suffix(), vs.Dim)
+}
+
+// EnsureVectorSpace 创建向量空间维度对应的匹配函数和索引
+func EnsureVectorSpace(ctx context.Context, db ormDB, vs VectorSpace) error {
+	if vs.Dim <= 0 {
+		return fmt.Errorf("%w: %d", ErrVectorDim, vs.Dim)
+	}
+	for _, vt := range vectorTargets {
+		if _, err := db.ExecContext(ctx, vt.funcDDL(vs)); err != nil {
+			logger().Infow("create vector match func fail", "target", vt.name, "space", vs, "err", err)
+			return err
+		}
+		if vs.Dim > maxIndexDim && len(vt.scope) == 0 {
+			logger().Infow("dimension too large for index, skip", "target", vt.name, "space", vs)
+			continue
+		}
+		if _, err := db.ExecContext(ctx, vt.indexDDL(vs)); err != nil {
+			logger().Infow("create vector index fail", "target", vt.name, "space", vs, "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareFreshVectorColumn 新建的向量列不限维度，而已发布的早期迁移以维度 1024 建 ivfflat 索引，
+// 该迁移未运行且表为空时暂设维度，其后的 vector_space 迁移会删除该索引并恢复为不限维度
+func prepareFreshVectorColumn(ctx context.Context, db ormDB) error {
+	var applied bool
+	err := db.NewRaw("SELECT to_regclass('bun_migrations') IS NOT NULL").Scan(ctx, &applied)
+	if err != nil {
+		return err
+	}
+	if applied {
+		err = db.NewRaw("SELECT EXISTS (SELECT 1 FROM bun_migrations WHERE name = ?)", "20260312010200").Scan(ctx, &applied)
+		if err != nil || applied {
+			return err
+		}
+	}
+	var fresh bool
+	err = db.NewRaw("SELECT a.atttypmod < 0 AND NOT EXISTS (SELECT 1 FROM "+corpus.DocVectorTable+
+		") FROM pg_attribute a WHERE a.attrelid = ?::regclass AND a.attname = 'embedding'", corpus.DocVectorTable).Scan(ctx, &fresh)
+	if err != nil || !fresh {
+		return err
+	}
+	_, err = db.ExecContext(ctx, "ALTER TABLE "+corpus.DocVectorTable+" ALTER COLUMN embedding TYPE vector(1024)")
+	return err
+}
+
+// ClaimLegacyVectors 将未记录模型且维度一致的旧向量归入向量空间
+func ClaimLegacyVectors(ctx context.Context, db ormDB, vs VectorSpace) (n int64, err error) {
+	for _, vt := range vectorTargets {
+		res, err := db.NewUpdate().Table(vt.table).Set("model = ?", vs.Model).
+			Where("model = '' AND vector_dims(embedding) = ?", vs.Dim).Exec(ctx)
+		if err != nil {
+			return n, err
+		}
+		if c, err := res.RowsAffected(); err == nil {
+			n += c
+		}
+	}
+	return
+}
+
+// VectorStat 向量空间中各目标的向量数量
+type VectorStat struct {
+	Target string `bun:"target" json:"target"`
+	Model  string `bun:"model" json:"model"`
+	Dim    int    `bun:"dim" json:"dim"`
+	Count  int    `bun:"count" json:"count"`
+}
+
+// VectorStats 统计各目标按模型和维度的向量数量
+func VectorStats(ctx context.Context, db ormDB) (data []VectorStat, err error) {
+	for _, vt := range vectorTargets {
+		var rows []VectorStat
+		err = db.NewRaw("SELECT ? AS target, model, vector_dims(embedding) AS dim, count(*) AS count FROM "+
+			vt.table+" GROUP BY 2, 3 ORDER BY 2, 3", vt.name).Scan(ctx, &rows)
+		if err != nil {
+			return
+		}
+		data = append(data, rows...)
+	}
+	return
+}
+
+// DropVectorSpace 删除模型的全部向量，不能删除当前使用的模型
+func DropVectorSpace(ctx context.Context, db ormDB, model string) (n int64, err error) {
+	if len(model) == 0 || model == ActiveSpace().Model {
+		return 0, fmt.Errorf("refuse to drop vectors of active or empty model %q", model)
+	}
+	for _, vt := range vectorTargets {
+		res, err := db.NewDelete().Table(vt.table).Where("model = ?", model).Exec(ctx)
+		if err != nil {
+			return n, err
+		}
+		if c, err := res.RowsAffected(); err == nil {
+			n += c
+		}
+	}
+	return
+}
+
+// dbGetVector 查找对象在向量空间中的向量，兼容未记录模型的旧向量
+func dbGetVector(ctx context.Context, db ormDB, obj Model, key string, val any, vs VectorSpace) error {
+	return dbGet(ctx, db, obj, key+" = ? AND model IN (?, '')", val, vs.Model)
+}
+
+// scanVectorMatch 在上下文的向量空间中匹配目标，限定范围的目标需要给出 scope
+func scanVectorMatch(ctx context.Context, db ormDB, target string, vec corpus.Vector, threshold float32, limit int,
+	dest any, scope ...oid.OID) error {
+	query, args, err := vectorMatchQuery(ctx, target, vec, threshold, limit, scope...)
+	if err != nil {
+		return err
+	}
+	return db.NewRaw(query, args...).Scan(ctx, dest)
+}
+
+// vectorMatchQuery 返回匹配查询及参数，供需要再过滤结果的调用方包装
+func vectorMatchQuery(ctx context.Context, target string, vec corpus.Vector, threshold float32, limit int,
+	scope ...oid.OID) (string, []any, error) {
+	vs := VectorSpaceFromContext(ctx)
+	if len(vec) != vs.Dim {
+		return "", nil, fmt.Errorf("%w: got %d, want %d of %s", ErrVectorDim, len(vec), vs.Dim, vs)
+	}
+	vt := vectorTargetOf(target)
+	args := []any{vec, vs.Model}
+	if len(vt.scope) > 0 {
+		if len(scope) == 0 || scope[0].IsZero() {
+			return "", nil, fmt.Errorf("%w: scope of %s", ErrEmptyParam, target)
+		}
+		args = append(args, scope[0])
+	}
+	args = append(args, threshold, limit)
+	return vt.matchSQL(vs), args, nil
+}
@@ -0,0 +1,72 @@
+package stores
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liut/morign/pkg/settings"
+)
+
+func TestVectorSpaceNaming(t *testing.T) {
+	vs := VectorSpace{Model: "bge-m3", Dim: 1024}
+	assert.Equal(t, "400", vs.Suffix())
+	assert.Equal(t, "600", VectorSpace{Dim: 1536}.Suffix())
+	assert.Equal(t, "300", VectorSpace{Dim: 768}.Suffix())
+	assert.Equal(t, "bge-m3/1024", vs.String())
+
+	vt := vectorTargetOf("capability")
+	assert.Equal(t, "vector_match_capability_400", vt.funcName(vs))
+	assert.Equal(t, "SELECT * FROM vector_match_capability_400(?, ?, ?, ?)", vt.matchSQL(vs))
+
+	ddl := vt.funcDDL(vs)
+	assert.Contains(t, ddl, "query_embedding vector(1024)")
+	assert.Contains(t, ddl, "RETURNS TABLE (doc_id bigint, subject text, similarity float)")
+	assert.Contains(t, ddl, "SELECT t.cap_id, t.subject")
+	assert.Contains(t, ddl, "FROM api_capability_vector t")
+	assert.Contains(t, ddl, "vector_dims(t.embedding) = 1024 AND t.model IN (model_name, '')")
+
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS idx_mcp_tool_vector_embedding_600 ON mcp_tool_vector\n"+
+		"  USING hnsw ((embedding::vector(1536)) vector_cosine_ops) WHERE vector_dims(embedding) = 1536",
+		vectorTargetOf("tool").indexDDL(VectorSpace{Dim: 1536}))
+
+	assert.Panics(t, func() { vectorTargetOf("nope") })
+}
+
+func TestVectorSpaceFromContext(t *testing.T) {
+	orig := *settings.Current
+	defer func() { *settings.Current = orig }()
+	settings.Current.Embedding.Model = "bge-m3"
+	settings.Current.EmbeddingDim = 1024
+
+	ctx := context.Background()
+	assert.Equal(t, VectorSpace{Model: "bge-m3", Dim: 1024}, VectorSpaceFromContext(ctx))
+
+	vs := VectorSpace{Model: "text-embedding-3-small", Dim: 1536}
+	assert.Equal(t, vs, VectorSpaceFromContext(ContextWithVectorSpace(ctx, vs)))
+
+	_, err := NewVectorSpace(settings.Provider{Model: "m"}, 0)
+	assert.ErrorIs(t, err, ErrEmptyParam)
+	_, err = DropVectorSpace(ctx, nil, "bge-m3")
+	assert.Error(t, err)
+}
+
+func TestVectorTargetScope(t *testing.T) {
+	vs := VectorSpace{Model: "bge-m3", Dim: 1024}
+	vt := vectorTargetOf("memory")
+	assert.Equal(t, "SELECT * FROM vector_match_memory_400(?, ?, ?, ?, ?)", vt.matchSQL(vs))
+
+	ddl := vt.funcDDL(vs)
+	assert.Contains(t, ddl, "model_name text,\n  scope_id bigint,\n  similarity_threshold float")
+	assert.Contains(t, ddl, "SELECT t.mem_id, t.subject")
+	assert.Contains(t, ddl, "t.model IN (model_name, '') AND t.owner_id = scope_id\n")
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS idx_convo_memory_vector_owner_id ON convo_memory_vector (owner_id, model)",
+		vt.indexDDL(vs))
+
+	ctx := ContextWithVectorSpace(context.Background(), VectorSpace{Model: "m", Dim: 2})
+	err := scanVectorMatch(ctx, nil, "memory", []float32{1, 0}, 0.5, 5, nil)
+	assert.ErrorIs(t, err, ErrEmptyParam)
+	err = scanVectorMatch(ctx, nil, "docs", []float32{1}, 0.5, 5, nil)
+	assert.ErrorIs(t, err, ErrVectorDim)
+}
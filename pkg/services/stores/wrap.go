@@ -162,19 +162,36 @@ func (w *Wrap) Close() {
 
 // InitDB initializes database schema and runs migrations
 func InitDB(ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	sctx, cancel := context.WithTimeout(ctx, time.Second*10)
 	defer cancel()
 	db := SgtDB()
-	if err := db.InitSchemas(ctx, false); err != nil {
+	if err := db.InitSchemas(sctx, false); err != nil {
 		logger().Errorw("InitSchemas fail", "err", err)
 		return err
 	}
 
-	if err := db.RunMigrations(ctx); err != nil {
+	if err := prepareFreshVectorColumn(sctx, db); err != nil {
+		logger().Errorw("prepare vector column fail", "err", err)
+		return err
+	}
+	if err := db.RunMigrations(sctx); err != nil {
 		logger().Errorw("RunMigrations fail", "err", err)
 		return err
 	}
 
+	// 首次为已有向量建索引可能较慢，不受上面的超时限制
+	vs := ActiveSpace()
+	if err := EnsureVectorSpace(ctx, db, vs); err != nil {
+		logger().Errorw("EnsureVectorSpace fail", "space", vs, "err", err)
+		return err
+	}
+	if n, err := ClaimLegacyVectors(ctx, db, vs); err != nil {
+		logger().Errorw("ClaimLegacyVectors fail", "space", vs, "err", err)
+		return err
+	} else if n > 0 {
+		logger().Infow("claimed legacy vectors", "space", vs, "count", n)
+	}
+
 	return nil
 }
 
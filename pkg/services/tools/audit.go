@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/cupogo/andvari/models/oid"
+
+	"github.com/liut/morign/pkg/models/convo"
+	"github.com/liut/morign/pkg/services/stores"
+	"github.com/liut/morign/pkg/settings"
+	"github.com/liut/morign/pkg/utils/words"
+)
+
+// 工具来源
+const (
+	ToolSourceBuiltin    = "builtin"
+	ToolSourceCapability = "capability"
+	ToolSourceMCPPrefix  = "mcp:"
+)
+
+// auditor 异步写入工具调用记录，队列满时丢弃并记录日志，不阻塞工具循环
+type auditor struct {
+	ch   chan convo.ToolCallBasic
+	save func(ctx context.Context, in convo.ToolCallBasic) error
+}
+
+func newAuditor(size int, save func(ctx context.Context, in convo.ToolCallBasic) error) *auditor {
+	a := &auditor{ch: make(chan convo.ToolCallBasic, max(size, 1)), save: save}
+	go a.loop()
+	return a
+}
+
+func (a *auditor) loop() {
+	for in := range a.ch {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := a.save(ctx, in); err != nil {
+			logger().Infow("save tool call fail", "tool", in.ToolName, "err", err)
+		}
+		cancel()
+	}
+}
+
+func (a *auditor) push(in convo.ToolCallBasic) {
+	select {
+	case a.ch <- in:
+	default:
+		logger().Warnw("tool call audit queue full, dropped", "tool", in.ToolName, "session", in.SessionID)
+	}
+}
+
+// toolSource 返回工具来源
+func (r *Registry) toolSource(name string) string {
+	r.serversMu.RLock()
+	defer r.serversMu.RUnlock()
+	for sn, conn := range r.servers {
+		for _, tn := range conn.toolNames {
+			if tn == name {
+				return ToolSourceMCPPrefix + sn
+			}
+		}
+	}
+	if strings.HasPrefix(name, "capability_") {
+		return ToolSourceCapability
+	}
+	return ToolSourceBuiltin
+}
+
+// audit 记录一次工具调用
+func (r *Registry) audit(ctx context.Context, name string, params, result map[string]any, err error, cost time.Duration) {
+	if r.auditor == nil {
+		return
+	}
+	r.auditor.push(newToolCallRecord(ctx, name, r.toolSource(name), params, result, err, cost))
+}
+
+func newToolCallRecord(ctx context.Context, name, source string, params, result map[string]any,
+	err error, cost time.Duration) convo.ToolCallBasic {
+	in := convo.ToolCallBasic{
+		SessionID: oid.Cast(stores.ConvoIDFromContext(ctx)),
+		ToolName:  name,
+		Source:    source,
+		Arguments: params,
+		Status:    convo.ToolCallStatusOk,
+		Latency:   int(cost.Milliseconds()),
+	}
+	if user, ok := stores.UserFromContext(ctx); ok {
+		in.OwnerID = oid.Cast(user.OID)
+	}
+	if in.Arguments == nil {
+		in.Arguments = map[string]any{}
+	}
+	if err != nil {
+		in.Status = convo.ToolCallStatusError
+		in.Error = err.Error()
+	} else if isErr, _ := result["isError"].(bool); isErr {
+		in.Status = convo.ToolCallStatusFailed
+	}
+	if result != nil {
+		if b, e := json.Marshal(result); e == nil {
+			in.Result = words.TakeHead(string(b), settings.Current.ToolAuditResultLen, "...")
+		}
+	}
+	return in
+}
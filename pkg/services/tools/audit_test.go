@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cupogo/andvari/models/oid"
+	auth "github.com/liut/simpauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/models/convo"
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/services/stores"
+)
+
+func TestNewToolCallRecord(t *testing.T) {
+	csid := oid.NewID(oid.OtEvent)
+	uid := oid.NewID(oid.OtAccount)
+	ctx := stores.ContextWithConvoID(context.Background(), csid.String())
+	ctx = auth.ContextWithUser(ctx, &stores.User{OID: uid.String(), UID: "alice"})
+
+	params := map[string]any{"method": "POST", "endpoint": "/api/accounts"}
+	in := newToolCallRecord(ctx, ToolNameCapabilityInvoke, ToolSourceCapability, params,
+		mcps.BuildToolSuccessResult("created"), nil, 120*time.Millisecond)
+	assert.Equal(t, csid, in.SessionID)
+	assert.Equal(t, uid, in.OwnerID)
+	assert.Equal(t, convo.ToolCallStatusOk, in.Status)
+	assert.Equal(t, 120, in.Latency)
+	assert.Equal(t, params, in.Arguments)
+	assert.Contains(t, in.Result, "created")
+
+	in = newToolCallRecord(context.Background(), "x", ToolSourceBuiltin, nil, mcps.BuildToolErrorResult("bad"), nil, 0)
+	assert.Equal(t, convo.ToolCallStatusFailed, in.Status)
+	assert.NotNil(t, in.Arguments)
+	assert.True(t, in.OwnerID.IsZero())
+
+	in = newToolCallRecord(context.Background(), "x", ToolSourceBuiltin, nil, nil, errors.New("boom"), 0)
+	assert.Equal(t, convo.ToolCallStatusError, in.Status)
+	assert.Equal(t, "boom", in.Error)
+}
+
+func TestInvokeAudit(t *testing.T) {
+	saved := make(chan convo.ToolCallBasic, 4)
+	r := NewRegistry(nil)
+	r.auditor = newAuditor(4, func(ctx context.Context, in convo.ToolCallBasic) error {
+		saved <- in
+		return nil
+	})
+	r.servers["gh"] = &MCPConnection{Name: "gh", toolNames: []string{"gh-issues"}}
+	require.NoError(t, r.AddInvoker("gh-issues", func(ctx context.Context, _ map[string]any) (map[string]any, error) {
+		return mcps.BuildToolSuccessResult("ok"), nil
+	}, "", nil))
+
+	_, err := r.Invoke(context.Background(), "gh-issues", map[string]any{"q": "bug"})
+	require.NoError(t, err)
+	_, err = r.Invoke(context.Background(), ToolNameCodeRun, map[string]any{"code": "1+1"})
+	require.NoError(t, err)
+
+	for _, want := range []struct{ name, source string }{
+		{"gh-issues", ToolSourceMCPPrefix + "gh"},
+		{ToolNameCodeRun, ToolSourceBuiltin},
+	} {
+		select {
+		case in := <-saved:
+			assert.Equal(t, want.name, in.ToolName)
+			assert.Equal(t, want.source, in.Source)
+		case <-time.After(time.Second):
+			t.Fatal("tool call not audited")
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"path"
+	"slices"
+	"strings"
+
+	mcp "github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/liut/morign/pkg/models/aigc"
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/services/stores"
+)
+
+// uncachedTools 有副作用或依赖会话状态的工具，永不缓存
+var uncachedTools = []string{
+	ToolNameKBCreate,
+	ToolNameMemoryStore,
+	ToolNameMemoryForget,
+	ToolNameToolSearch,
+	ToolNameResultPage,
+}
+
+// userScopedTools 结果总是依赖当前用户的工具，不允许跨用户共享
+var userScopedTools = []string{
+	ToolNameMemoryList,
+	ToolNameMemoryRecall,
+	ToolNameCapabilityInvoke,
+}
+
+// WithToolCache 设置 preset 中的工具结果缓存规则，需同时设置 WithResultStore
+func WithToolCache(rules map[string]aigc.ToolCacheRule) RegistryOption {
+	return func(r *Registry) {
+		r.cacheRules = rules
+	}
+}
+
+// cacheRule 返回工具的缓存规则，先按工具名精确匹配，再按通配模式，写工具、非 GET 和 dry_run 的能力调用不缓存
+func (r *Registry) cacheRule(name string, params map[string]any) (rule aigc.ToolCacheRule, ok bool) {
+	if r.rc == nil || len(r.cacheRules) == 0 || slices.Contains(uncachedTools, name) {
+		return
+	}
+	if name == ToolNameCapabilityInvoke {
+		if dryRun, _ := params["dry_run"].(bool); dryRun || !strings.EqualFold(mcps.StringArg(params, "method"), "GET") {
+			return
+		}
+	}
+	if r.isWriteTool(name) {
+		return
+	}
+	if rule, ok = r.cacheRules[name]; !ok {
+		for p, v := range r.cacheRules {
+			if m, _ := path.Match(p, name); m {
+				rule, ok = v, true
+				break
+			}
+		}
+	}
+	if rule.TTL <= 0 {
+		return rule, false
+	}
+	if slices.Contains(userScopedTools, name) {
+		rule.Shared = false
+	}
+	return
+}
+
+// isReadOnly MCP 工具明确声明了只读，未声明时按 MCP 约定视为非只读
+func isReadOnly(tool mcp.Tool) bool {
+	ro := tool.Annotations.ReadOnlyHint
+	return ro != nil && *ro
+}
+
+// isWriteTool MCP 工具未声明只读
+func (r *Registry) isWriteTool(name string) bool {
+	r.serversMu.RLock()
+	defer r.serversMu.RUnlock()
+	for _, conn := range r.servers {
+		if slices.Contains(conn.writeTools, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey 由工具名、调用范围和归一化参数组成，非共享规则在无用户时返回空
+func cacheKey(ctx context.Context, name string, rule aigc.ToolCacheRule, params map[string]any) string {
+	scope := "shared"
+	if !rule.Shared {
+		user, ok := stores.UserFromContext(ctx)
+		if !ok || len(user.OID) == 0 {
+			return ""
+		}
+		scope = user.OID
+	}
+	// json 按键排序输出 map，相同参数得到相同摘要
+	b, err := json.Marshal(normalizeArgs(params))
+	if err != nil {
+		return ""
+	}
+	sum := sha1.Sum(b)
+	return "toolcache-" + name + "-" + scope + "-" + hex.EncodeToString(sum[:])
+}
+
+// normalizeArgs 去除字符串首尾空白和空值
+func normalizeArgs(v any) any {
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, x := range val {
+			if x == nil {
+				continue
+			}
+			out[k] = normalizeArgs(x)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, x := range val {
+			out[i] = normalizeArgs(x)
+		}
+		return out
+	}
+	return v
+}
+
+// cachedInvoke 命中缓存时直接返回，否则调用并缓存成功结果
+func (r *Registry) cachedInvoke(ctx context.Context, name string, invoker Invoker, params map[string]any) (map[string]any, error) {
+	rule, ok := r.cacheRule(name, params)
+	if !ok {
+		return invoker(ctx, params)
+	}
+	key := cacheKey(ctx, name, rule, params)
+	if len(key) == 0 {
+		return invoker(ctx, params)
+	}
+	if fn, ok := r.cacheScopes[name]; ok {
+		scope, err := fn(ctx)
+		if err != nil {
+			logger().Infow("tool cache scope fail", "toolName", name, "err", err)
+			return invoker(ctx, params)
+		}
+		key += "-" + scope
+	}
+
+	if b, err := r.rc.Get(ctx, key).Bytes(); err == nil {
+		var result map[string]any
+		if err = json.Unmarshal(b, &result); err == nil {
+			logger().Debugw("tool cache hit", "toolName", name, "key", key)
+			return result, nil
+		}
+	}
+
+	result, err := invoker(ctx, params)
+	if err != nil || result == nil {
+		return result, err
+	}
+	if isErr, _ := result["isError"].(bool); isErr {
+		return result, nil
+	}
+	if b, err := json.Marshal(result); err == nil {
+		if err = r.rc.Set(ctx, key, b, rule.TTL).Err(); err != nil {
+			logger().Infow("tool cache set fail", "toolName", name, "err", err)
+		}
+	}
+	return result, nil
+}
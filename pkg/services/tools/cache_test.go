@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cupogo/andvari/models/oid"
+	auth "github.com/liut/simpauth"
+	mcp "github.com/mark3labs/mcp-go/mcp"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/models/aigc"
+	"github.com/liut/morign/pkg/models/corpus"
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/services/stores"
+)
+
+func TestIsReadOnly(t *testing.T) {
+	yes, no := true, false
+	assert.True(t, isReadOnly(mcp.Tool{Annotations: mcp.ToolAnnotation{ReadOnlyHint: &yes}}))
+	assert.False(t, isReadOnly(mcp.Tool{Annotations: mcp.ToolAnnotation{ReadOnlyHint: &no}}))
+	// 未声明时按 MCP 约定视为非只读
+	assert.False(t, isReadOnly(mcp.Tool{}))
+}
+
+func TestToolCache(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	r := NewRegistry(nil, WithResultStore(rc), WithToolCache(map[string]aigc.ToolCacheRule{
+		"weather":           {TTL: time.Minute, Shared: true},
+		"profile":           {TTL: time.Minute},
+		"gh-*":              {TTL: time.Minute, Shared: true},
+		"capability_invoke": {TTL: time.Minute, Shared: true},
+	}))
+
+	calls := map[string]int{}
+	counter := func(name string) Invoker {
+		return func(ctx context.Context, args map[string]any) (map[string]any, error) {
+			calls[name]++
+			if mcps.StringArg(args, "fail") == "yes" {
+				return mcps.BuildToolErrorResult("failed"), nil
+			}
+			return mcps.BuildToolSuccessResult(map[string]any{"n": calls[name]}), nil
+		}
+	}
+	for _, name := range []string{"weather", "profile", "gh-list_issues", "gh-create_issue", ToolNameCapabilityInvoke} {
+		r.invokers[name] = counter(name)
+	}
+	r.servers["gh"] = &MCPConnection{Name: "gh", writeTools: []string{"gh-create_issue"}}
+
+	alice := auth.ContextWithUser(context.Background(), &stores.User{OID: "alice-oid", UID: "alice"})
+	bob := auth.ContextWithUser(context.Background(), &stores.User{OID: "bob-oid", UID: "bob"})
+
+	invoke := func(ctx context.Context, name string, args map[string]any) {
+		_, err := r.Invoke(ctx, name, args)
+		require.NoError(t, err)
+	}
+
+	// 归一化参数后命中
+	invoke(alice, "weather", map[string]any{"city": "Beijing", "days": nil})
+	invoke(bob, "weather", map[string]any{"city": " Beijing "})
+	assert.Equal(t, 1, calls["weather"])
+	invoke(alice, "weather", map[string]any{"city": "Shanghai"})
+	assert.Equal(t, 2, calls["weather"])
+
+	// 错误结果不缓存
+	invoke(alice, "weather", map[string]any{"fail": "yes"})
+	invoke(alice, "weather", map[string]any{"fail": "yes"})
+	assert.Equal(t, 4, calls["weather"])
+
+	// 默认按用户隔离，无用户时不缓存
+	invoke(alice, "profile", nil)
+	invoke(alice, "profile", nil)
+	invoke(bob, "profile", nil)
+	assert.Equal(t, 2, calls["profile"])
+	invoke(context.Background(), "profile", nil)
+	invoke(context.Background(), "profile", nil)
+	assert.Equal(t, 4, calls["profile"])
+
+	// 未声明只读的 MCP 工具不缓存
+	invoke(alice, "gh-list_issues", nil)
+	invoke(alice, "gh-list_issues", nil)
+	assert.Equal(t, 1, calls["gh-list_issues"])
+	invoke(alice, "gh-create_issue", nil)
+	invoke(alice, "gh-create_issue", nil)
+	assert.Equal(t, 2, calls["gh-create_issue"])
+
+	// 仅缓存 GET 能力调用，且总是按用户隔离
+	get := map[string]any{"method": "get", "endpoint": "/api/accounts"}
+	invoke(alice, ToolNameCapabilityInvoke, get)
+	invoke(alice, ToolNameCapabilityInvoke, get)
+	assert.Equal(t, 1, calls[ToolNameCapabilityInvoke])
+	invoke(bob, ToolNameCapabilityInvoke, get)
+	assert.Equal(t, 2, calls[ToolNameCapabilityInvoke])
+	post := map[string]any{"method": "POST", "endpoint": "/api/accounts"}
+	invoke(alice, ToolNameCapabilityInvoke, post)
+	invoke(alice, ToolNameCapabilityInvoke, post)
+	assert.Equal(t, 4, calls[ToolNameCapabilityInvoke])
+	dry := map[string]any{"method": "GET", "endpoint": "/api/accounts", "dry_run": true}
+	invoke(alice, ToolNameCapabilityInvoke, dry)
+	invoke(alice, ToolNameCapabilityInvoke, dry)
+	assert.Equal(t, 6, calls[ToolNameCapabilityInvoke])
+}
+
+func TestToolCacheKBSearch(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	r := NewRegistry(nil, WithResultStore(rc), WithToolCache(map[string]aigc.ToolCacheRule{
+		ToolNameKBSearch: {TTL: time.Minute, Shared: true},
+	}))
+	var calls int
+	r.invokers[ToolNameKBSearch] = func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		calls++
+		docs := corpus.Documents{{DocumentBasic: corpus.DocumentBasic{Title: "Handbook", Heading: "Leave", Content: "Ten days"}}}
+		docs[0].SetID(oid.NewID(oid.OtEvent))
+		return mcps.BuildToolSuccessResult(map[string]any{"documents": docs}), nil
+	}
+	scopes := map[string]string{"alice": "hr", "bob": "hr", "carol": "public"}
+	r.cacheScopes[ToolNameKBSearch] = func(ctx context.Context) (string, error) {
+		user, _ := stores.UserFromContext(ctx)
+		return scopes[user.UID], nil
+	}
+	r.finishers[ToolNameKBSearch] = stores.CiteSearchResult
+
+	userCtx := func(uid string) context.Context {
+		return stores.ContextWithCitations(auth.ContextWithUser(context.Background(), &stores.User{OID: uid + "-oid", UID: uid}))
+	}
+	search := func(ctx context.Context) string {
+		result, err := r.Invoke(ctx, ToolNameKBSearch, map[string]any{"subject": "leave"})
+		require.NoError(t, err)
+		content, _ := result["content"].([]map[string]any)
+		require.Len(t, content, 1)
+		text, _ := content[0]["text"].(string)
+		return text
+	}
+
+	// 可见集合相同的用户共享缓存，缓存的结果同样带有本轮的引用标记
+	alice := userCtx("alice")
+	assert.Contains(t, search(alice), "[^1]")
+	bob := userCtx("bob")
+	assert.Contains(t, search(bob), "[^1]")
+	assert.Equal(t, 1, calls)
+	assert.Len(t, stores.CitationsFromContext(bob), 1)
+
+	// 可见集合不同时不共享
+	search(userCtx("carol"))
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/settings"
+)
+
+var ErrCodeTimeout = errors.New("code run timed out")
+
+// aliveFunc 插入到每个谓词（map、filter、reduce 等的循环体）前的检查函数
+const aliveFunc = "__alive"
+
+// alivePatcher 在谓词体前插入 aliveFunc 调用，
+// 表达式没有递归和 while，循环只能经由谓词，因此每轮迭代都会检查一次
+type alivePatcher struct{}
+
+func (alivePatcher) Visit(node *ast.Node) {
+	if p, ok := (*node).(*ast.PredicateNode); ok {
+		call := &ast.CallNode{Callee: &ast.IdentifierNode{Value: aliveFunc}}
+		p.Node = &ast.SequenceNode{Nodes: []ast.Node{call, p.Node}}
+	}
+}
+
+// runCode 在受限的表达式解释器中求值
+// 只能访问 vars 中的数据和内置函数，没有文件、网络等外部访问；
+// 内存按解释器分配预算限制，超时后在下一轮迭代时中止求值
+func runCode(ctx context.Context, code string, vars map[string]any, timeout time.Duration, memory uint) (any, error) {
+	var stopped atomic.Bool
+	alive := func(...any) (any, error) {
+		if stopped.Load() {
+			return nil, ErrCodeTimeout
+		}
+		return true, nil
+	}
+	env := map[string]any{"vars": vars}
+	program, err := expr.Compile(code, expr.Env(env), expr.MaxNodes(2000),
+		expr.Function(aliveFunc, alive, new(func() bool)), expr.Patch(alivePatcher{}))
+	if err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		out any
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("runtime error: %v", r)}
+			}
+		}()
+		machine := vm.VM{MemoryBudget: memory}
+		out, err := machine.Run(program, env)
+		done <- result{out, err}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	select {
+	case res := <-done:
+		return res.out, res.err
+	case <-ctx.Done():
+		stopped.Store(true)
+		return nil, ErrCodeTimeout
+	}
+}
+
+// callCodeRun code_run 工具实现
+func (r *Registry) callCodeRun(ctx context.Context, args map[string]any) (map[string]any, error) {
+	code := mcps.StringArg(args, "code")
+	if code == "" {
+		return mcps.BuildToolErrorResult("missing required argument: code"), nil
+	}
+	vars, _ := args["vars"].(map[string]any)
+
+	out, err := runCode(ctx, code, vars, settings.Current.CodeRunTimeout, settings.Current.CodeRunMemory)
+	if err != nil {
+		logger().Infow("code run fail", "code", code, "err", err)
+		return mcps.BuildToolErrorResult(err.Error()), nil
+	}
+	if s, ok := out.(string); ok {
+		return mcps.BuildToolSuccessResult(s), nil
+	}
+	return mcps.BuildToolSuccessResult(map[string]any{"result": out}), nil
+}
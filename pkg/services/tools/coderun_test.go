@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCode(t *testing.T) {
+	vars := map[string]any{
+		"items": []any{
+			map[string]any{"price": 2.5, "qty": float64(4)},
+			map[string]any{"price": 1.25, "qty": float64(2)},
+		},
+	}
+	tests := []struct {
+		code string
+		want any
+	}{
+		{"(1234.5 * 0.13) | round()", float64(160)},
+		{"sum(map(vars.items, .price * .qty))", 12.5},
+		{`date("2024-03-01").Add(duration("720h")).Format("2006-01-02")`, "2024-03-31"},
+		{`toJSON(map(filter(vars.items, .qty > 2), .price))`, "[\n  2.5\n]"},
+		{"len(vars.items)", 2},
+	}
+	for _, tt := range tests {
+		out, err := runCode(context.Background(), tt.code, vars, time.Second, 1e6)
+		require.NoError(t, err, tt.code)
+		assert.Equal(t, tt.want, out, tt.code)
+	}
+
+	_, err := runCode(context.Background(), "1 +", nil, time.Second, 1e6)
+	assert.Error(t, err)
+
+	_, err = runCode(context.Background(), "len(map(1..100000000, # * 2))", nil, time.Second, 1e6)
+	assert.ErrorContains(t, err, "memory budget exceeded")
+
+	_, err = runCode(context.Background(), `repeat("a", 100000000)`, nil, time.Second, 1e6)
+	assert.Error(t, err)
+}
+
+func TestRunCodeTimeout(t *testing.T) {
+	before := runtime.NumGoroutine()
+	code := "reduce(1..5000, reduce(1..5000, #acc + #, 0) + #acc, 0)"
+	_, err := runCode(context.Background(), code, nil, 20*time.Millisecond, 1e9)
+	assert.ErrorIs(t, err, ErrCodeTimeout)
+	// 超时后求值协程应很快停止
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestCodeRunTool(t *testing.T) {
+	r := NewRegistry(nil)
+	res, err := r.Invoke(context.Background(), ToolNameCodeRun, map[string]any{"code": "1 + 2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"result": 3}, res["structuredContent"])
+
+	res, _ = r.Invoke(context.Background(), ToolNameCodeRun, map[string]any{"code": "vars.x + 'b'", "vars": map[string]any{"x": "a"}})
+	assert.Equal(t, "ab", res["content"].([]map[string]any)[0]["text"])
+
+	res, _ = r.Invoke(context.Background(), ToolNameCodeRun, map[string]any{})
+	assert.Equal(t, true, res["isError"])
+}
@@ -10,11 +10,12 @@ import (
 
 // MCPConnection represents a connection to an MCP server
 type MCPConnection struct {
-	Name      string
-	URL       string
-	TransType mcps.TransType
-	client    *client.Client
-	toolNames []string // 注册的工具名列表
+	Name       string
+	URL        string
+	TransType  mcps.TransType
+	client     *client.Client
+	toolNames  []string // 注册的工具名列表
+	writeTools []string // 未声明只读的工具，不缓存结果
 }
 
 // getToolKey returns the tool key with server prefix
@@ -13,6 +13,12 @@ const (
 	ToolNameKBCreate = "kb_create" // 知识库创建工具
 	ToolNameFetch    = "fetch"     // 网页抓取工具
 
+	ToolNameWebSearch = "web_search" // 网页搜索工具，配置搜索后端后注册
+	ToolNameCodeRun   = "code_run"   // 受限表达式求值工具
+
+	ToolNameToolSearch = "tool_search"      // 工具检索工具
+	ToolNameResultPage = "tool_result_page" // 截断结果分页读取工具
+
 	ToolNameMemoryList   = "memory_list"   // 记忆列表工具
 	ToolNameMemoryRecall = "memory_recall" // 记忆召回工具
 	ToolNameMemoryStore  = "memory_store"  // 记忆存储工具
@@ -37,6 +43,10 @@ var (
 					"type":        "string",
 					"description": "text of keywords or subject",
 				},
+				"collection": map[string]any{
+					"type":        "string",
+					"description": "optional name of a knowledge base collection to search in",
+				},
 			},
 			"required": []string{"subject"},
 		},
@@ -100,6 +110,96 @@ var (
 		},
 	}
 
+	// webSearchDescriptor 网页搜索工具描述
+	webSearchDescriptor = mcps.ToolDescriptor{
+		Name:        ToolNameWebSearch,
+		Description: "Search the web and return a list of results with title, url and snippet. Use fetch to read a result page when the snippet is not enough.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{
+					"type":        "string",
+					"description": "Search keywords",
+				},
+				"limit": map[string]any{
+					"type":        "integer",
+					"description": "Max results to return (default: 5)",
+					"minimum":     1,
+					"maximum":     20,
+				},
+			},
+			"required": []string{"query"},
+		},
+	}
+
+	// codeRunDescriptor 受限表达式求值工具描述
+	codeRunDescriptor = mcps.ToolDescriptor{
+		Name: ToolNameCodeRun,
+		Description: "Evaluate an expression (expr-lang syntax) for exact arithmetic, date math and reshaping JSON data, instead of computing in your head. " +
+			"Input data is accessible as `vars`. Examples: `(1234.5 * 0.13) | round()`, `date(\"2024-03-01\").Add(duration(\"720h\")).Format(\"2006-01-02\")`, " +
+			"`sum(map(vars.items, .price * .qty))`, `groupBy(vars.rows, .dept)`, `toJSON(filter(vars.list, .age > 30))`. " +
+			"No file or network access; time and memory are limited.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code": map[string]any{
+					"type":        "string",
+					"description": "Expression to evaluate",
+				},
+				"vars": map[string]any{
+					"type":        "object",
+					"description": "Optional input data, e.g. the result of a previous tool call",
+				},
+			},
+			"required": []string{"code"},
+		},
+	}
+
+	// toolSearchDescriptor 工具检索工具描述
+	toolSearchDescriptor = mcps.ToolDescriptor{
+		Name:        ToolNameToolSearch,
+		Description: "Search for more tools by intent when none of the currently offered tools fits the task. Found tools become callable in the next step.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{
+					"type":        "string",
+					"description": "What the tool should do, e.g. 'create a calendar event'",
+				},
+				"limit": map[string]any{
+					"type":        "integer",
+					"description": "Max tools to return (default: 5)",
+					"default":     5,
+					"minimum":     1,
+					"maximum":     20,
+				},
+			},
+			"required": []string{"query"},
+		},
+	}
+
+	// resultPageDescriptor 截断结果分页读取工具描述
+	resultPageDescriptor = mcps.ToolDescriptor{
+		Name:        ToolNameResultPage,
+		Description: "Read the full content of a truncated tool result page by page. Only use the id given in a truncation note.",
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"id": map[string]any{
+					"type":        "string",
+					"description": "id of the truncated result",
+				},
+				"page": map[string]any{
+					"type":        "integer",
+					"description": "Page number, starting from 1 (default: 1)",
+					"default":     1,
+					"minimum":     1,
+				},
+			},
+			"required": []string{"id"},
+		},
+	}
+
 	// memoryListDescriptor 记忆列表工具描述
 	memoryListDescriptor = mcps.ToolDescriptor{
 		Name:        ToolNameMemoryList,
@@ -221,7 +321,7 @@ var (
 	// capabilityInvokeDescriptor API 能力调用工具描述
 	capabilityInvokeDescriptor = mcps.ToolDescriptor{
 		Name:        ToolNameCapabilityInvoke,
-		Description: "Invoke a specific API capability. Returns the API response from Bus. Use the capability info from capability_match to construct the request. The method, endpoint are provided for LLM to construct the full URI. Note: Database fields use snake_case naming, not camelCase. For example, joiningAt in the model is joining_at in the database. This is especially useful when sorting.",
+		Description: "Invoke a specific API capability. Returns the API response from Bus. Use the capability info from capability_match to construct the request, only matched capabilities can be invoked and params are checked against their parameters. Users without the keeper role may only use read methods (GET by default). The method, endpoint are provided for LLM to construct the full URI. Note: Database fields use snake_case naming, not camelCase. For example, joiningAt in the model is joining_at in the database. This is especially useful when sorting.",
 		InputSchema: map[string]any{
 			"type": "object",
 			"properties": map[string]any{
@@ -237,6 +337,14 @@ var (
 					"type":        "object",
 					"description": "API parameters values to fill in (path variables, query params, body, etc.)",
 				},
+				"source": map[string]any{
+					"type":        "string",
+					"description": "API source name from capability_match, required only when the capability has a source",
+				},
+				"dry_run": map[string]any{
+					"type":        "boolean",
+					"description": "Return the built request without sending it, use it to preview a write call for the user to confirm",
+				},
 			},
 			"required": []string{"method", "endpoint"},
 		},
@@ -0,0 +1,288 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	nurl "net/url"
+
+	"github.com/liut/morign/pkg/services/stores"
+	"github.com/liut/morign/pkg/settings"
+)
+
+var (
+	ErrFetchScheme     = errors.New("only http and https are allowed")
+	ErrFetchBlockedIP  = errors.New("destination address is not allowed")
+	ErrFetchDomain     = errors.New("domain is not allowed")
+	ErrFetchRedirects  = errors.New("too many redirects")
+	ErrFetchRobots     = errors.New("disallowed by robots.txt")
+	errFetchBodyTooBig = errors.New("body too large")
+)
+
+// 除 net/netip 判定外额外禁止的网段
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // 基准测试
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64
+}
+
+// isBlockedAddr 是否为私有、回环、链路本地等不可访问的地址
+func isBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain host 是否为 domains 中的域名或其子域名
+func matchDomain(domains []string, host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "*.")
+		if len(d) > 0 && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetcher 受限的网页抓取器
+type fetcher struct {
+	allow, deny  []string
+	allowPrivate bool
+	maxBytes     int64
+	maxRedirects int
+	robots       bool
+
+	// 缓存转换后的内容
+	rc       stores.RedisClient
+	cacheTTL time.Duration
+
+	client *http.Client
+}
+
+func newFetcherFromSettings() *fetcher {
+	return newFetcher(&fetcher{
+		allow:        settings.Current.FetchAllowDomains,
+		deny:         settings.Current.FetchDenyDomains,
+		allowPrivate: settings.Current.FetchAllowPrivate,
+		maxBytes:     settings.Current.FetchMaxBytes,
+		maxRedirects: settings.Current.FetchMaxRedirects,
+		robots:       settings.Current.FetchRobots,
+		cacheTTL:     settings.Current.FetchCacheTTL,
+	})
+}
+
+func newFetcher(f *fetcher) *fetcher {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		// 在建立连接时检查实际解析出的地址，重定向和 DNS 重绑定同样受约束
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if f.allowPrivate {
+				return nil
+			}
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if isBlockedAddr(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrFetchBlockedIP, ap.Addr())
+			}
+			return nil
+		},
+	}
+	f.client = &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy:                 nil, // 经代理时无法校验目标地址
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 20 * time.Second,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       60 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > f.maxRedirects {
+				return ErrFetchRedirects
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+	return f
+}
+
+// checkURL 校验协议和域名策略，地址在连接时校验
+func (f *fetcher) checkURL(u *nurl.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrFetchScheme
+	}
+	host := u.Hostname()
+	if len(host) == 0 {
+		return fmt.Errorf("invalid url: %s", u)
+	}
+	if matchDomain(f.deny, host) {
+		return fmt.Errorf("%w: %s", ErrFetchDomain, host)
+	}
+	if len(f.allow) > 0 && !matchDomain(f.allow, host) {
+		return fmt.Errorf("%w: %s", ErrFetchDomain, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !f.allowPrivate && isBlockedAddr(addr) {
+		return fmt.Errorf("%w: %s", ErrFetchBlockedIP, host)
+	}
+	return nil
+}
+
+// get 发起 GET 请求，返回不超过 maxBytes 的响应内容，truncated 表示内容被截断
+func (f *fetcher) get(ctx context.Context, urlStr, userAgent string) (resp *http.Response, body []byte, truncated bool, err error) {
+	u, err := nurl.Parse(urlStr)
+	if err != nil {
+		return
+	}
+	if err = f.checkURL(u); err != nil {
+		return
+	}
+	if f.robots {
+		if err = f.checkRobots(ctx, u, userAgent); err != nil {
+			return
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err = f.client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err = readLimited(resp.Body, f.maxBytes)
+	if errors.Is(err, errFetchBodyTooBig) {
+		truncated, err = true, nil
+	}
+	return
+}
+
+// readLimited 读取至多 n 字节，超出时返回已读部分和 errFetchBodyTooBig
+func readLimited(r io.Reader, n int64) ([]byte, error) {
+	if n <= 0 {
+		return io.ReadAll(r)
+	}
+	b, err := io.ReadAll(io.LimitReader(r, n+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > n {
+		return b[:n], errFetchBodyTooBig
+	}
+	return b, nil
+}
+
+// checkRobots 按 robots.txt 中适用于 * 的规则校验路径，robots.txt 不可用时放行
+func (f *fetcher) checkRobots(ctx context.Context, u *nurl.URL, userAgent string) error {
+	robotsURL := &nurl.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		logger().Debugw("fetch robots.txt fail", "url", robotsURL, "err", err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	body, _ := readLimited(resp.Body, 512<<10)
+	if !robotsAllowed(string(body), u.EscapedPath()) {
+		return fmt.Errorf("%w: %s", ErrFetchRobots, u.Path)
+	}
+	return nil
+}
+
+// robotsAllowed 取 User-agent: * 分组中最长匹配的规则，同长度时 Allow 优先
+func robotsAllowed(robots, p string) bool {
+	if len(p) == 0 {
+		p = "/"
+	}
+	var (
+		inGroup, lastUA bool
+		best            = -1
+		allowed         = true
+	)
+	sc := bufio.NewScanner(strings.NewReader(robots))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+		switch key {
+		case "user-agent":
+			if !lastUA {
+				inGroup = false
+			}
+			inGroup = inGroup || val == "*"
+			lastUA = true
+			continue
+		case "allow", "disallow":
+			if inGroup && len(val) > 0 && robotsMatch(val, p) && len(val) >= best {
+				if len(val) > best || key == "allow" {
+					allowed = key == "allow"
+				}
+				best = len(val)
+			}
+		}
+		lastUA = false
+	}
+	return allowed
+}
+
+// robotsMatch 支持 * 通配和 $ 结尾锚定
+func robotsMatch(pattern, p string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(p, parts[0]) {
+		return false
+	}
+	rest := p[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored {
+		return len(rest) == 0 || (len(parts) > 1 && strings.HasSuffix(p, parts[len(parts)-1]))
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	nurl "net/url"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func TestIsBlockedAddr(t *testing.T) {
+	for _, s := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"0.0.0.0", "100.64.0.1", "::1", "fe80::1", "fc00::1", "::ffff:127.0.0.1",
+	} {
+		assert.True(t, isBlockedAddr(netip.MustParseAddr(s)), s)
+	}
+	for _, s := range []string{"8.8.8.8", "1.1.1.1", "2606:4700::1111"} {
+		assert.False(t, isBlockedAddr(netip.MustParseAddr(s)), s)
+	}
+}
+
+func TestFetcherCheckURL(t *testing.T) {
+	f := newFetcher(&fetcher{
+		allow: []string{"example.com", "*.golang.org"},
+		deny:  []string{"bad.example.com"},
+	})
+	for s, ok := range map[string]bool{
+		"https://example.com/a":        true,
+		"https://www.example.com/a":    true,
+		"https://go.golang.org/":       true,
+		"https://bad.example.com/":     false,
+		"https://x.bad.example.com/":   false,
+		"https://notexample.com/":      false,
+		"ftp://example.com/":           false,
+		"file:///etc/passwd":           false,
+		"http://169.254.169.254/":      false,
+		"http://[::1]:6379/":           false,
+		"https://example.com.evil.io/": false,
+	} {
+		u, err := nurl.Parse(s)
+		require.NoError(t, err)
+		assert.Equal(t, ok, f.checkURL(u) == nil, s)
+	}
+}
+
+func TestFetcherBlocksPrivate(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secret"))
+	}))
+	defer ts.Close()
+
+	f := newFetcher(&fetcher{maxRedirects: 3})
+	_, _, _, err := f.get(context.Background(), ts.URL, "test")
+	assert.ErrorIs(t, err, ErrFetchBlockedIP)
+
+	// 以域名访问时在连接阶段拦截
+	_, _, _, err = f.get(context.Background(), strings.Replace(ts.URL, "127.0.0.1", "localhost", 1), "test")
+	assert.ErrorIs(t, err, ErrFetchBlockedIP)
+}
+
+func TestFetcherLimits(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", 100)))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+	mux.HandleFunc("/private/x", func(w http.ResponseWriter, r *http.Request) {})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	f := newFetcher(&fetcher{allowPrivate: true, maxBytes: 10, maxRedirects: 2, robots: true})
+	_, body, truncated, err := f.get(context.Background(), ts.URL+"/big", "test")
+	require.NoError(t, err)
+	assert.True(t, truncated)
+	assert.Len(t, body, 10)
+
+	_, _, _, err = f.get(context.Background(), ts.URL+"/loop", "test")
+	assert.ErrorIs(t, err, ErrFetchRedirects)
+
+	_, _, _, err = f.get(context.Background(), ts.URL+"/private/x", "test")
+	assert.ErrorIs(t, err, ErrFetchRobots)
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	robots := `
+User-agent: googlebot
+Disallow: /
+
+User-agent: other
+User-agent: *
+Disallow: /admin
+Allow: /admin/public
+Disallow: /*.pdf$
+`
+	for p, want := range map[string]bool{
+		"/":                 true,
+		"/admin":            false,
+		"/admin/x":          false,
+		"/admin/public/a":   true,
+		"/docs/a.pdf":       false,
+		"/docs/a.pdf?x=1":   true,
+		"/news/2024/a.html": true,
+	} {
+		assert.Equal(t, want, robotsAllowed(robots, p), p)
+	}
+	assert.True(t, robotsAllowed("", "/a"))
+}
+
+func TestFetchURLCharsetAndCache(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		b, _ := simplifiedchinese.GBK.NewEncoder().Bytes([]byte("中文内容"))
+		w.Header().Set("Content-Type", "text/plain; charset=gbk")
+		_, _ = w.Write(b)
+	}))
+	defer ts.Close()
+
+	mr := miniredis.RunT(t)
+	f := newFetcher(&fetcher{allowPrivate: true, cacheTTL: time.Minute})
+	f.rc = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	for range 2 {
+		content, prefix, err := f.fetchURL(context.Background(), ts.URL, "test", false)
+		require.NoError(t, err)
+		assert.Equal(t, "中文内容", content)
+		assert.Contains(t, prefix, "text/plain")
+	}
+	assert.Equal(t, 1, hits, "second read should hit cache")
+
+	// 域名被禁止后不再返回缓存内容
+	u, _ := nurl.Parse(ts.URL)
+	f.deny = []string{u.Hostname()}
+	_, _, err := f.fetchURL(context.Background(), ts.URL, "test", false)
+	assert.ErrorIs(t, err, ErrFetchDomain)
+	assert.Equal(t, 1, hits)
+}
@@ -1,12 +1,15 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"io"
+	"mime"
 	"net/http"
 	"strings"
-	"time"
 
 	nurl "net/url"
 
@@ -14,6 +17,7 @@ import (
 
 	readeck "codeberg.org/readeck/go-readability/v2"
 	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/utils/doctext"
 )
 
 const (
@@ -49,7 +53,7 @@ func (r *Registry) callFetch(ctx context.Context, args map[string]any) (map[stri
 	}
 
 	// Fetch URL
-	content, prefix, err := fetchURL(ctx, urlStr, DEFAULT_USER_AGENT_AUTONOMOUS, raw)
+	content, prefix, err := r.fetcher.fetchURL(ctx, urlStr, DEFAULT_USER_AGENT_AUTONOMOUS, raw)
 	if err != nil {
 		logger().Infow("fetch", "url", urlStr, "err", err)
 		return mcps.BuildToolSuccessResult(err.Error()), nil
@@ -113,46 +117,110 @@ func extractContentFromHTML(htmlContent, uri string) string {
 }
 
 // fetchURL fetches web page content, supports HTML to Markdown conversion
-func fetchURL(ctx context.Context, urlStr, userAgent string, raw bool) (content, prefix string, err error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+// 转换后的内容按 URL 缓存，分页读取时不再重复下载；
+// 查缓存前先按当前的协议和域名策略检查，已禁止的域名不再返回缓存内容
+func (f *fetcher) fetchURL(ctx context.Context, urlStr, userAgent string, raw bool) (content, prefix string, err error) {
+	u, err := nurl.Parse(urlStr)
 	if err != nil {
 		return
 	}
-	req.Header.Set("User-Agent", userAgent)
-
-	resp, err := client.Do(req)
-	if err != nil {
+	if err = f.checkURL(u); err != nil {
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 400 {
-		err = fmt.Errorf("HTTP %d", resp.StatusCode)
-		return
+	cacheable := f.rc != nil && f.cacheTTL > 0
+	key := fetchCacheKey(urlStr, raw)
+	if cacheable {
+		var fc fetchCached
+		if b, e := f.rc.Get(ctx, key).Bytes(); e == nil && json.Unmarshal(b, &fc) == nil {
+			logger().Debugw("fetch cache hit", "url", urlStr)
+			return fc.Content, fc.Prefix, nil
+		}
+	}
+
+	content, prefix, err = f.fetchContent(ctx, urlStr, userAgent, raw)
+	if err == nil && cacheable {
+		b, _ := json.Marshal(fetchCached{Content: content, Prefix: prefix})
+		if e := f.rc.Set(ctx, key, b, f.cacheTTL).Err(); e != nil {
+			logger().Infow("cache fetched content fail", "url", urlStr, "err", e)
+		}
+	}
+	return
+}
+
+type fetchCached struct {
+	Content string `json:"content"`
+	Prefix  string `json:"prefix"`
+}
+
+func fetchCacheKey(urlStr string, raw bool) string {
+	sum := sha1.Sum([]byte(urlStr))
+	if raw {
+		return "fetch-raw-" + hex.EncodeToString(sum[:])
 	}
+	return "fetch-" + hex.EncodeToString(sum[:])
+}
+
+const mimeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
 
-	b, err := io.ReadAll(resp.Body)
+// fetchContent 下载并按内容类型转换为文本
+func (f *fetcher) fetchContent(ctx context.Context, urlStr, userAgent string, raw bool) (content, prefix string, err error) {
+	resp, b, truncated, err := f.get(ctx, urlStr, userAgent)
 	if err != nil {
 		return
 	}
-	content = string(b)
 
-	contentType := resp.Header.Get("content-type")
-	pagePreview := content
-	if len(pagePreview) > 100 {
-		pagePreview = pagePreview[:100]
+	if resp.StatusCode >= 400 {
+		err = fmt.Errorf("HTTP %d", resp.StatusCode)
+		return
 	}
-	isHTML := strings.Contains(contentType, "text/html") || strings.Contains(pagePreview, "<html")
 
-	if isHTML && !raw {
-		content = extractContentFromHTML(content, urlStr)
+	contentType := resp.Header.Get("content-type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if len(mediaType) == 0 || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(b))
+	}
+	pagePreview := b[:min(len(b), 100)]
+	isHTML := mediaType == "text/html" || mediaType == "application/xhtml+xml" ||
+		bytes.Contains(pagePreview, []byte("<html"))
+
+	switch {
+	case mediaType == "application/pdf" || mediaType == mimeDOCX:
+		// 文档被截断后无法解析
+		if truncated {
+			err = fmt.Errorf("document exceeds %d bytes", f.maxBytes)
+			return
+		}
+		if mediaType == mimeDOCX {
+			content, err = doctext.DOCX(b)
+		} else {
+			content, err = doctext.PDF(b)
+		}
+		prefix = fmt.Sprintf("Text extracted from %s", mediaType)
+	case isHTML && !raw:
+		content = extractContentFromHTML(doctext.Decode(b, contentType), urlStr)
 		prefix = "Markdown"
-	} else {
+	case raw || isTextual(mediaType):
+		content = doctext.Decode(b, contentType)
 		prefix = fmt.Sprintf("Content type %s, raw content:", contentType)
+	default:
+		err = fmt.Errorf("unsupported content type %s", mediaType)
+		return
+	}
+	if truncated {
+		prefix += fmt.Sprintf(" (body truncated at %d bytes)", f.maxBytes)
 	}
 	return
 }
+
+// isTextual 是否为可直接阅读的文本类型
+func isTextual(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript", "application/x-yaml", "application/yaml":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/liut/morign/pkg/models/aigc"
+	"github.com/liut/morign/pkg/services/stores"
+)
+
+// ToolScope 单次请求的工具范围
+type ToolScope struct {
+	// 来源渠道，为空表示 Web
+	Channel string
+	// 请求选择的 MCP Server，为空表示不限
+	Servers []string
+	// 会话固定的工具（支持通配），为空表示不限
+	Pinned []string
+
+	// 本次请求中 tool_search 发现的工具
+	found *foundTools
+}
+
+type ctxToolScopeKey struct{}
+
+// ContextWithToolScope 在 context 中设置工具范围，同时设置来源渠道
+func ContextWithToolScope(ctx context.Context, scope ToolScope) context.Context {
+	if scope.found == nil {
+		scope.found = new(foundTools)
+	}
+	ctx = stores.ContextWithChannel(ctx, scope.Channel)
+	return context.WithValue(ctx, ctxToolScopeKey{}, scope)
+}
+
+// ToolScopeFromContext 从 context 获取工具范围
+func ToolScopeFromContext(ctx context.Context) (ToolScope, bool) {
+	scope, ok := ctx.Value(ctxToolScopeKey{}).(ToolScope)
+	return scope, ok
+}
+
+// WithToolPolicy 设置 preset 中的工具策略
+func WithToolPolicy(policy aigc.ToolPolicy) RegistryOption {
+	return func(r *Registry) {
+		r.policy = policy
+	}
+}
+
+// matchTool 工具名是否匹配任一模式，忽略大小写
+func matchTool(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rulesFor 返回当前调用者适用的规则
+func rulesFor(ctx context.Context, policy aigc.ToolPolicy, channel string) []aigc.ToolRule {
+	rules := []aigc.ToolRule{policy.Default}
+	if rule, ok := policy.Channels[channel]; ok && len(channel) > 0 {
+		rules = append(rules, rule)
+	}
+	if user, ok := stores.UserFromContext(ctx); ok {
+		for _, role := range user.Roles {
+			if rule, ok := policy.Roles[role]; ok {
+				rules = append(rules, rule)
+			}
+		}
+		if rule, ok := policy.Users[user.UID]; ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// permitByRules 任一规则拒绝即拒绝；否则有 allow 匹配或均无 allow 时允许
+func permitByRules(rules []aigc.ToolRule, name string) bool {
+	var hasAllow, allowed bool
+	for _, rule := range rules {
+		if matchTool(rule.Deny, name) {
+			return false
+		}
+		if len(rule.Allow) > 0 {
+			hasAllow = true
+			allowed = allowed || matchTool(rule.Allow, name)
+		}
+	}
+	return allowed || !hasAllow
+}
+
+// toolPermitter 返回判断工具是否可用的函数，ToolsFor 和 Invoke 共用
+func (r *Registry) toolPermitter(ctx context.Context) func(name string) bool {
+	scope, _ := ToolScopeFromContext(ctx)
+	rules := rulesFor(ctx, r.policy, scope.Channel)
+
+	// 请求限定了 MCP Server 时，其他 Server 的工具不可用，内置工具不受影响
+	var excluded []string
+	if len(scope.Servers) > 0 {
+		r.serversMu.RLock()
+		for name, conn := range r.servers {
+			if !slices.ContainsFunc(scope.Servers, func(s string) bool { return strings.EqualFold(s, name) }) {
+				excluded = append(excluded, conn.toolNames...)
+			}
+		}
+		r.serversMu.RUnlock()
+	}
+
+	return func(name string) bool {
+		if slices.Contains(excluded, name) {
+			return false
+		}
+		if len(scope.Pinned) > 0 && !matchTool(scope.Pinned, name) {
+			return false
+		}
+		return permitByRules(rules, name)
+	}
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/liut/morign/pkg/models/aigc"
+	"github.com/liut/morign/pkg/models/mcps"
+)
+
+func TestPermitByRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []aigc.ToolRule
+		tool  string
+		want  bool
+	}{
+		{"no rules", nil, "fetch", true},
+		{"empty rule", []aigc.ToolRule{{}}, "fetch", true},
+		{"allow match", []aigc.ToolRule{{Allow: []string{"kb_*"}}}, "kb_search", true},
+		{"allow miss", []aigc.ToolRule{{Allow: []string{"kb_*"}}}, "fetch", false},
+		{"deny wins", []aigc.ToolRule{{Allow: []string{"*"}}, {Deny: []string{"fetch"}}}, "fetch", false},
+		{"allow union", []aigc.ToolRule{{Allow: []string{"kb_*"}}, {Allow: []string{"fetch"}}}, "fetch", true},
+		{"case insensitive", []aigc.ToolRule{{Deny: []string{"GitHub-*"}}}, "github-search", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, permitByRules(tt.rules, tt.tool))
+		})
+	}
+}
+
+func toolNames(tds []mcps.ToolDescriptor) []string {
+	names := make([]string, len(tds))
+	for i, td := range tds {
+		names[i] = td.Name
+	}
+	return names
+}
+
+func TestRegistryToolsForScope(t *testing.T) {
+	r := &Registry{
+		tools: []mcps.ToolDescriptor{
+			{Name: ToolNameKBSearch}, {Name: ToolNameFetch},
+			{Name: "gh-issues"}, {Name: "jira-search"},
+		},
+		invokers: map[string]Invoker{
+			ToolNameFetch: func(ctx context.Context, _ map[string]any) (map[string]any, error) {
+				return mcps.BuildToolSuccessResult(nil), nil
+			},
+		},
+		privTools: []mcps.ToolDescriptor{{Name: ToolNameKBCreate}},
+		servers: map[string]*MCPConnection{
+			"gh":   {Name: "gh", toolNames: []string{"gh-issues"}},
+			"jira": {Name: "jira", toolNames: []string{"jira-search"}},
+		},
+		policy: aigc.ToolPolicy{
+			Channels: map[string]aigc.ToolRule{"wecom": {Deny: []string{"fetch"}}},
+		},
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, []string{ToolNameKBSearch, ToolNameFetch, "gh-issues", "jira-search"}, toolNames(r.ToolsFor(ctx)))
+
+	sctx := ContextWithToolScope(ctx, ToolScope{Servers: []string{"gh"}})
+	assert.Equal(t, []string{ToolNameKBSearch, ToolNameFetch, "gh-issues"}, toolNames(r.ToolsFor(sctx)))
+
+	sctx = ContextWithToolScope(ctx, ToolScope{Pinned: []string{"kb_*", "jira-*"}})
+	assert.Equal(t, []string{ToolNameKBSearch, "jira-search"}, toolNames(r.ToolsFor(sctx)))
+
+	sctx = ContextWithToolScope(ctx, ToolScope{Channel: "wecom"})
+	assert.NotContains(t, toolNames(r.ToolsFor(sctx)), ToolNameFetch)
+
+	// Invoke 与 ToolsFor 一致
+	res, err := r.Invoke(sctx, ToolNameFetch, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, true, res["isError"])
+	res, err = r.Invoke(ctx, ToolNameFetch, nil)
+	assert.NoError(t, err)
+	assert.NotEqual(t, true, res["isError"])
+}
@@ -2,15 +2,21 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/cupogo/andvari/models/comm"
 	"github.com/mark3labs/mcp-go/client"
 	"github.com/mark3labs/mcp-go/client/transport"
 	mcp "github.com/mark3labs/mcp-go/mcp"
 
+	"github.com/liut/morign/pkg/models/aigc"
+	"github.com/liut/morign/pkg/models/convo"
 	"github.com/liut/morign/pkg/models/mcps"
 	"github.com/liut/morign/pkg/services/stores"
 	"github.com/liut/morign/pkg/settings"
@@ -29,8 +35,27 @@ type Registry struct {
 	clientInfo mcp.Implementation // MCP 客户端信息
 	headerFunc HeaderFunc
 
+	// 工具可见策略
+	policy aigc.ToolPolicy
+
+	sto stores.Storage
+	// 保存被截断的完整工具结果
+	rc stores.RedisClient
+
+	fetcher *fetcher
+	auditor *auditor
+	// 工具结果缓存规则
+	cacheRules map[string]aigc.ToolCacheRule
+	// 缓存键的附加范围，如 kb_search 按可见集合区分
+	cacheScopes map[string]func(context.Context) (string, error)
+	// 在缓存之后按会话加工结果，如 kb_search 分配本轮的引用序号
+	finishers map[string]func(context.Context, map[string]any) map[string]any
+	// 工具向量已同步，可按语义检索
+	vectorsReady atomic.Bool
+
 	// MCP Servers 连接容器（name -> connection）
-	servers   map[string]*MCPConnection
+	servers map[string]*MCPConnection
+	// 保护 servers 以及 tools、invokers，后台加载 Server 时与请求并发
 	serversMu sync.RWMutex
 }
 
@@ -56,13 +81,31 @@ func NewRegistry(sto stores.Storage, opts ...RegistryOption) *Registry {
 		tools:    make([]mcps.ToolDescriptor, 0),
 		invokers: make(map[string]Invoker),
 		servers:  make(map[string]*MCPConnection),
+		sto:      sto,
+		fetcher:  newFetcherFromSettings(),
+
+		cacheScopes: make(map[string]func(context.Context) (string, error)),
+		finishers:   make(map[string]func(context.Context, map[string]any) map[string]any),
 	}
 	r.initTools(sto)
+	if sto != nil && settings.Current.ToolAudit {
+		r.auditor = newAuditor(settings.Current.ToolAuditQueue, func(ctx context.Context, in convo.ToolCallBasic) error {
+			_, err := sto.Convo().CreateToolCall(ctx, in)
+			return err
+		})
+	}
 
 	for _, opt := range opts {
 		opt(r)
 	}
 
+	// 公开工具：ResultPage，需要保存完整结果
+	if r.rc != nil {
+		r.fetcher.rc = r.rc
+		r.tools = append(r.tools, resultPageDescriptor)
+		r.invokers[ToolNameResultPage] = r.callResultPage
+	}
+
 	return r
 }
 
@@ -77,6 +120,7 @@ func (r *Registry) AddInvoker(name string, fn Invoker, desc string, inputSchema
 		return err
 	}
 
+	r.serversMu.Lock()
 	// 注册 invoker
 	r.invokers[name] = fn
 
@@ -86,6 +130,7 @@ func (r *Registry) AddInvoker(name string, fn Invoker, desc string, inputSchema
 		Description: desc,
 		InputSchema: inputSchema,
 	})
+	r.serversMu.Unlock()
 
 	logger().Infow("custom invoker added", "name", name)
 	return nil
@@ -98,12 +143,70 @@ func (r *Registry) Invoke(ctx context.Context, name string, params map[string]an
 	}
 
 	logger().Debugw("invoking", "toolName", name, "params", params)
-	for key, invoker := range r.invokers {
-		if strings.EqualFold(key, name) {
-			return invoker(ctx, params)
+	var key string
+	var invoker Invoker
+	r.serversMu.RLock()
+	for k, fn := range r.invokers {
+		if strings.EqualFold(k, name) {
+			key, invoker = k, fn
+			break
+		}
+	}
+	r.serversMu.RUnlock()
+
+	if invoker == nil {
+		return mcps.BuildToolErrorResult("tool not found"), nil
+	}
+	if !r.isPermitted(ctx, key) {
+		logger().Infow("tool not permitted", "toolName", key)
+		result := mcps.BuildToolErrorResult("tool not available: " + key)
+		r.audit(ctx, key, params, result, nil, 0)
+		return result, nil
+	}
+
+	// 按 InputSchema 校验并转换参数，无效调用直接返回明确的错误给模型
+	if schema := r.inputSchema(key); schema != nil {
+		args, err := mcps.ValidateArgs(schema, params)
+		if err != nil {
+			logger().Infow("invalid tool arguments", "toolName", key, "err", err)
+			result := mcps.BuildToolErrorResult(key + ": " + err.Error() + ". Fix the arguments according to the tool schema and call again.")
+			r.audit(ctx, key, params, result, nil, 0)
+			return result, nil
 		}
+		params = args
 	}
-	return mcps.BuildToolErrorResult("tool not found"), nil
+
+	start := time.Now()
+	result, err := r.cachedInvoke(ctx, key, invoker, params)
+	if fn, ok := r.finishers[key]; ok && err == nil && result != nil {
+		result = fn(ctx, result)
+	}
+	r.audit(ctx, key, params, result, err, time.Since(start))
+	return result, err
+}
+
+// inputSchema 返回工具的参数 schema
+func (r *Registry) inputSchema(name string) map[string]any {
+	r.serversMu.RLock()
+	defer r.serversMu.RUnlock()
+	for _, list := range [][]mcps.ToolDescriptor{r.tools, r.privTools} {
+		for _, td := range list {
+			if td.Name == name {
+				return td.InputSchema
+			}
+		}
+	}
+	return nil
+}
+
+// isPermitted 与 ToolsFor 一致的校验，确保模型只能调用提供给它的工具
+func (r *Registry) isPermitted(ctx context.Context, name string) bool {
+	if !stores.IsKeeper(ctx) && slices.ContainsFunc(r.privTools, func(td mcps.ToolDescriptor) bool {
+		return td.Name == name
+	}) {
+		return false
+	}
+	return r.toolPermitter(ctx)(name)
 }
 
 func (r *Registry) initTools(sto stores.Storage) {
@@ -112,6 +215,8 @@ func (r *Registry) initTools(sto stores.Storage) {
 		// 公开工具：KBSearch
 		r.tools = append(r.tools, kbSearchDescriptor)
 		r.invokers[ToolNameKBSearch] = sto.Corpus().InvokerForSearch()
+		r.cacheScopes[ToolNameKBSearch] = sto.Corpus().CollectionScope
+		r.finishers[ToolNameKBSearch] = stores.CiteSearchResult
 
 		// 受限工具：KBCreate (需要 keeper 角色)
 		r.privTools = append(r.privTools, kbCreateDescriptor)
@@ -139,6 +244,14 @@ func (r *Registry) initTools(sto stores.Storage) {
 	r.tools = append(r.tools, fetchDescriptor)
 	r.invokers[ToolNameFetch] = r.callFetch
 
+	// 公开工具：CodeRun
+	r.tools = append(r.tools, codeRunDescriptor)
+	r.invokers[ToolNameCodeRun] = r.callCodeRun
+
+	// 公开工具：ToolSearch，仅在启用工具检索时提供给模型
+	r.tools = append(r.tools, toolSearchDescriptor)
+	r.invokers[ToolNameToolSearch] = r.callToolSearch
+
 	logger().Debugw("init tools", "tools", mcps.ToolNames(r.tools), "priv", len(r.privTools))
 }
 
@@ -165,15 +278,25 @@ func (r *Registry) ApplyToolDescriptions(descriptions map[string]string) {
 }
 
 // ToolsFor 返回适合当前上下文的工具列表
-// 如果用户有 keeper 角色，返回所有工具；否则只返回公开工具
+// 受限工具仅对 keeper 角色可见，再按工具策略和请求范围过滤
 func (r *Registry) ToolsFor(ctx context.Context) []mcps.ToolDescriptor {
-	// TODO: 工具过滤器
+	permit := r.toolPermitter(ctx)
 
+	r.serversMu.RLock()
+	candidates := slices.Clone(r.tools)
 	if stores.IsKeeper(ctx) {
 		// 合并公开工具和受限工具
-		return append(r.tools, r.privTools...)
+		candidates = append(candidates, r.privTools...)
 	}
-	return r.tools
+	r.serversMu.RUnlock()
+
+	out := make([]mcps.ToolDescriptor, 0, len(candidates))
+	for _, td := range candidates {
+		if permit(td.Name) {
+			out = append(out, td)
+		}
+	}
+	return out
 }
 
 // convertInputSchema 将 ToolInputSchema 转换为 map[string]any
@@ -256,12 +379,20 @@ func (r *Registry) AddServer(ctx context.Context, server *mcps.Server) error {
 		return fmt.Errorf("failed to create transport: %w", err)
 	}
 
-	// 创建并启动 client
+	// 创建并启动 client，SSE 长连接随 client 关闭，不随调用方 ctx 结束
 	c := client.NewClient(tp)
-	if err := c.Start(ctx); err != nil {
+	if err := c.Start(context.WithoutCancel(ctx)); err != nil {
 		return fmt.Errorf("failed to start MCP client: %w", err)
 	}
 
+	// 握手和列出工具限时，避免慢 Server 长时间占用
+	timeout := settings.Current.MCPConnectTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
 	logger().Debugw("MCP initializing", "name", server.Name, "uri", server.URL, "type", server.TransType)
 	// 初始化 MCP 协议
 	if _, err := c.Initialize(ctx, mcp.InitializeRequest{
@@ -289,8 +420,20 @@ func (r *Registry) AddServer(ctx context.Context, server *mcps.Server) error {
 		}
 	}
 
-	// 注册工具
+	// 注册工具，连接期间可能有其他 Server 完成注册，加锁后再次检查冲突
 	r.serversMu.Lock()
+	if err := r.conflictLocked(server.Name); err != nil {
+		r.serversMu.Unlock()
+		_ = c.Close()
+		return err
+	}
+	for _, tool := range result.Tools {
+		if err := r.conflictLocked(tool.Name); err != nil {
+			r.serversMu.Unlock()
+			_ = c.Close()
+			return err
+		}
+	}
 	mcpc := &MCPConnection{
 		Name:      server.Name,
 		URL:       server.URL,
@@ -310,6 +453,9 @@ func (r *Registry) AddServer(ctx context.Context, server *mcps.Server) error {
 			return r.callServerTool(ctx, server.Name, tool.Name, params)
 		}
 		toolNames = append(toolNames, toolKey)
+		if !isReadOnly(tool) {
+			mcpc.writeTools = append(mcpc.writeTools, toolKey)
+		}
 		logger().Infow("MCP tool registered", "server", server.Name, "tool", tool.Name)
 	}
 	mcpc.toolNames = toolNames
@@ -322,9 +468,16 @@ func (r *Registry) AddServer(ctx context.Context, server *mcps.Server) error {
 
 // checkToolNameConflict 检查工具名是否冲突
 func (r *Registry) checkToolNameConflict(name string) error {
+	r.serversMu.RLock()
+	defer r.serversMu.RUnlock()
+	return r.conflictLocked(name)
+}
+
+// conflictLocked 同 checkToolNameConflict，调用方须持有 serversMu
+func (r *Registry) conflictLocked(name string) error {
 	// 检查是否与内置工具冲突
 	switch name {
-	case ToolNameKBSearch, ToolNameKBCreate, ToolNameFetch,
+	case ToolNameKBSearch, ToolNameKBCreate, ToolNameFetch, ToolNameCodeRun, ToolNameToolSearch, ToolNameResultPage,
 		ToolNameMemoryList, ToolNameMemoryRecall, ToolNameMemoryStore, ToolNameMemoryForget:
 		return fmt.Errorf("tool name %q conflicts with built-in tool", name)
 	}
@@ -342,14 +495,9 @@ func (r *Registry) checkToolNameConflict(name string) error {
 	}
 
 	// 检查是否与已注册的 server 冲突
-	r.serversMu.RLock()
-	for _, s := range r.servers {
-		if s.Name == name {
-			r.serversMu.RUnlock()
-			return fmt.Errorf("server %q already exists", name)
-		}
+	if _, ok := r.servers[name]; ok {
+		return fmt.Errorf("server %q already exists", name)
 	}
-	r.serversMu.RUnlock()
 
 	return nil
 }
@@ -384,36 +532,86 @@ func (r *Registry) callServerTool(ctx context.Context, serverName, toolName stri
 	return convertMCPToolResult(result), nil
 }
 
-// LoadServers 加载所有 Running 状态的 MCP Server
+// LoadServers 分页加载所有激活的 MCP Server，并发连接，数量由 MCPLoadWorkers 限制
+// 每个 Server 的连接结果写回状态列，失败原因记在 meta 的 lastError，全部失败合并返回
 func (r *Registry) LoadServers(ctx context.Context, sto stores.Storage) error {
 	if sto == nil {
 		logger().Warnw("no storage configured, skipping MCP server load")
 		return nil
 	}
 
+	var servers mcps.Servers
 	spec := &stores.MCPServerSpec{
 		IsActive: "true",
 	}
-	spec.Limit = 2
+	spec.Limit = 20
 	spec.Sort = "created DESC"
-	servers, _, err := sto.MCP().ListServer(ctx, spec)
-	if err != nil {
-		return fmt.Errorf("failed to list MCP servers: %w", err)
+	for spec.Page = 1; ; spec.Page++ {
+		data, total, err := sto.MCP().ListServer(ctx, spec)
+		if err != nil {
+			return fmt.Errorf("failed to list MCP servers: %w", err)
+		}
+		servers = append(servers, data...)
+		if len(data) < spec.Limit || len(servers) >= total {
+			break
+		}
 	}
 
-	for _, server := range servers {
-		if !server.TransType.IsRemote() {
-			logger().Infow("skipping non-remote MCP server", "name", server.Name, "type", server.TransType)
+	workers := max(settings.Current.MCPLoadWorkers, 1)
+	jobs := make(chan *mcps.Server)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for range workers {
+		wg.Go(func() {
+			for server := range jobs {
+				if err := r.loadServer(ctx, sto, server); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("%s: %w", server.Name, err))
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	for i := range servers {
+		if !servers[i].TransType.IsRemote() {
+			logger().Infow("skipping non-remote MCP server", "name", servers[i].Name, "type", servers[i].TransType)
 			continue
 		}
-		if err := r.AddServer(ctx, &server); err != nil {
-			logger().Warnw("failed to load MCP server", "name", server.Name, "err", err)
-			continue
+		jobs <- &servers[i]
+	}
+	close(jobs)
+	wg.Wait()
+
+	logger().Infow("MCP servers loaded", "count", len(servers), "failed", len(errs))
+	return errors.Join(errs...)
+}
+
+// loadServer 连接单个 Server 并更新其状态
+func (r *Registry) loadServer(ctx context.Context, sto stores.Storage, server *mcps.Server) error {
+	id := server.StringID()
+	setStatus := func(status mcps.Status, err error) {
+		in := mcps.ServerSet{Status: &status}
+		if err != nil {
+			in.MetaAddKVs("lastError", err.Error())
+		} else {
+			in.MetaDiff = &comm.MetaDiff{Delete: []string{"lastError"}}
+		}
+		if uerr := sto.MCP().UpdateServer(ctx, id, in); uerr != nil {
+			logger().Infow("update MCP server status fail", "name", server.Name, "err", uerr)
 		}
-		logger().Infow("loaded MCP server", "name", server.Name)
 	}
 
-	logger().Info("MCP servers loaded", "count", len(servers))
+	setStatus(mcps.StatusConnecting, nil)
+	if err := r.AddServer(ctx, server); err != nil {
+		logger().Warnw("failed to load MCP server", "name", server.Name, "err", err)
+		setStatus(mcps.StatusDisconnected, err)
+		return err
+	}
+	setStatus(mcps.StatusConnected, nil)
+	logger().Infow("loaded MCP server", "name", server.Name)
 	return nil
 }
 
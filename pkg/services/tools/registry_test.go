@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	mcp "github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/models/mcps"
+)
+
+func newTestMCPServer(t *testing.T, name string) string {
+	t.Helper()
+	ms := server.NewMCPServer(name, "0.1")
+	ms.AddTool(mcp.NewTool("echo"), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText(name), nil
+	})
+	ts := server.NewTestStreamableHTTPServer(ms)
+	t.Cleanup(ts.Close)
+	return ts.URL + "/mcp"
+}
+
+func TestRegistryAddServerConcurrent(t *testing.T) {
+	r := &Registry{
+		invokers: make(map[string]Invoker),
+		servers:  make(map[string]*MCPConnection),
+	}
+
+	const n = 4
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		name := fmt.Sprintf("s%d", i)
+		url := newTestMCPServer(t, name)
+		wg.Go(func() {
+			errs[i] = r.AddServer(context.Background(), &mcps.Server{ServerBasic: mcps.ServerBasic{
+				Name: name, URL: url, TransType: mcps.TransTypeStreamable,
+			}})
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	assert.Len(t, r.ToolsFor(context.Background()), n)
+
+	res, err := r.Invoke(context.Background(), "s2-echo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "s2", formatText(res))
+
+	// 重复名称
+	err = r.AddServer(context.Background(), &mcps.Server{ServerBasic: mcps.ServerBasic{
+		Name: "s1", URL: newTestMCPServer(t, "s1"), TransType: mcps.TransTypeStreamable,
+	}})
+	assert.Error(t, err)
+}
+
+func formatText(res map[string]any) string {
+	if sc, ok := res["structuredContent"].(map[string]any); ok {
+		s, _ := sc["text"].(string)
+		return s
+	}
+	return ""
+}
+
+func TestRegistryInvokeValidatesArgs(t *testing.T) {
+	r := NewRegistry(nil)
+	var got map[string]any
+	require.NoError(t, r.AddInvoker("lookup", func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		got = args
+		return mcps.BuildToolSuccessResult("ok"), nil
+	}, "Lookup items", map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{"type": "string"},
+			"limit": map[string]any{"type": "integer"},
+		},
+		"required": []string{"query"},
+	}))
+
+	result, err := r.Invoke(context.Background(), "lookup", map[string]any{"query": "go", "limit": "5"})
+	require.NoError(t, err)
+	assert.Nil(t, result["isError"])
+	assert.Equal(t, 5.0, got["limit"], "should coerce string to number")
+
+	got = nil
+	result, err = r.Invoke(context.Background(), "lookup", map[string]any{"limit": "many"})
+	require.NoError(t, err)
+	assert.Equal(t, true, result["isError"])
+	assert.Nil(t, got, "invoker should not be called")
+	text := result["content"].([]map[string]any)[0]["text"].(string)
+	assert.Contains(t, text, "query: is required")
+	assert.Contains(t, text, `limit: expected integer, got string "many"`)
+}
@@ -0,0 +1,173 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/cupogo/andvari/models/oid"
+
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/services/stores"
+	"github.com/liut/morign/pkg/settings"
+	"github.com/liut/morign/pkg/utils/words"
+)
+
+// WithResultStore 设置 Redis，用于保存完整工具结果（启用 tool_result_page）、缓存抓取内容和工具结果
+func WithResultStore(rc stores.RedisClient) RegistryOption {
+	return func(r *Registry) {
+		r.rc = rc
+	}
+}
+
+// resultBudget 返回工具结果的 token 预算，先按工具名精确匹配，再按通配模式，最后为全局预算
+func resultBudget(name string) int {
+	budgets := settings.Current.ToolResultBudgets
+	if n, ok := budgets[name]; ok {
+		return n
+	}
+	for p, n := range budgets {
+		if ok, _ := path.Match(p, name); ok {
+			return n
+		}
+	}
+	return settings.Current.ToolResultBudget
+}
+
+func resultKey(ctx context.Context, id string) string {
+	return "toolres-" + stores.ConvoIDFromContext(ctx) + "-" + id
+}
+
+// FitResult 将超出预算的工具结果截断，JSON 保留结构，文本保留首尾
+// 完整结果按页保存，模型可通过 tool_result_page 读取
+func (r *Registry) FitResult(ctx context.Context, name, text string) string {
+	budget := resultBudget(name)
+	if budget <= 0 || name == ToolNameResultPage {
+		return text
+	}
+	total := words.EstimateTokens(text)
+	if total <= budget {
+		return text
+	}
+
+	// 预留提示信息的空间
+	view := shrinkResult(text, budget*9/10)
+	note := fmt.Sprintf("[Result truncated: about %d tokens in total.]", total)
+	if id, pages, err := r.saveResult(ctx, text, budget); err == nil {
+		note = fmt.Sprintf("[Result truncated: about %d tokens in total. Call %s with id %q and page 1-%d to read the full result.]",
+			total, ToolNameResultPage, id, pages)
+	} else if r.rc != nil {
+		logger().Infow("save tool result fail", "tool", name, "err", err)
+	}
+	logger().Infow("tool result truncated", "tool", name, "tokens", total, "budget", budget)
+	return view + "\n\n" + note
+}
+
+func (r *Registry) saveResult(ctx context.Context, text string, budget int) (id string, pages int, err error) {
+	if r.rc == nil {
+		return "", 0, fmt.Errorf("result store not configured")
+	}
+	parts := words.SplitByTokens(text, budget)
+	values := make([]any, len(parts))
+	for i := range parts {
+		values[i] = parts[i]
+	}
+	id = oid.NewID(oid.OtEvent).String()
+	key := resultKey(ctx, id)
+	pipe := r.rc.TxPipeline()
+	pipe.RPush(ctx, key, values...)
+	pipe.Expire(ctx, key, settings.Current.ToolResultTTL)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return "", 0, err
+	}
+	return id, len(parts), nil
+}
+
+// callResultPage 读取被截断的完整工具结果
+func (r *Registry) callResultPage(ctx context.Context, args map[string]any) (map[string]any, error) {
+	id := mcps.StringArg(args, "id")
+	if id == "" {
+		return mcps.BuildToolErrorResult("missing required argument: id"), nil
+	}
+	page, _, _ := mcps.IntArg(args, "page")
+	if page <= 0 {
+		page = 1
+	}
+	key := resultKey(ctx, id)
+	pages, err := r.rc.LLen(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if pages == 0 {
+		return mcps.BuildToolErrorResult("result not found or expired: " + id), nil
+	}
+	if int64(page) > pages {
+		return mcps.BuildToolErrorResult(fmt.Sprintf("page out of range, total %d pages", pages)), nil
+	}
+	text, err := r.rc.LIndex(ctx, key, int64(page-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	return mcps.BuildToolSuccessResult(fmt.Sprintf("[page %d of %d]\n%s", page, pages, text)), nil
+}
+
+// shrinkResult 截断到预算以内，可解析为 JSON 时按结构截断
+func shrinkResult(text string, budget int) string {
+	var v any
+	if trimmed := strings.TrimSpace(text); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') &&
+		json.Unmarshal([]byte(trimmed), &v) == nil {
+		// 逐级收紧数组样本数和字符串长度，直到满足预算
+		for _, lv := range []struct{ items, chars int }{{20, 1000}, {10, 400}, {5, 200}, {3, 100}, {1, 50}} {
+			b, err := json.Marshal(shrinkJSON(v, lv.items, lv.chars))
+			if err != nil {
+				break
+			}
+			if words.EstimateTokens(string(b)) <= budget {
+				return string(b)
+			}
+			text = string(b)
+		}
+	}
+	return headTail(text, budget)
+}
+
+// shrinkJSON 数组只保留前 items 个元素并注明总数，字符串保留前 chars 个字符，保留所有键
+func shrinkJSON(v any, items, chars int) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, e := range val {
+			out[k] = shrinkJSON(e, items, chars)
+		}
+		return out
+	case []any:
+		n := min(len(val), items)
+		out := make([]any, 0, n+1)
+		for _, e := range val[:n] {
+			out = append(out, shrinkJSON(e, items, chars))
+		}
+		if len(val) > n {
+			out = append(out, fmt.Sprintf("... %d more items, %d in total", len(val)-n, len(val)))
+		}
+		return out
+	case string:
+		if n := len([]rune(val)); n > chars {
+			return words.TakeHead(val, chars, fmt.Sprintf("...(%d chars)", n))
+		}
+	}
+	return v
+}
+
+// headTail 保留开头约 2/3 和结尾约 1/3，中间以省略说明替代
+func headTail(text string, budget int) string {
+	parts := words.SplitByTokens(text, max(budget/3, 1))
+	if len(parts) <= 3 {
+		return text
+	}
+	head := parts[0] + parts[1]
+	tail := parts[len(parts)-1]
+	omitted := words.EstimateTokens(text) - words.EstimateTokens(head) - words.EstimateTokens(tail)
+	return fmt.Sprintf("%s\n...[about %d tokens omitted]...\n%s", head, omitted, tail)
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/settings"
+	"github.com/liut/morign/pkg/utils/words"
+)
+
+func TestShrinkResultJSON(t *testing.T) {
+	items := make([]any, 500)
+	for i := range items {
+		items[i] = map[string]any{"id": i, "name": fmt.Sprintf("item-%d", i), "note": strings.Repeat("x", 300)}
+	}
+	b, _ := json.Marshal(map[string]any{"total": 500, "data": items})
+
+	out := shrinkResult(string(b), 500)
+	assert.LessOrEqual(t, words.EstimateTokens(out), 500)
+
+	var v map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &v), "should stay valid json")
+	assert.EqualValues(t, 500, v["total"])
+	data := v["data"].([]any)
+	require.NotEmpty(t, data)
+	assert.Contains(t, data[0], "name", "should keep keys of items")
+	assert.Contains(t, data[len(data)-1], "500 in total")
+}
+
+func TestShrinkResultText(t *testing.T) {
+	text := "BEGIN " + strings.Repeat("log line\n", 2000) + " END"
+	out := shrinkResult(text, 300)
+	assert.LessOrEqual(t, words.EstimateTokens(out), 320)
+	assert.True(t, strings.HasPrefix(out, "BEGIN"))
+	assert.True(t, strings.HasSuffix(out, "END"))
+	assert.Contains(t, out, "tokens omitted")
+
+	assert.Equal(t, "short", shrinkResult("short", 300))
+}
+
+func TestFitResultAndPage(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	old := *settings.Current
+	defer func() { *settings.Current = old }()
+	settings.Current.ToolResultBudget = 100
+	settings.Current.ToolResultBudgets = map[string]int{"big-*": 0}
+	settings.Current.ToolResultTTL = time.Minute
+
+	r := NewRegistry(nil, WithResultStore(rc))
+	ctx := ContextWithToolScope(context.Background(), ToolScope{})
+
+	text := strings.Repeat("0123456789", 100)
+	assert.Equal(t, text, r.FitResult(ctx, "big-dump", text), "budget 0 disables truncation")
+	assert.Equal(t, "ok", r.FitResult(ctx, "echo", "ok"))
+
+	out := r.FitResult(ctx, "echo", text)
+	assert.Less(t, len(out), len(text))
+	m := regexp.MustCompile(`id "([^"]+)" and page 1-(\d+)`).FindStringSubmatch(out)
+	require.Len(t, m, 3, out)
+
+	var full strings.Builder
+	for page := 1; page <= 3; page++ {
+		res, err := r.Invoke(ctx, ToolNameResultPage, map[string]any{"id": m[1], "page": page})
+		require.NoError(t, err)
+		require.Nil(t, res["isError"])
+		s := res["content"].([]map[string]any)[0]["text"].(string)
+		_, body, _ := strings.Cut(s, "\n")
+		full.WriteString(body)
+	}
+	assert.Equal(t, "3", m[2])
+	assert.Equal(t, text, full.String())
+
+	res, _ := r.Invoke(ctx, ToolNameResultPage, map[string]any{"id": m[1], "page": 4})
+	assert.Equal(t, true, res["isError"])
+	res, _ = r.Invoke(ctx, ToolNameResultPage, map[string]any{"id": "nope"})
+	assert.Equal(t, true, res["isError"])
+}
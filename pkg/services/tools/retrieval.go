@@ -0,0 +1,206 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/services/stores"
+	"github.com/liut/morign/pkg/settings"
+)
+
+// foundTools 收集单次请求中 tool_search 发现的工具名
+type foundTools struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (f *foundTools) add(names ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range names {
+		if !slices.Contains(f.names, name) {
+			f.names = append(f.names, name)
+		}
+	}
+}
+
+func (f *foundTools) list() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.names)
+}
+
+// SyncToolVectors 为全部工具（含受限工具）生成向量，完成后启用语义检索
+func (r *Registry) SyncToolVectors(ctx context.Context) error {
+	if r.sto == nil {
+		return nil
+	}
+	r.serversMu.RLock()
+	all := slices.Concat(r.tools, r.privTools)
+	r.serversMu.RUnlock()
+
+	all = slices.DeleteFunc(all, func(td mcps.ToolDescriptor) bool { return td.Name == ToolNameToolSearch })
+	if err := r.sto.MCP().SyncToolVectors(ctx, all); err != nil {
+		logger().Infow("sync tool vectors fail", "err", err)
+		return err
+	}
+	r.vectorsReady.Store(true)
+	return nil
+}
+
+// retrievable 是否按语义检索工具
+func (r *Registry) retrievable(count int) bool {
+	limit := settings.Current.ToolRetrieveMin
+	return limit > 0 && count > limit && r.sto != nil && r.vectorsReady.Load()
+}
+
+// SelectTools 返回本轮提供给模型的工具
+// 可用工具不多时全部提供；否则只提供核心工具、与 query 最相关的 ToolTopK 个工具和 tool_search
+func (r *Registry) SelectTools(ctx context.Context, query string) []mcps.ToolDescriptor {
+	all := r.ToolsFor(ctx)
+	if !r.retrievable(len(all)) {
+		return withoutToolSearch(all)
+	}
+
+	matches, err := r.sto.MCP().MatchTools(ctx, stores.MatchSpec{
+		Query:        query,
+		Threshold:    settings.Current.ToolThreshold,
+		Limit:        settings.Current.ToolTopK,
+		SkipKeywords: true,
+	})
+	if err != nil {
+		logger().Infow("match tools fail, offer all", "err", err)
+		return withoutToolSearch(all)
+	}
+
+	keep := []string{ToolNameToolSearch, ToolNameResultPage}
+	for _, m := range matches {
+		keep = append(keep, m.Name)
+	}
+	if scope, ok := ToolScopeFromContext(ctx); ok && scope.found != nil {
+		keep = append(keep, scope.found.list()...)
+	}
+	core := settings.Current.ToolCoreSet
+
+	out := make([]mcps.ToolDescriptor, 0, len(keep)+len(core))
+	for _, td := range all {
+		if slices.Contains(keep, td.Name) || matchTool(core, td.Name) {
+			out = append(out, td)
+		}
+	}
+	logger().Debugw("selected tools", "all", len(all), "tools", mcps.ToolNames(out))
+	return out
+}
+
+// Discovered 返回本次请求中 tool_search 发现且可用的工具
+func (r *Registry) Discovered(ctx context.Context) []mcps.ToolDescriptor {
+	scope, ok := ToolScopeFromContext(ctx)
+	if !ok || scope.found == nil {
+		return nil
+	}
+	names := scope.found.list()
+	if len(names) == 0 {
+		return nil
+	}
+	var out []mcps.ToolDescriptor
+	for _, td := range r.ToolsFor(ctx) {
+		if slices.Contains(names, td.Name) {
+			out = append(out, td)
+		}
+	}
+	return out
+}
+
+func withoutToolSearch(tools []mcps.ToolDescriptor) []mcps.ToolDescriptor {
+	return slices.DeleteFunc(tools, func(td mcps.ToolDescriptor) bool { return td.Name == ToolNameToolSearch })
+}
+
+// callToolSearch 按意图检索可用工具，向量不可用时退化为关键词匹配
+func (r *Registry) callToolSearch(ctx context.Context, args map[string]any) (map[string]any, error) {
+	query := mcps.StringArg(args, "query")
+	if query == "" {
+		return mcps.BuildToolErrorResult("missing required argument: query"), nil
+	}
+	limit, _, _ := mcps.IntArg(args, "limit")
+	if limit <= 0 {
+		limit = 5
+	}
+
+	candidates := withoutToolSearch(r.ToolsFor(ctx))
+	var found []mcps.ToolDescriptor
+	if r.sto != nil && r.vectorsReady.Load() {
+		matches, err := r.sto.MCP().MatchTools(ctx, stores.MatchSpec{
+			Query:        query,
+			Threshold:    settings.Current.ToolThreshold,
+			Limit:        limit * 2, // 部分结果可能不可用
+			SkipKeywords: true,
+		})
+		if err != nil {
+			logger().Infow("match tools fail", "query", query, "err", err)
+		}
+		for _, m := range matches {
+			if i := slices.IndexFunc(candidates, func(td mcps.ToolDescriptor) bool { return td.Name == m.Name }); i >= 0 {
+				found = append(found, candidates[i])
+			}
+		}
+	}
+	if len(found) == 0 {
+		found = searchToolsByKeywords(candidates, query)
+	}
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	if len(found) == 0 {
+		return mcps.BuildToolSuccessResult("No matching tools found"), nil
+	}
+
+	items := make([]map[string]any, 0, len(found))
+	names := make([]string, 0, len(found))
+	for _, td := range found {
+		names = append(names, td.Name)
+		items = append(items, map[string]any{
+			"name":        td.Name,
+			"description": td.Description,
+		})
+	}
+	if scope, ok := ToolScopeFromContext(ctx); ok && scope.found != nil {
+		scope.found.add(names...)
+	}
+	logger().Infow("tool_search", "query", query, "found", names)
+
+	return mcps.BuildToolSuccessResult(map[string]any{
+		"tools": items,
+		"note":  "Found tools are now available to call.",
+	}), nil
+}
+
+// searchToolsByKeywords 按空白分词，命中词数多者在前
+func searchToolsByKeywords(tools []mcps.ToolDescriptor, query string) []mcps.ToolDescriptor {
+	terms := strings.Fields(strings.ToLower(query))
+	type hit struct {
+		td    mcps.ToolDescriptor
+		score int
+	}
+	var hits []hit
+	for _, td := range tools {
+		subject := strings.ToLower(td.GetSubject())
+		var score int
+		for _, t := range terms {
+			if strings.Contains(subject, t) {
+				score++
+			}
+		}
+		if score > 0 {
+			hits = append(hits, hit{td, score})
+		}
+	}
+	slices.SortStableFunc(hits, func(a, b hit) int { return b.score - a.score })
+	out := make([]mcps.ToolDescriptor, len(hits))
+	for i, h := range hits {
+		out[i] = h.td
+	}
+	return out
+}
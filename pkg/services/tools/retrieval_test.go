@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/models/mcps"
+)
+
+func TestToolSearchKeywords(t *testing.T) {
+	r := NewRegistry(nil)
+	require.NoError(t, r.AddInvoker("calendar_create", nil, "Create a calendar event", nil))
+	require.NoError(t, r.AddInvoker("calendar_list", nil, "List calendar events of a day", nil))
+	require.NoError(t, r.AddInvoker("weather", nil, "Weather forecast of a city", nil))
+
+	ctx := ContextWithToolScope(context.Background(), ToolScope{})
+
+	// 未启用检索时不提供 tool_search
+	assert.NotContains(t, toolNames(r.SelectTools(ctx, "event")), ToolNameToolSearch)
+	assert.Empty(t, r.Discovered(ctx))
+
+	res, err := r.Invoke(ctx, ToolNameToolSearch, map[string]any{"query": "create calendar event", "limit": 1})
+	require.NoError(t, err)
+	items := res["structuredContent"].(map[string]any)["tools"].([]map[string]any)
+	require.Len(t, items, 1)
+	assert.Equal(t, "calendar_create", items[0]["name"])
+	assert.Equal(t, []string{"calendar_create"}, toolNames(r.Discovered(ctx)))
+
+	res, err = r.Invoke(ctx, ToolNameToolSearch, map[string]any{"query": "horoscope"})
+	require.NoError(t, err)
+	assert.Nil(t, res["structuredContent"])
+
+	res, _ = r.Invoke(ctx, ToolNameToolSearch, map[string]any{})
+	assert.Equal(t, true, res["isError"])
+}
+
+func TestSearchToolsByKeywords(t *testing.T) {
+	tools := []mcps.ToolDescriptor{
+		{Name: "a", Description: "list issues"},
+		{Name: "b", Description: "create issues with labels"},
+		{Name: "c", Description: "weather"},
+	}
+	assert.Equal(t, []string{"b", "a"}, toolNames(searchToolsByKeywords(tools, "Create Issues")))
+	assert.Empty(t, searchToolsByKeywords(tools, "stock"))
+}
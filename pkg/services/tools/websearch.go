@@ -0,0 +1,230 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	nurl "net/url"
+
+	"github.com/liut/morign/pkg/models/mcps"
+	"github.com/liut/morign/pkg/settings"
+)
+
+// ErrUnknownSearchBackend 不支持的搜索后端
+var ErrUnknownSearchBackend = errors.New("unknown search backend")
+
+// SearchResult 归一化的搜索结果
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// SearchProvider 网页搜索后端
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+}
+
+// NewSearchProvider 按配置创建搜索后端，Backend 为空时返回 nil
+func NewSearchProvider(cfg settings.WebSearch) (SearchProvider, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+	switch strings.ToLower(cfg.Backend) {
+	case "":
+		return nil, nil
+	case "searxng":
+		if len(cfg.URL) == 0 {
+			return nil, fmt.Errorf("searxng: url is required")
+		}
+		endpoint := strings.TrimSuffix(cfg.URL, "/") + "/search"
+		return &jsonSearch{
+			name: "searxng", client: client,
+			build: func(q string, limit int) (*http.Request, error) {
+				return newSearchRequest(endpoint, nurl.Values{"q": {q}, "format": {"json"}})
+			},
+			path: "results", title: "title", url: "url", snippet: "content",
+		}, nil
+	case "bing":
+		endpoint := cfg.URL
+		if len(endpoint) == 0 {
+			endpoint = "https://api.bing.microsoft.com/v7.0/search"
+		}
+		return &jsonSearch{
+			name: "bing", client: client,
+			build: func(q string, limit int) (*http.Request, error) {
+				req, err := newSearchRequest(endpoint, nurl.Values{"q": {q}, "count": {strconv.Itoa(limit)}})
+				if err == nil {
+					req.Header.Set("Ocp-Apim-Subscription-Key", cfg.APIKey)
+				}
+				return req, err
+			},
+			path: "webPages.value", title: "name", url: "url", snippet: "snippet",
+		}, nil
+	case "brave":
+		endpoint := cfg.URL
+		if len(endpoint) == 0 {
+			endpoint = "https://api.search.brave.com/res/v1/web/search"
+		}
+		return &jsonSearch{
+			name: "brave", client: client,
+			build: func(q string, limit int) (*http.Request, error) {
+				req, err := newSearchRequest(endpoint, nurl.Values{"q": {q}, "count": {strconv.Itoa(limit)}})
+				if err == nil {
+					req.Header.Set("X-Subscription-Token", cfg.APIKey)
+				}
+				return req, err
+			},
+			path: "web.results", title: "title", url: "url", snippet: "description",
+		}, nil
+	case "json":
+		if len(cfg.URL) == 0 {
+			return nil, fmt.Errorf("json: url is required")
+		}
+		return &jsonSearch{
+			name: "json", client: client,
+			build: func(q string, limit int) (*http.Request, error) {
+				req, err := newSearchRequest(cfg.URL, nurl.Values{cfg.QueryParam: {q}, "limit": {strconv.Itoa(limit)}})
+				if err == nil && len(cfg.APIKey) > 0 {
+					req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+				}
+				return req, err
+			},
+			path: cfg.ResultsPath, title: cfg.TitleField, url: cfg.URLField, snippet: cfg.SnippetField,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownSearchBackend, cfg.Backend)
+}
+
+func newSearchRequest(endpoint string, params nurl.Values) (*http.Request, error) {
+	u, err := nurl.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// jsonSearch 返回 JSON 的搜索接口，按路径和字段名提取结果
+type jsonSearch struct {
+	name   string
+	client *http.Client
+	build  func(query string, limit int) (*http.Request, error)
+
+	path                string
+	title, url, snippet string
+}
+
+func (s *jsonSearch) Name() string { return s.name }
+
+func (s *jsonSearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	req, err := s.build(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s: HTTP %d", s.name, resp.StatusCode)
+	}
+
+	var body any
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.name, err)
+	}
+	for _, key := range strings.Split(s.path, ".") {
+		if m, ok := body.(map[string]any); ok && len(key) > 0 {
+			body = m[key]
+		}
+	}
+	items, _ := body.([]any)
+
+	out := make([]SearchResult, 0, min(len(items), limit))
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		sr := SearchResult{Title: stringOf(m[s.title]), URL: stringOf(m[s.url]), Snippet: stringOf(m[s.snippet])}
+		if len(sr.URL) == 0 {
+			continue
+		}
+		out = append(out, sr)
+		if len(out) >= limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func stringOf(v any) string {
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return ""
+}
+
+// FakeSearch 固定结果的搜索后端，用于测试，返回标题或摘要包含查询词的结果
+type FakeSearch []SearchResult
+
+func (FakeSearch) Name() string { return "fake" }
+
+func (z FakeSearch) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	query = strings.ToLower(query)
+	var out []SearchResult
+	for _, sr := range z {
+		if strings.Contains(strings.ToLower(sr.Title+" "+sr.Snippet), query) {
+			out = append(out, sr)
+			if len(out) >= limit {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// AddSearchProvider 以 web_search 工具注册搜索后端
+func (r *Registry) AddSearchProvider(sp SearchProvider, limit int) error {
+	if limit <= 0 {
+		limit = 5
+	}
+	invoker := func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		query := mcps.StringArg(args, "query")
+		if query == "" {
+			return mcps.BuildToolErrorResult("missing required argument: query"), nil
+		}
+		n, _, _ := mcps.IntArg(args, "limit")
+		if n <= 0 || n > 20 {
+			n = limit
+		}
+		start := time.Now()
+		data, err := sp.Search(ctx, query, n)
+		if err != nil {
+			logger().Infow("web search fail", "backend", sp.Name(), "query", query, "err", err)
+			return mcps.BuildToolErrorResult("search failed: " + err.Error()), nil
+		}
+		logger().Infow("web search", "backend", sp.Name(), "query", query, "results", len(data), "cost", time.Since(start))
+		if len(data) == 0 {
+			return mcps.BuildToolSuccessResult("No results found"), nil
+		}
+		return mcps.BuildToolSuccessResult(map[string]any{"results": data}), nil
+	}
+	return r.AddInvoker(ToolNameWebSearch, invoker, webSearchDescriptor.Description, webSearchDescriptor.InputSchema)
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/liut/morign/pkg/settings"
+)
+
+func TestSearchProviders(t *testing.T) {
+	responses := map[string]string{
+		"searxng": `{"results":[{"title":"Go","url":"https://go.dev","content":"The Go language"},{"title":"no url"}]}`,
+		"bing":    `{"webPages":{"value":[{"name":"Go","url":"https://go.dev","snippet":"The Go language"}]}}`,
+		"brave":   `{"web":{"results":[{"title":"Go","url":"https://go.dev","description":"The Go language"}]}}`,
+		"json":    `{"data":{"items":[{"name":"Go","link":"https://go.dev","desc":"The Go language"}]}}`,
+	}
+	for backend, body := range responses {
+		t.Run(backend, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "golang", r.URL.Query().Get("q"))
+				switch backend {
+				case "searxng":
+					assert.Equal(t, "/search", r.URL.Path)
+					assert.Equal(t, "json", r.URL.Query().Get("format"))
+				case "bing":
+					assert.Equal(t, "k1", r.Header.Get("Ocp-Apim-Subscription-Key"))
+				case "brave":
+					assert.Equal(t, "k1", r.Header.Get("X-Subscription-Token"))
+				case "json":
+					assert.Equal(t, "Bearer k1", r.Header.Get("Authorization"))
+				}
+				_, _ = w.Write([]byte(body))
+			}))
+			defer ts.Close()
+
+			sp, err := NewSearchProvider(settings.WebSearch{
+				Backend: backend, URL: ts.URL, APIKey: "k1", Timeout: time.Second,
+				QueryParam: "q", ResultsPath: "data.items", TitleField: "name", URLField: "link", SnippetField: "desc",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, backend, sp.Name())
+
+			data, err := sp.Search(context.Background(), "golang", 5)
+			require.NoError(t, err)
+			assert.Equal(t, []SearchResult{{Title: "Go", URL: "https://go.dev", Snippet: "The Go language"}}, data)
+		})
+	}
+
+	sp, err := NewSearchProvider(settings.WebSearch{})
+	assert.NoError(t, err)
+	assert.Nil(t, sp)
+	_, err = NewSearchProvider(settings.WebSearch{Backend: "altavista"})
+	assert.ErrorIs(t, err, ErrUnknownSearchBackend)
+}
+
+func TestWebSearchTool(t *testing.T) {
+	r := NewRegistry(nil)
+	require.NoError(t, r.AddSearchProvider(FakeSearch{
+		{Title: "Go", URL: "https://go.dev", Snippet: "The Go programming language"},
+		{Title: "Rust", URL: "https://rust-lang.org", Snippet: "A language empowering everyone"},
+	}, 5))
+	assert.Contains(t, toolNames(r.ToolsFor(context.Background())), ToolNameWebSearch)
+
+	res, err := r.Invoke(context.Background(), ToolNameWebSearch, map[string]any{"query": "language", "limit": 1})
+	require.NoError(t, err)
+	data := res["structuredContent"].(map[string]any)["results"].([]SearchResult)
+	assert.Len(t, data, 1)
+	assert.Equal(t, "https://go.dev", data[0].URL)
+
+	res, _ = r.Invoke(context.Background(), ToolNameWebSearch, map[string]any{"query": "python"})
+	assert.Nil(t, res["structuredContent"])
+	res, _ = r.Invoke(context.Background(), ToolNameWebSearch, map[string]any{})
+	assert.Equal(t, true, res["isError"])
+}
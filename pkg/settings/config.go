@@ -2,6 +2,7 @@ package settings
 
 import (
 	"log"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -27,6 +28,7 @@ type Config struct {
 	AllowOrigins []string `envconfig:"allow_origins" default:"*" desc:"cors"` // CORS: 允许的 Origin 调用来源
 	AuthRequired bool     `envconfig:"Auth_Required"`
 	AuthSecret   string   `envconfig:"Auth_Secret" desc:"for chatgpt-web session only"`
+	SecretKey    string   `envconfig:"Secret_Key" desc:"key to encrypt credentials at rest, e.g. secrets of mcp server"`
 	CookieName   string   `envconfig:"Cookie_Name" default:"oaic" desc:"for oauth client"`
 	CookiePath   string   `envconfig:"Cookie_Path" default:"/" desc:"for oauth client"`
 	CookieDomain string   `envconfig:"Cookie_Domain" desc:"for oauth client"`
@@ -39,12 +41,18 @@ type Config struct {
 	// BusPrefix is the base URL for Bus API calls (used by capability invoke)
 	BusPrefix string `envconfig:"Bus_Prefix" desc:"Prefix for Bus API"`
 	BusResult string `envconfig:"Bus_Result" default:"result"`
+	// 非 keeper 用户可调用的 API 能力方法，keeper 不受限
+	CapabilityMethods []string `envconfig:"Capability_Methods" default:"GET" desc:"methods non-keepers may invoke, * for all"`
 
 	SitePathMe   string `envconfig:"Site_Path_Me" desc:"OAuth SP Path of /api/me in whole site"`
 	SiteTokenKey string `envconfig:"Site_Token_Key" default:"token" desc:"token key in whole site"`
 
 	StrataMCPURL string `envconfig:"Strata_MCP_URL"`
 
+	// 启动时并发连接 MCP Server 的数量及单个连接超时
+	MCPLoadWorkers    int           `envconfig:"MCP_Load_Workers" default:"4"`
+	MCPConnectTimeout time.Duration `envconfig:"MCP_Connect_Timeout" default:"30s"`
+
 	WebAppPath string `envconfig:"Web_App_Path" default:"/" desc:"web app path for oauth redirect"`
 
 	PresetFile  string `envconfig:"preset_file" desc:"custom welcome and messages"`
@@ -63,12 +71,93 @@ type Config struct {
 	// 相似度匹配数量
 	VectorLimit int `envconfig:"Vector_Limit" default:"6"`
 
+	// 混合检索：向量与全文检索结果按倒数排名融合（RRF），权重为 0 时不使用该路检索
+	HybridVectorWeight float32 `envconfig:"Hybrid_Vector_Weight" default:"1"`
+	HybridTextWeight   float32 `envconfig:"Hybrid_Text_Weight" default:"1" desc:"0 to disable full-text search"`
+	HybridRRFK         int     `envconfig:"Hybrid_RRF_K" default:"60"`
+
+	// 文档导入：按标题切分，超过 IngestChunkSize 个字符的小节再按段落切分，相邻分块重叠 IngestChunkOverlap 个字符
+	IngestChunkSize    int   `envconfig:"Ingest_Chunk_Size" default:"800"`
+	IngestChunkOverlap int   `envconfig:"Ingest_Chunk_Overlap" default:"100"`
+	IngestMaxBytes     int64 `envconfig:"Ingest_Max_Bytes" default:"20971520" desc:"max size of uploaded file"`
+
+	// 工具检索：可用工具数超过 ToolRetrieveMin 时，每轮只提供语义最相关的 ToolTopK 个和核心工具
+	ToolRetrieveMin int      `envconfig:"Tool_Retrieve_Min" default:"24" desc:"0 to disable tool retrieval"`
+	ToolTopK        int      `envconfig:"Tool_TopK" default:"8"`
+	ToolThreshold   float32  `envconfig:"Tool_Threshold" default:"0.55"`
+	ToolCoreSet     []string `envconfig:"Tool_Core_Set" default:"kb_search,fetch,memory_*" desc:"tools always offered, patterns allowed"`
+
+	// 工具结果预算（估算 token），超出时截断，完整结果可通过 tool_result_page 分页读取
+	ToolResultBudget  int            `envconfig:"Tool_Result_Budget" default:"4000" desc:"0 to disable truncation"`
+	ToolResultBudgets map[string]int `envconfig:"Tool_Result_Budgets" desc:"per tool budgets, e.g. fetch:8000,gh-*:2000"`
+	ToolResultTTL     time.Duration  `envconfig:"Tool_Result_TTL" default:"1h"`
+
+	// fetch 工具：禁止访问内网地址，域名支持 example.com（含子域名）形式，deny 优先
+	FetchAllowDomains []string `envconfig:"Fetch_Allow_Domains" desc:"only these domains can be fetched if not empty"`
+	FetchDenyDomains  []string `envconfig:"Fetch_Deny_Domains"`
+	FetchAllowPrivate bool     `envconfig:"Fetch_Allow_Private" desc:"allow private, loopback and link-local addresses, for development only"`
+	FetchMaxBytes     int64    `envconfig:"Fetch_Max_Bytes" default:"5242880"`
+	FetchMaxRedirects int      `envconfig:"Fetch_Max_Redirects" default:"5"`
+	FetchRobots       bool     `envconfig:"Fetch_Robots" desc:"honor robots.txt"`
+	// 抓取内容缓存时长，0 为不缓存
+	FetchCacheTTL time.Duration `envconfig:"Fetch_Cache_TTL" default:"10m"`
+
+	// 工具调用审计：异步写入 convo_tool_call，结果截断到 ToolAuditResultLen 个字符
+	ToolAudit          bool `envconfig:"Tool_Audit" default:"true"`
+	ToolAuditQueue     int  `envconfig:"Tool_Audit_Queue" default:"256"`
+	ToolAuditResultLen int  `envconfig:"Tool_Audit_Result_Len" default:"2000"`
+
+	// code_run 工具：求值超时和解释器内存预算（分配的元素数）
+	CodeRunTimeout time.Duration `envconfig:"Code_Run_Timeout" default:"2s"`
+	CodeRunMemory  uint          `envconfig:"Code_Run_Memory" default:"1000000"`
+
 	// LLM调用循环次数限制，防止无限循环
 	MaxLoopIterations int `envconfig:"MAX_LOOP_ITERATIONS" default:"12"`
 
 	Embedding Provider
 	Interact  Provider
 	Summarize Provider
+
+	// 嵌入模型输出的向量维度，与 Embedding.Model 一起确定写入和匹配的向量空间
+	EmbeddingDim int `envconfig:"Embedding_Dim" default:"1024"`
+
+	// 向量同步：每页数量、单项失败的重试次数和首次退避时间（之后倍增），断点的保留时间
+	EmbedSyncPageSize      int           `envconfig:"Embed_Sync_Page_Size" default:"90"`
+	EmbedSyncRetries       int           `envconfig:"Embed_Sync_Retries" default:"3"`
+	EmbedSyncBackoff       time.Duration `envconfig:"Embed_Sync_Backoff" default:"2s"`
+	EmbedSyncCheckpointTTL time.Duration `envconfig:"Embed_Sync_Checkpoint_TTL" default:"168h"`
+
+	WebSearch WebSearch
+
+	Rerank Rerank
+}
+
+// WebSearch web_search 工具的搜索后端，Backend 为空时不启用
+type WebSearch struct {
+	Backend string        `envconfig:"backend" desc:"searxng, bing, brave or json"`
+	URL     string        `envconfig:"url" desc:"endpoint, required for searxng and json"`
+	APIKey  string        `envconfig:"Api_Key"`
+	Limit   int           `envconfig:"limit" default:"5"`
+	Timeout time.Duration `envconfig:"timeout" default:"15s"`
+	// 通用 JSON 后端：查询参数名、结果数组路径（点分隔）和字段名
+	QueryParam   string `envconfig:"Query_Param" default:"q"`
+	ResultsPath  string `envconfig:"Results_Path" default:"results"`
+	TitleField   string `envconfig:"Title_Field" default:"title"`
+	URLField     string `envconfig:"URL_Field" default:"url"`
+	SnippetField string `envconfig:"Snippet_Field" default:"snippet"`
+}
+
+// Rerank 检索结果重排序，各目标多取候选后重排，保留前 Limit 个，Backend 为空时不启用
+type Rerank struct {
+	Backend string        `envconfig:"backend" desc:"http (jina, cohere or bge compatible api) or llm (summarize client)"`
+	URL     string        `envconfig:"url" desc:"endpoint of rerank api, e.g. https://api.jina.ai/v1/rerank"`
+	APIKey  string        `envconfig:"Api_Key"`
+	Model   string        `envconfig:"model"`
+	Timeout time.Duration `envconfig:"timeout" default:"15s"`
+	// 各目标的候选数量（N），未列出或为 0 的目标不重排
+	Targets map[string]int `envconfig:"targets" default:"doc:20,capability:15,memory:10" desc:"candidates of doc, capability and memory"`
+	// 重排得分低于此值的结果被丢弃，0 为不过滤
+	MinScore float32 `envconfig:"Min_Score"`
 }
 
 type Provider struct {
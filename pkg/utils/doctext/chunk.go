@@ -0,0 +1,235 @@
+package doctext
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk 文档分块，Headings 为所在小节的标题路径
+type Chunk struct {
+	Headings []string
+	Text     string
+	// 小节被切分时的序号（从 1 开始）和总数
+	Part, Parts int
+}
+
+// Heading 返回以 " / " 连接的标题路径，小节被切分时附加序号
+func (c Chunk) Heading() string {
+	h := strings.Join(c.Headings, " / ")
+	if c.Parts > 1 {
+		if len(h) > 0 {
+			h += " "
+		}
+		h += "(" + strconv.Itoa(c.Part) + "/" + strconv.Itoa(c.Parts) + ")"
+	}
+	return h
+}
+
+var atxHeadingRE = regexp.MustCompile(`^(#{1,6})[ \t]+(.+?)[ \t#]*$`)
+
+type mdSection struct {
+	headings []string
+	lines    []string
+}
+
+// SplitMarkdown 按标题将 Markdown 切分为小节，超过 size 个字符的小节按段落切分为分块，
+// 相邻分块重叠 overlap 个字符。文档仅有一个一级标题时作为标题返回，不计入标题路径。
+func SplitMarkdown(md string, size, overlap int) (title string, chunks []Chunk) {
+	if size <= 0 {
+		size = 800
+	}
+	if overlap < 0 || overlap > size/2 {
+		overlap = size / 5
+	}
+
+	sections, title := splitSections(md)
+	for _, sec := range sections {
+		parts := packParagraphs(paragraphs(sec.lines), size)
+		for i, text := range parts {
+			if i > 0 && overlap > 0 {
+				text = overlapTail(parts[i-1], overlap) + "\n" + text
+			}
+			chunks = append(chunks, Chunk{
+				Headings: sec.headings,
+				Text:     text,
+				Part:     i + 1,
+				Parts:    len(parts),
+			})
+		}
+	}
+	return
+}
+
+// splitSections 按 ATX 标题切分小节，忽略代码块中的 # 行
+func splitSections(md string) (out []mdSection, title string) {
+	lines := strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n")
+
+	var h1s []string
+	var fence string
+	for _, line := range lines {
+		if f := fenceOf(line); len(f) > 0 {
+			fence = toggleFence(fence, f)
+			continue
+		}
+		if m := atxHeadingRE.FindStringSubmatch(line); len(fence) == 0 && m != nil && len(m[1]) == 1 {
+			h1s = append(h1s, m[2])
+		}
+	}
+	if len(h1s) == 1 {
+		title = strings.TrimSpace(h1s[0])
+	}
+
+	var stack [6]string
+	cur := mdSection{}
+	fence = ""
+	for _, line := range lines {
+		if f := fenceOf(line); len(f) > 0 {
+			fence = toggleFence(fence, f)
+		} else if m := atxHeadingRE.FindStringSubmatch(line); len(fence) == 0 && m != nil {
+			out = append(out, cur)
+			level := len(m[1])
+			text := strings.TrimSpace(m[2])
+			stack[level-1] = text
+			for i := level; i < len(stack); i++ {
+				stack[i] = ""
+			}
+			var path []string
+			for i, h := range stack[:level] {
+				if len(h) == 0 || (i == 0 && len(title) > 0) {
+					continue
+				}
+				path = append(path, h)
+			}
+			cur = mdSection{headings: path}
+			continue
+		}
+		cur.lines = append(cur.lines, line)
+	}
+	out = append(out, cur)
+	return
+}
+
+func fenceOf(line string) string {
+	s := strings.TrimSpace(line)
+	for _, f := range []string{"```", "~~~"} {
+		if strings.HasPrefix(s, f) {
+			return f
+		}
+	}
+	return ""
+}
+
+func toggleFence(open, f string) string {
+	if len(open) == 0 {
+		return f
+	}
+	if open == f {
+		return ""
+	}
+	return open
+}
+
+// paragraphs 以空行分隔段落，代码块作为整体
+func paragraphs(lines []string) (out []string) {
+	var buf []string
+	var fence string
+	flush := func() {
+		if p := strings.TrimSpace(strings.Join(buf, "\n")); len(p) > 0 {
+			out = append(out, p)
+		}
+		buf = buf[:0]
+	}
+	for _, line := range lines {
+		if f := fenceOf(line); len(f) > 0 {
+			fence = toggleFence(fence, f)
+		}
+		if len(fence) == 0 && len(strings.TrimSpace(line)) == 0 {
+			flush()
+			continue
+		}
+		buf = append(buf, line)
+	}
+	flush()
+	return
+}
+
+// packParagraphs 将段落合并为不超过 size 个字符的分块，超长段落按句子切分
+func packParagraphs(paras []string, size int) (out []string) {
+	var cur strings.Builder
+	var n int
+	flush := func() {
+		if cur.Len() > 0 {
+			out = append(out, cur.String())
+			cur.Reset()
+			n = 0
+		}
+	}
+	for _, p := range paras {
+		pn := utf8.RuneCountInString(p)
+		if pn > size {
+			flush()
+			out = append(out, splitLong(p, size)...)
+			continue
+		}
+		if n > 0 && n+2+pn > size {
+			flush()
+		}
+		if n > 0 {
+			cur.WriteString("\n\n")
+			n += 2
+		}
+		cur.WriteString(p)
+		n += pn
+	}
+	flush()
+	return
+}
+
+// splitLong 按 size 切分超长文本，尽量在后 1/4 范围内的句末或空白处断开
+func splitLong(s string, size int) (out []string) {
+	rs := []rune(s)
+	for len(rs) > size {
+		cut := size
+		for i := size - 1; i >= size*3/4; i-- {
+			if isBreak(rs[i]) {
+				cut = i + 1
+				break
+			}
+		}
+		if part := strings.TrimSpace(string(rs[:cut])); len(part) > 0 {
+			out = append(out, part)
+		}
+		rs = rs[cut:]
+	}
+	if part := strings.TrimSpace(string(rs)); len(part) > 0 {
+		out = append(out, part)
+	}
+	return
+}
+
+func isBreak(r rune) bool {
+	switch r {
+	case '。', '！', '？', '；', '.', '!', '?', ';', '\n':
+		return true
+	}
+	return false
+}
+
+// overlapTail 返回文本末尾约 n 个字符，尽量从句子或词的边界开始
+func overlapTail(s string, n int) string {
+	rs := []rune(s)
+	if len(rs) <= n {
+		return s
+	}
+	start := len(rs) - n
+	for i := start; i < len(rs)-n/2; i++ {
+		if isBreak(rs[i]) || unicode.IsSpace(rs[i]) {
+			start = i + 1
+			break
+		}
+	}
+	return strings.TrimSpace(string(rs[start:]))
+}
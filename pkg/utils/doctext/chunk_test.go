@@ -0,0 +1,120 @@
+package doctext
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const testManual = `# 用户手册
+
+简介段落。
+
+## 安装
+
+### Linux
+
+执行 install.sh 完成安装。
+
+` + "```sh\n# 不是标题\n./install.sh\n```" + `
+
+### Windows
+
+双击 setup.exe。
+
+## 空小节
+
+## 配置
+
+第一段。第二句。
+
+第二段。
+`
+
+func TestSplitMarkdown(t *testing.T) {
+	title, chunks := SplitMarkdown(testManual, 800, 100)
+	if title != "用户手册" {
+		t.Errorf("title = %q", title)
+	}
+	want := []string{"", "安装 / Linux", "安装 / Windows", "配置"}
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks: %+v", len(chunks), chunks)
+	}
+	for i, h := range want {
+		if got := chunks[i].Heading(); got != h {
+			t.Errorf("chunk %d heading = %q, want %q", i, got, h)
+		}
+	}
+	if !strings.Contains(chunks[1].Text, "# 不是标题") {
+		t.Errorf("code block should be kept in section: %q", chunks[1].Text)
+	}
+	if chunks[3].Text != "第一段。第二句。\n\n第二段。" {
+		t.Errorf("unexpected text %q", chunks[3].Text)
+	}
+}
+
+func TestSplitMarkdownOverlap(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("# A\n\n# B\n\n")
+	for i := 0; i < 30; i++ {
+		sb.WriteString("这是一段用于测试切分的文字，包含若干句子。第二句话在这里。\n\n")
+	}
+	title, chunks := SplitMarkdown(sb.String(), 200, 40)
+	if title != "" {
+		t.Errorf("multiple h1 should not be title, got %q", title)
+	}
+	if len(chunks) < 3 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if c.Headings[0] != "B" || c.Parts != len(chunks) || c.Part != i+1 {
+			t.Errorf("unexpected chunk %d: %v %d/%d", i, c.Headings, c.Part, c.Parts)
+		}
+		if n := utf8.RuneCountInString(c.Text); n > 200+40+1 {
+			t.Errorf("chunk %d too long: %d", i, n)
+		}
+		if i > 0 {
+			prev := []rune(chunks[i-1].Text)
+			head := string(prev[len(prev)-10:])
+			if !strings.Contains(c.Text, head) {
+				t.Errorf("chunk %d should overlap with previous tail %q", i, head)
+			}
+		}
+	}
+	if h := chunks[0].Heading(); !strings.HasPrefix(h, "B (1/") {
+		t.Errorf("heading = %q", h)
+	}
+
+	// 无标题的长段落按句子切分
+	_, chunks = SplitMarkdown(strings.Repeat("句子。", 100), 90, 0)
+	if len(chunks) != 4 || chunks[0].Text != strings.Repeat("句子。", 30) {
+		t.Errorf("unexpected chunks %+v", chunks)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"a.md": FormatMarkdown, "b.HTML": FormatHTML, "c.pdf": FormatPDF,
+		"d.docx": FormatDOCX, "e.txt": FormatText, "f.doc": "",
+	}
+	for name, want := range tests {
+		if got := FormatOf(name); got != want {
+			t.Errorf("FormatOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestToMarkdown(t *testing.T) {
+	got, err := ToMarkdown(FormatHTML, []byte(`<html><body><h1>标题</h1><p>内容</p><h2>小节</h2><p>更多</p></body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, chunks := SplitMarkdown(got, 800, 0)
+	if title != "标题" || len(chunks) != 2 || chunks[1].Heading() != "小节" {
+		t.Errorf("unexpected split of %q: %q %+v", got, title, chunks)
+	}
+
+	if _, err := ToMarkdown("doc", nil); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,179 @@
+// Package doctext 从网页和文档中提取纯文本
+package doctext
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+var ErrUnsupported = errors.New("unsupported document")
+
+// Decode 按 Content-Type、BOM 和 meta 标签检测编码并转为 UTF-8，如 GBK 页面
+func Decode(b []byte, contentType string) string {
+	enc, name, certain := charset.DetermineEncoding(b, contentType)
+	if !certain && !utf8.Valid(b) {
+		// 未声明编码且非 UTF-8 时，中文页面多为 GBK
+		enc, name = simplifiedchinese.GB18030, "gb18030"
+	}
+	if name == "utf-8" {
+		return string(b)
+	}
+	out, err := enc.NewDecoder().Bytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return string(out)
+}
+
+// PDF 提取 PDF 中的纯文本
+func PDF(b []byte) (text string, err error) {
+	// 解析器遇到个别损坏文件会 panic
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: broken pdf: %v", ErrUnsupported, r)
+		}
+	}()
+	r, err := pdf.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	var sb strings.Builder
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= r.NumPage(); i++ {
+		p := r.Page(i)
+		if p.V.IsNull() {
+			continue
+		}
+		for _, name := range p.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				f := p.Font(name)
+				fonts[name] = &f
+			}
+		}
+		s, err := p.GetPlainText(fonts)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(strings.TrimSpace(s))
+		sb.WriteString("\n\n")
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// DOCX 提取 Word 文档正文，段落以换行分隔
+func DOCX(b []byte) (string, error) {
+	return docx(b, false)
+}
+
+// DOCXMarkdown 提取 Word 文档正文，标题段落（Heading 1-6、Title 或大纲级别）转为 Markdown 标题
+func DOCXMarkdown(b []byte) (string, error) {
+	return docx(b, true)
+}
+
+func docx(b []byte, headings bool) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		return docxText(rc, headings)
+	}
+	return "", fmt.Errorf("%w: word/document.xml not found", ErrUnsupported)
+}
+
+func docxText(r io.Reader, headings bool) (string, error) {
+	var sb, pb strings.Builder
+	dec := xml.NewDecoder(r)
+	var inText bool
+	var level int
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				pb.WriteByte('\t')
+			case "br", "cr":
+				pb.WriteByte('\n')
+			case "pStyle":
+				if lv := styleLevel(attrValue(t, "val")); lv > 0 {
+					level = lv
+				}
+			case "outlineLvl":
+				if n, err := strconv.Atoi(attrValue(t, "val")); err == nil && n < 6 && level == 0 {
+					level = n + 1
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := pb.String()
+				if headings && level > 0 && len(strings.TrimSpace(text)) > 0 {
+					sb.WriteString("\n" + strings.Repeat("#", level) + " " + strings.TrimSpace(text) + "\n")
+				} else {
+					sb.WriteString(text)
+				}
+				sb.WriteByte('\n')
+				pb.Reset()
+				level = 0
+			}
+		case xml.CharData:
+			if inText {
+				pb.Write(t)
+			}
+		}
+	}
+	sb.WriteString(pb.String())
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func attrValue(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// styleLevel 返回标题样式的级别，中文 Word 的标题样式编号多为纯数字
+func styleLevel(style string) int {
+	s := strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if s == "title" {
+		return 1
+	}
+	s = strings.TrimPrefix(s, "heading")
+	if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= 6 {
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package doctext
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func gbk(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+	}{
+		{"utf8", []byte("你好"), "text/plain", "你好"},
+		{"header", gbk(t, "你好"), "text/plain; charset=gbk", "你好"},
+		{"meta", gbk(t, `<html><head><meta charset="gbk"></head>你好</html>`), "text/html",
+			`<html><head><meta charset="gbk"></head>你好</html>`},
+		{"undeclared", gbk(t, "中文内容"), "text/plain", "中文内容"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Decode(tt.body, tt.contentType); got != tt.want {
+				t.Errorf("Decode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDOCX(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("word/document.xml")
+	_, _ = w.Write([]byte(`<?xml version="1.0"?><w:document xmlns:w="w"><w:body>` +
+		`<w:p><w:r><w:t>标题</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>第一</w:t></w:r><w:r><w:tab/><w:t xml:space="preserve">段 </w:t></w:r></w:p>` +
+		`</w:body></w:document>`))
+	_ = zw.Close()
+
+	got, err := DOCX(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "标题\n第一\t段"; got != want {
+		t.Errorf("DOCX() = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	w, _ = zw.Create("word/document.xml")
+	_, _ = w.Write([]byte(`<?xml version="1.0"?><w:document xmlns:w="w"><w:body>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>安装</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="1"/></w:pPr><w:r><w:t>概述</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>正文</w:t></w:r></w:p>` +
+		`</w:body></w:document>`))
+	_ = zw.Close()
+	got, err = DOCXMarkdown(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "## 安装\n\n\n# 概述\n\n正文"; got != want {
+		t.Errorf("DOCXMarkdown() = %q, want %q", got, want)
+	}
+
+	if _, err = DOCX([]byte("not a zip")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("DOCX() err = %v, want ErrUnsupported", err)
+	}
+	if _, err = PDF([]byte("not a pdf")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("PDF() err = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,62 @@
+package doctext
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	htmd "github.com/JohannesKaufmann/html-to-markdown"
+)
+
+// 支持导入的文档格式
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+	FormatDOCX     = "docx"
+	FormatText     = "txt"
+)
+
+var htmlConverter = htmd.NewConverter("", true, nil)
+
+// FormatOf 按文件扩展名判断格式，未知扩展名返回空串
+func FormatOf(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	case ".pdf":
+		return FormatPDF
+	case ".docx":
+		return FormatDOCX
+	case ".txt", ".text":
+		return FormatText
+	}
+	return ""
+}
+
+// HTML 将网页转为 Markdown，保留标题层级
+func HTML(b []byte, contentType string) (string, error) {
+	out, err := htmlConverter.ConvertString(Decode(b, contentType))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	return out, nil
+}
+
+// ToMarkdown 按格式提取文档内容，HTML 和 DOCX 的标题转为 Markdown 标题，
+// PDF 和纯文本没有结构信息，原样返回文本
+func ToMarkdown(format string, b []byte) (string, error) {
+	switch format {
+	case FormatMarkdown, FormatText:
+		return Decode(b, "text/plain"), nil
+	case FormatHTML:
+		return HTML(b, "text/html")
+	case FormatPDF:
+		return PDF(b)
+	case FormatDOCX:
+		return DOCXMarkdown(b)
+	}
+	return "", fmt.Errorf("%w: format %q", ErrUnsupported, format)
+}
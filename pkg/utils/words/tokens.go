@@ -0,0 +1,46 @@
+package words
+
+import "unicode/utf8"
+
+// EstimateTokens 粗略估算 token 数：CJK 等宽字符按 1 个计，其余按 4 字节 1 个计
+func EstimateTokens(s string) int {
+	var wide, other int
+	for _, r := range s {
+		if r >= 0x2E80 {
+			wide++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return wide + (other+3)/4
+}
+
+// SplitByTokens 按估算 token 数将字符串切分为多段，每段不超过 n
+func SplitByTokens(s string, n int) []string {
+	if n <= 0 || len(s) == 0 {
+		return []string{s}
+	}
+	var (
+		out         []string
+		start       int
+		wide, other int
+	)
+	for i, r := range s {
+		if r >= 0x2E80 {
+			wide++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+		if wide+(other+3)/4 > n {
+			out = append(out, s[start:i])
+			start = i
+			wide, other = 0, 0
+			if r >= 0x2E80 {
+				wide = 1
+			} else {
+				other = utf8.RuneLen(r)
+			}
+		}
+	}
+	return append(out, s[start:])
+}
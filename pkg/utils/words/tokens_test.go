@@ -0,0 +1,40 @@
+package words
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"你好世界", 4},
+		{"hi 你好", 3},
+	}
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.s); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSplitByTokens(t *testing.T) {
+	s := strings.Repeat("abcd", 10) + strings.Repeat("中", 5)
+	parts := SplitByTokens(s, 4)
+	if got := strings.Join(parts, ""); got != s {
+		t.Fatalf("joined parts = %q, want %q", got, s)
+	}
+	for i, p := range parts {
+		if n := EstimateTokens(p); n > 4 {
+			t.Errorf("part %d has %d tokens", i, n)
+		}
+	}
+	if len(parts) != 4 {
+		t.Errorf("len(parts) = %d, want 4", len(parts))
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/ulule/limiter/v3"
 	"github.com/ulule/limiter/v3/drivers/middleware/stdlib"
 	limitRedis "github.com/ulule/limiter/v3/drivers/store/redis"
-	urlquerybinder "github.com/wgarunap/url-query-binder"
 
 	staffio "github.com/liut/staffio-client"
 
@@ -30,7 +29,7 @@ import (
 
 var handles = []handleIn{}
 
-var queryBinder = urlquerybinder.NewQueryBinder()
+var queryBinder = &formBinder{tag: "form"}
 
 type haFunc func(a *api) http.HandlerFunc
 
@@ -56,10 +55,11 @@ type api struct {
 	toolExec *ToolExecutor
 
 	router chi.Router // 用于平台 HTTP 回调注册
+
+	embedding embeddingJob // 后台文档向量同步
 }
 
 func init() {
-	queryBinder.SetTag("form")
 	routes.Register("api", routes.StrapFunc(strap))
 }
 
@@ -78,9 +78,19 @@ func newapi(sto stores.Storage) *api {
 	// 初始化 OAuth MCP 配置
 	var opts = []tools.RegistryOption{
 		tools.WithClientInfo(settings.Current.Name, settings.Version()),
+		tools.WithToolPolicy(preset.ToolPolicy),
+		tools.WithResultStore(stores.SgtRC()),
+		tools.WithToolCache(preset.ToolCache),
 	}
 
 	toolreg := tools.NewRegistry(sto, opts...)
+	if sp, err := tools.NewSearchProvider(settings.Current.WebSearch); err != nil {
+		logger().Warnw("invalid web search backend", "err", err)
+	} else if sp != nil {
+		if err = toolreg.AddSearchProvider(sp, settings.Current.WebSearch.Limit); err != nil {
+			logger().Warnw("add web search fail", "err", err)
+		}
+	}
 	toolreg.ApplyToolDescriptions(preset.Tools)
 
 	if settings.Current.OAuthPathMCP != "" {
@@ -110,10 +120,14 @@ func newapi(sto stores.Storage) *api {
 		}
 	}
 
-	// 加载已激活的 MCP Servers
-	if err := toolreg.LoadServers(context.Background(), sto); err != nil {
-		logger().Warnw("failed to load MCP servers", "err", err)
-	}
+	// 后台加载已激活的 MCP Servers，不阻塞启动，随后同步工具向量
+	go func() {
+		ctx := context.Background()
+		if err := toolreg.LoadServers(ctx, sto); err != nil {
+			logger().Warnw("failed to load MCP servers", "err", err)
+		}
+		_ = toolreg.SyncToolVectors(ctx)
+	}()
 
 	staffio.RegisterStateStore(sto.State())
 
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+)
+
+// formBinder 按 form 标签将查询参数绑定到结构体
+//
+// 与 url-query-binder 不同，会递归嵌入的结构（如 PageSpec、ModelSpec），
+// 并支持布尔、浮点、自定义字符串类型和 encoding.TextUnmarshaler。
+type formBinder struct {
+	tag string
+}
+
+func (b *formBinder) SetTag(tag string) {
+	b.tag = tag
+}
+
+func (b *formBinder) Bind(obj any, u *url.URL) error {
+	rv := reflect.ValueOf(obj)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind query: invalid object type %T", obj)
+	}
+	return b.bindStruct(rv.Elem(), u.Query())
+}
+
+func (b *formBinder) bindStruct(sv reflect.Value, params url.Values) error {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		f, fv := st.Field(i), sv.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		key, ok := f.Tag.Lookup(b.tag)
+		if !ok && f.Anonymous && fv.Kind() == reflect.Struct {
+			if err := b.bindStruct(fv, params); err != nil {
+				return err
+			}
+			continue
+		}
+		if !ok || key == "-" {
+			continue
+		}
+		vals, ok := params[key]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if err := setFormValue(fv, vals); err != nil {
+			return fmt.Errorf("invalid query value of %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func setFormValue(fv reflect.Value, vals []string) error {
+	if tu, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return tu.UnmarshalText([]byte(vals[0]))
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(vals[0])
+	case reflect.Bool:
+		if len(vals[0]) == 0 {
+			fv.SetBool(true)
+			return nil
+		}
+		v, err := strconv.ParseBool(vals[0])
+		if err != nil {
+			return err
+		}
+		fv.SetBool(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := strconv.ParseInt(vals[0], 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(vals[0], 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := strconv.ParseFloat(vals[0], fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(v)
+	case reflect.Slice:
+		out := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setFormValue(out.Index(i), []string{s}); err != nil {
+				return err
+			}
+		}
+		fv.Set(out)
+	default:
+		return fmt.Errorf("unsupported kind %s", fv.Kind())
+	}
+	return nil
+}
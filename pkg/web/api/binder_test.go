@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/liut/morign/pkg/services/stores"
+)
+
+func TestFormBinder(t *testing.T) {
+	u, _ := url.Parse("/api/corpus/documents?title=faq&page=2&limit=10&sort=heading&ids=a,b")
+	var spec stores.CobDocumentSpec
+	if err := queryBinder.Bind(&spec, u); err != nil {
+		t.Fatalf("bind fail: %v", err)
+	}
+	if spec.Title != "faq" || spec.Page != 2 || spec.Limit != 10 || spec.Sort != "heading" {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+	if spec.IDsStr != "a,b" {
+		t.Errorf("embedded model spec should be bound, got %q", spec.IDsStr)
+	}
+
+	u, _ = url.Parse("/api/corpus/documents/search?q=hello&threshold=0.5&limit=3&skipKeywords=true")
+	var ms stores.MatchSpec
+	if err := queryBinder.Bind(&ms, u); err != nil {
+		t.Fatalf("bind fail: %v", err)
+	}
+	if ms.Query != "hello" || ms.Threshold != 0.5 || ms.Limit != 3 || !ms.SkipKeywords {
+		t.Errorf("unexpected match spec %+v", ms)
+	}
+
+	u, _ = url.Parse("/api/corpus/documents?limit=ten")
+	if err := queryBinder.Bind(&spec, u); err == nil {
+		t.Error("expected error for invalid limit")
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"time"
 
+	"github.com/liut/morign/pkg/models/aigc"
 	"github.com/liut/morign/pkg/services/llm"
 	"github.com/liut/morign/pkg/utils/words"
 )
@@ -10,6 +11,7 @@ import (
 const (
 	historyLimitToken = 50 * 1024
 	esDone            = "[DONE]"
+	eventCitations    = "citations"
 
 	dftSystemMsg = "You are a helpful assistant. If you cannot find relevant information in the provided context to answer the user's question, please honestly state that you don't know rather than making up an answer."
 	dftToolsMsg  = "You will select the appropriate tool based on the user's question and call the tool to solve the problem. If the tool returns no relevant information, honestly state that you don't know rather than making up an answer. If the tool requires parameters, you must extract them from the user's question. Note that it is important to clearly distinguish between read and write operations. If a write operation is required by the tool, it must be explicitly stated in the user's question for writing purposes (such as adding, creating, appending, modifying, etc.), and all necessary parameters for the tool must be included in the user's question before calling; otherwise, treat it as a regular read operation or Q&A."
@@ -59,7 +61,10 @@ type ChatRequest struct {
 	Regenerate      bool   `json:"regen"`
 	Stream          bool   `json:"stream"`
 
+	// 本次请求可用的 MCP Server 名称，为空不限
 	MCPs []string `json:"mcps,omitempty"`
+	// 固定到会话的工具名（支持通配），后续请求沿用
+	Tools []string `json:"tools,omitempty"`
 
 	// deprecated: for github.com/Chanzhaoyu/chatgpt-web only
 	Options struct {
@@ -97,6 +102,9 @@ type ChatMessage struct {
 	ConversationID string `json:"csid,omitempty"`
 
 	Title string `json:"title,omitempty"`
+
+	// 回答引用的知识库文档，与回答中的 [^n] 标记对应
+	Citations aigc.Citations `json:"citations,omitempty"`
 }
 
 // wrap response from llm
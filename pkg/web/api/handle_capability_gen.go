@@ -0,0 +1,168 @@
+// This file is generated - Do Not Edit.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/liut/morign/pkg/models/capability"
+	"github.com/liut/morign/pkg/services/stores"
+	binder "github.com/marcsv/go-binder/binder"
+)
+
+func init() {
+	regHI(true, "GET", "/capability/sources", "capability-sources-get", func(a *api) http.HandlerFunc {
+		return a.getCapabilitySources
+	})
+	regHI(true, "GET", "/capability/sources/:id", "capability-sources-id-get", func(a *api) http.HandlerFunc {
+		return a.getCapabilitySource
+	})
+	regHI(true, "POST", "/capability/sources", "capability-sources-post", func(a *api) http.HandlerFunc {
+		return a.postCapabilitySource
+	})
+	regHI(true, "PUT", "/capability/sources/:id", "capability-sources-id-put", func(a *api) http.HandlerFunc {
+		return a.putCapabilitySource
+	})
+	regHI(true, "DELETE", "/capability/sources/:id", "capability-sources-id-delete", func(a *api) http.HandlerFunc {
+		return a.deleteCapabilitySource
+	})
+}
+
+// @Tags 默认 文档生成
+// @ID capability-sources-get
+// @Summary 列出API 来源 🔑
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  query   stores.CapSourceSpec  true   "Object"
+// @Success 200 {object} Done{result=ResultData{data=capability.Sources}}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/capability/sources [get]
+func (a *api) getCapabilitySources(w http.ResponseWriter, r *http.Request) {
+	var spec stores.CapSourceSpec
+	if err := queryBinder.Bind(&spec, r.URL); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	ctx := r.Context()
+	data, total, err := a.sto.Capability().ListSource(ctx, &spec)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, dtResult(data, total))
+}
+
+// @Tags 默认 文档生成
+// @ID capability-sources-id-get
+// @Summary 获取API 来源 🔑
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done{result=capability.Source}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/capability/sources/{id} [get]
+func (a *api) getCapabilitySource(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	obj, err := a.sto.Capability().GetSource(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, obj)
+}
+
+// @Tags 默认 文档生成
+// @ID capability-sources-post
+// @Summary 录入API 来源 🔑
+// @Accept json,mpfd
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  body   capability.SourceBasic  true   "Object"
+// @Success 200 {object} Done{result=ResultID}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/capability/sources [post]
+func (a *api) postCapabilitySource(w http.ResponseWriter, r *http.Request) {
+	var in capability.SourceBasic
+	if err := binder.BindBody(r, &in); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	obj, err := a.sto.Capability().CreateSource(r.Context(), in)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, idResult(obj.ID))
+}
+
+// @Tags 默认 文档生成
+// @ID capability-sources-id-put
+// @Summary 更新API 来源 🔑
+// @Accept json,mpfd
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Param   query  body   capability.SourceSet  true   "Object"
+// @Success 200 {object} Done{result=string}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/capability/sources/{id} [put]
+func (a *api) putCapabilitySource(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in capability.SourceSet
+	if err := binder.BindBody(r, &in); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	err := a.sto.Capability().UpdateSource(r.Context(), id, in)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, "ok")
+}
+
+// @Tags 默认 文档生成
+// @ID capability-sources-id-delete
+// @Summary 删除API 来源 🔑
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/capability/sources/{id} [delete]
+func (a *api) deleteCapabilitySource(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	err := a.sto.Capability().DeleteSource(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, "ok")
+}
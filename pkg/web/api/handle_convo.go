@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -58,6 +59,7 @@ type chatRequest struct {
 	hi       *aigc.HistoryItem
 	chunkIdx int // 全局 chunk 计数器，用于 SSE 事件序号
 	prompt   string
+	scope    toolsvc.ToolScope
 }
 
 func (cr *chatRequest) gatherUsage(res chatResponse) convo.UsageRecordBasic {
@@ -96,6 +98,17 @@ func convertMCPToolsToLLMTools(tools []mcps.ToolDescriptor) []llm.ToolDefinition
 	return result
 }
 
+// mergeDiscoveredTools 将 tool_search 发现的工具加入后续轮次
+func mergeDiscoveredTools(ctx context.Context, toolreg *tools.Registry, defs []llm.ToolDefinition) []llm.ToolDefinition {
+	names := llm.Tools(defs).Names()
+	for _, def := range convertMCPToolsToLLMTools(toolreg.Discovered(ctx)) {
+		if !slices.Contains(names, def.Function.Name) {
+			defs = append(defs, def)
+		}
+	}
+	return defs
+}
+
 // prepareSystemMessage 准备系统消息，包括基础 prompt、记忆、工具或知识库
 func prepareSystemMessage(ctx context.Context, sto stores.Storage,
 	toolreg *tools.Registry, prompt string, cs stores.Conversation) (
@@ -136,7 +149,7 @@ func prepareSystemMessage(ctx context.Context, sto stores.Storage,
 	}
 
 	// 转换 MCP 工具为 LLM 工具定义
-	tools := convertMCPToolsToLLMTools(toolreg.ToolsFor(ctx))
+	tools := convertMCPToolsToLLMTools(toolreg.SelectTools(ctx, prompt))
 	if len(tools) > 0 {
 		toolsPrompt := dftToolsMsg
 		if len(sto.Preset().ToolsPrompt) > 0 {
@@ -152,7 +165,7 @@ func prepareSystemMessage(ctx context.Context, sto stores.Storage,
 		})
 		if err == nil {
 			logger().Infow("matches", "docs", len(docs), "prompt", prompt)
-			content := docs.MarkdownText()
+			content := stores.CiteDocumentsText(ctx, docs)
 			if len(docs) == 0 {
 				content += "\nPlease honestly state that you don't know rather than making up an answer."
 			}
@@ -179,6 +192,13 @@ func prepareSystemMessage(ctx context.Context, sto stores.Storage,
 
 func (a *api) prepareChatRequest(ctx context.Context, param *ChatRequest) *chatRequest {
 	cs := stores.NewConversation(ctx, param.GetConversionID())
+	cs.PinTools(param.Tools...)
+	scope := toolsvc.ToolScope{
+		Channel: cs.GetChannel(),
+		Servers: param.MCPs,
+		Pinned:  cs.PinnedTools(),
+	}
+	ctx = toolsvc.ContextWithToolScope(ctx, scope)
 
 	sysMsg, tools := prepareSystemMessage(ctx, a.sto, a.toolreg, param.Prompt, cs)
 	messages := []llm.Message{sysMsg}
@@ -230,6 +250,7 @@ func (a *api) prepareChatRequest(ctx context.Context, param *ChatRequest) *chatR
 			},
 		},
 		prompt: param.Prompt,
+		scope:  scope,
 	}
 }
 
@@ -251,7 +272,11 @@ func (a *api) postChat(w http.ResponseWriter, r *http.Request) {
 	}
 	isSSE := param.Stream || strings.HasSuffix(r.URL.Path, "-sse")
 	isStream := param.Stream || isSSE
+	// 记录本轮检索到的知识库文档，作为回答的引用
+	r = r.WithContext(stores.ContextWithCitations(r.Context()))
 	ccr := a.prepareChatRequest(r.Context(), &param)
+	// 工具调用时按同一范围校验
+	r = r.WithContext(toolsvc.ContextWithToolScope(r.Context(), ccr.scope))
 
 	ccr.isSSE = isSSE
 
@@ -301,11 +326,17 @@ func (a *api) postChat(w http.ResponseWriter, r *http.Request) {
 
 	var cm ChatMessage
 	cm.Text = answer
+	cm.Citations = stores.CitationsFromContext(r.Context())
 	render.JSON(w, r, &cm)
 }
 
 // writeEvent write and auto flush
 func writeEvent(w io.Writer, id string, m any) bool {
+	return writeTypedEvent(w, "", id, m)
+}
+
+// writeTypedEvent write a named event (empty as message) and auto flush
+func writeTypedEvent(w io.Writer, typ, id string, m any) bool {
 	var b []byte
 	var err error
 	if s, ok := m.(string); ok {
@@ -319,6 +350,7 @@ func writeEvent(w io.Writer, id string, m any) bool {
 	}
 
 	if err = eventsource.WriteEvent(w, eventsource.Event{
+		Type: typ,
 		ID:   id,
 		Data: b,
 	}); err != nil {
@@ -385,6 +417,7 @@ func (a *api) chatStreamResponseLoop(ccr *chatRequest, w http.ResponseWriter, r
 		// 执行工具调用，传入 reasoning_content 以便回传
 		ccr.messages, hasToolCall = a.doExecuteToolCalls(cctx, streamRes.toolCalls, ccr.messages, streamRes.think)
 		logger().Infow("executed tool calls", "hasToolCall", hasToolCall, "msgs", len(ccr.messages))
+		ccr.tools = mergeDiscoveredTools(cctx, a.toolreg, ccr.tools)
 		if !hasToolCall {
 			// 没有成功执行任何工具，跳出循环
 			res.finish = streamRes.finish
@@ -393,9 +426,11 @@ func (a *api) chatStreamResponseLoop(ccr *chatRequest, w http.ResponseWriter, r
 
 	}
 
+	citations := stores.CitationsFromContext(r.Context())
 	if len(res.answer) > 0 {
 		ccr.hi.ChatItem.Assistant = res.answer
 		ccr.hi.ChatItem.Think = res.think
+		ccr.hi.Citations = citations
 		if err := ccr.cs.AddHistory(r.Context(), ccr.hi); err == nil {
 			if err = ccr.cs.Save(r.Context()); err != nil {
 				logger().Infow("save convo fail", "err", err)
@@ -422,6 +457,12 @@ func (a *api) chatStreamResponseLoop(ccr *chatRequest, w http.ResponseWriter, r
 
 	_ = writeEvent(w, strconv.Itoa(ccr.chunkIdx), &cm)
 
+	// 发送引用事件，前端据此链接回答中的 [^n] 标记
+	if len(citations) > 0 {
+		ccr.chunkIdx++
+		_ = writeTypedEvent(w, eventCitations, strconv.Itoa(ccr.chunkIdx), citations)
+	}
+
 	// 发送完成事件（最后）
 	ccr.chunkIdx++
 	_ = writeEvent(w, strconv.Itoa(ccr.chunkIdx), esDone)
@@ -544,7 +585,7 @@ func (a *api) doExecuteToolCalls(ctx context.Context, toolCalls []llm.ToolCall,
 			"content", toolsvc.ResultLogs(content))
 		messages = append(messages, llm.Message{
 			Role:       llm.RoleTool,
-			Content:    formatToolResult(content),
+			Content:    a.toolreg.FitResult(ctx, tc.Function.Name, formatToolResult(content)),
 			ToolCallID: tc.ID,
 		})
 		hasToolCall = true
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/liut/morign/pkg/services/stores"
+)
+
+func init() {
+	regHI(true, "GET", "/convo/toolcalls", "convo-toolcalls-get", func(a *api) http.HandlerFunc {
+		return a.getConvoToolCalls
+	})
+	regHI(true, "GET", "/convo/toolcalls/{id}", "convo-toolcalls-id-get", func(a *api) http.HandlerFunc {
+		return a.getConvoToolCall
+	})
+}
+
+// @Tags 默认 文档生成
+// @ID convo-toolcalls-get
+// @Summary 列出工具调用 🔑
+// @Description 工具调用审计记录，可按会话、用户、工具名、来源、状态和创建时间过滤
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  query   stores.ConvoToolCallSpec  true   "Object"
+// @Success 200 {object} Done{result=ResultData{data=convo.ToolCalls}}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/convo/toolcalls [get]
+func (a *api) getConvoToolCalls(w http.ResponseWriter, r *http.Request) {
+	var spec stores.ConvoToolCallSpec
+	if err := queryBinder.Bind(&spec, r.URL); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	ctx := r.Context()
+	data, total, err := a.sto.Convo().ListToolCall(ctx, &spec)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, dtResult(data, total))
+}
+
+// @Tags 默认 文档生成
+// @ID convo-toolcalls-id-get
+// @Summary 获取工具调用 🔑
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done{result=convo.ToolCall}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/convo/toolcalls/{id} [get]
+func (a *api) getConvoToolCall(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	obj, err := a.sto.Convo().GetToolCall(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, obj)
+}
@@ -0,0 +1,477 @@
+// This file is generated - Do Not Edit.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/liut/morign/pkg/models/corpus"
+	"github.com/liut/morign/pkg/services/stores"
+	binder "github.com/marcsv/go-binder/binder"
+)
+
+func init() {
+	regHI(true, "GET", "/corpus/documents", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusDocuments
+	})
+	regHI(true, "GET", "/corpus/documents/:id", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusDocument
+	})
+	regHI(true, "POST", "/corpus/documents", "corpus-documents-post", func(a *api) http.HandlerFunc {
+		return a.postCorpusDocument
+	})
+	regHI(true, "PUT", "/corpus/documents/:id", "corpus-documents-id-put", func(a *api) http.HandlerFunc {
+		return a.putCorpusDocument
+	})
+	regHI(true, "DELETE", "/corpus/documents/:id", "corpus-documents-id-delete", func(a *api) http.HandlerFunc {
+		return a.deleteCorpusDocument
+	})
+	regHI(true, "GET", "/corpus/sources", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusSources
+	})
+	regHI(true, "GET", "/corpus/sources/:id", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusSource
+	})
+	regHI(true, "DELETE", "/corpus/sources/:id", "corpus-sources-id-delete", func(a *api) http.HandlerFunc {
+		return a.deleteCorpusSource
+	})
+	regHI(true, "GET", "/corpus/collections", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusCollections
+	})
+	regHI(true, "GET", "/corpus/collections/:id", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusCollection
+	})
+	regHI(true, "POST", "/corpus/collections", "corpus-collections-post", func(a *api) http.HandlerFunc {
+		return a.postCorpusCollection
+	})
+	regHI(true, "PUT", "/corpus/collections/:id", "corpus-collections-id-put", func(a *api) http.HandlerFunc {
+		return a.putCorpusCollection
+	})
+	regHI(true, "DELETE", "/corpus/collections/:id", "corpus-collections-id-delete", func(a *api) http.HandlerFunc {
+		return a.deleteCorpusCollection
+	})
+	regHI(true, "GET", "/corpus/revisions", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusRevisions
+	})
+	regHI(true, "GET", "/corpus/revisions/:id", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusRevision
+	})
+}
+
+// @Tags 默认 文档生成
+// @Summary 列出文档
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  query   stores.CobDocumentSpec  true   "Object"
+// @Success 200 {object} Done{result=ResultData{data=corpus.Documents}}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/documents [get]
+func (a *api) getCorpusDocuments(w http.ResponseWriter, r *http.Request) {
+	var spec stores.CobDocumentSpec
+	if err := queryBinder.Bind(&spec, r.URL); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	ctx := r.Context()
+	data, total, err := a.sto.Corpus().ListDocument(ctx, &spec)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, dtResult(data, total))
+}
+
+// @Tags 默认 文档生成
+// @Summary 获取文档
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done{result=corpus.Document}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/documents/{id} [get]
+func (a *api) getCorpusDocument(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var obj *corpus.Document
+	var err error
+	obj, err = a.sto.Corpus().GetDocument(r.Context(), id)
+	if errors.Is(err, stores.ErrNotFound) {
+		fail(w, r, 404, err)
+		return
+	}
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, obj)
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-documents-post
+// @Summary 录入文档 🔑
+// @Accept json,mpfd
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  body   corpus.DocumentBasic  true   "Object"
+// @Success 200 {object} Done{result=ResultID}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/documents [post]
+func (a *api) postCorpusDocument(w http.ResponseWriter, r *http.Request) {
+	var in corpus.DocumentBasic
+	if err := binder.BindBody(r, &in); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	obj, err := a.sto.Corpus().CreateDocument(r.Context(), in)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, idResult(obj.ID))
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-documents-id-put
+// @Summary 更新文档 🔑
+// @Accept json,mpfd
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Param   query  body   corpus.DocumentSet  true   "Object"
+// @Success 200 {object} Done{result=string}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/documents/{id} [put]
+func (a *api) putCorpusDocument(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in corpus.DocumentSet
+	if err := binder.BindBody(r, &in); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	err := a.sto.Corpus().UpdateDocument(r.Context(), id, in)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, "ok")
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-documents-id-delete
+// @Summary 删除文档 🔑
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/documents/{id} [delete]
+func (a *api) deleteCorpusDocument(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	err := a.sto.Corpus().DeleteDocument(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, "ok")
+}
+
+// @Tags 默认 文档生成
+// @Summary 列出来源文档
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  query   stores.CobSourceSpec  true   "Object"
+// @Success 200 {object} Done{result=ResultData{data=corpus.Sources}}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/sources [get]
+func (a *api) getCorpusSources(w http.ResponseWriter, r *http.Request) {
+	var spec stores.CobSourceSpec
+	if err := queryBinder.Bind(&spec, r.URL); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	ctx := r.Context()
+	data, total, err := a.sto.Corpus().ListSource(ctx, &spec)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, dtResult(data, total))
+}
+
+// @Tags 默认 文档生成
+// @Summary 获取来源文档
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done{result=corpus.Source}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/sources/{id} [get]
+func (a *api) getCorpusSource(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var obj *corpus.Source
+	var err error
+	obj, err = a.sto.Corpus().GetSource(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, obj)
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-sources-id-delete
+// @Summary 删除来源文档 🔑
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/sources/{id} [delete]
+func (a *api) deleteCorpusSource(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	err := a.sto.Corpus().DeleteSource(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, "ok")
+}
+
+// @Tags 默认 文档生成
+// @Summary 列出文档集合
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  query   stores.CobCollectionSpec  true   "Object"
+// @Success 200 {object} Done{result=ResultData{data=corpus.Collections}}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/collections [get]
+func (a *api) getCorpusCollections(w http.ResponseWriter, r *http.Request) {
+	var spec stores.CobCollectionSpec
+	if err := queryBinder.Bind(&spec, r.URL); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	ctx := r.Context()
+	data, total, err := a.sto.Corpus().ListCollection(ctx, &spec)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, dtResult(data, total))
+}
+
+// @Tags 默认 文档生成
+// @Summary 获取文档集合
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done{result=corpus.Collection}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/collections/{id} [get]
+func (a *api) getCorpusCollection(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var obj *corpus.Collection
+	var err error
+	obj, err = a.sto.Corpus().GetCollection(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, obj)
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-collections-post
+// @Summary 录入文档集合 🔑
+// @Accept json,mpfd
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  body   corpus.CollectionBasic  true   "Object"
+// @Success 200 {object} Done{result=ResultID}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/collections [post]
+func (a *api) postCorpusCollection(w http.ResponseWriter, r *http.Request) {
+	var in corpus.CollectionBasic
+	if err := binder.BindBody(r, &in); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	obj, err := a.sto.Corpus().CreateCollection(r.Context(), in)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, idResult(obj.ID))
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-collections-id-put
+// @Summary 更新文档集合 🔑
+// @Accept json,mpfd
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Param   query  body   corpus.CollectionSet  true   "Object"
+// @Success 200 {object} Done{result=string}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/collections/{id} [put]
+func (a *api) putCorpusCollection(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var in corpus.CollectionSet
+	if err := binder.BindBody(r, &in); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	err := a.sto.Corpus().UpdateCollection(r.Context(), id, in)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, "ok")
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-collections-id-delete
+// @Summary 删除文档集合 🔑
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/collections/{id} [delete]
+func (a *api) deleteCorpusCollection(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	err := a.sto.Corpus().DeleteCollection(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, "ok")
+}
+
+// @Tags 默认 文档生成
+// @Summary 列出文档修订
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  query   stores.CobRevisionSpec  true   "Object"
+// @Success 200 {object} Done{result=ResultData{data=corpus.Revisions}}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/revisions [get]
+func (a *api) getCorpusRevisions(w http.ResponseWriter, r *http.Request) {
+	var spec stores.CobRevisionSpec
+	if err := queryBinder.Bind(&spec, r.URL); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	ctx := r.Context()
+	data, total, err := a.sto.Corpus().ListRevision(ctx, &spec)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, dtResult(data, total))
+}
+
+// @Tags 默认 文档生成
+// @Summary 获取文档修订
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "编号"
+// @Success 200 {object} Done{result=corpus.Revision}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/revisions/{id} [get]
+func (a *api) getCorpusRevision(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var obj *corpus.Revision
+	var err error
+	obj, err = a.sto.Corpus().GetRevision(r.Context(), id)
+	if errors.Is(err, stores.ErrNotFound) {
+		fail(w, r, 404, err)
+		return
+	}
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, obj)
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/liut/morign/pkg/services/stores"
+	"github.com/liut/morign/pkg/settings"
+)
+
+func init() {
+	regHI(true, "GET", "/corpus/documents/search", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusSearch
+	})
+	regHI(true, "POST", "/corpus/documents/embedding", "corpus-documents-embedding-post", func(a *api) http.HandlerFunc {
+		return a.postCorpusEmbedding
+	})
+	regHI(true, "GET", "/corpus/documents/embedding", "corpus-documents-embedding-get", func(a *api) http.HandlerFunc {
+		return a.getCorpusEmbedding
+	})
+	regHI(true, "POST", "/corpus/sources", "corpus-sources-post", func(a *api) http.HandlerFunc {
+		return a.postCorpusSource
+	})
+	regHI(true, "GET", "/corpus/revisions/{id}/diff", "", func(a *api) http.HandlerFunc {
+		return a.getCorpusRevisionDiff
+	})
+	regHI(true, "POST", "/corpus/revisions/{id}/restore", "corpus-revisions-id-restore-post", func(a *api) http.HandlerFunc {
+		return a.postCorpusRevisionRestore
+	})
+}
+
+// @Tags 默认 文档生成
+// @Summary 语义搜索文档
+// @Description 以向量相似度匹配知识库文档，默认使用配置的阈值和数量
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  query   stores.MatchSpec  true   "Object"
+// @Success 200 {object} Done{result=ResultData{data=corpus.Documents}}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/documents/search [get]
+func (a *api) getCorpusSearch(w http.ResponseWriter, r *http.Request) {
+	var spec stores.MatchSpec
+	if err := queryBinder.Bind(&spec, r.URL); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+	spec.Query = strings.TrimSpace(spec.Query)
+	if len(spec.Query) == 0 {
+		fail(w, r, 400, "empty query")
+		return
+	}
+
+	data, err := a.sto.Corpus().MatchDocments(r.Context(), spec)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, dtResult(data, len(data)))
+}
+
+// EmbeddingStatus 后台文档向量同步的状态
+type EmbeddingStatus struct {
+	// 是否正在运行
+	Running bool `json:"running"`
+	// 开始时间
+	Started time.Time `json:"started,omitempty"`
+	// 上次完成或中止时的报告
+	Report *stores.SyncReport `json:"report,omitempty"`
+	// 上次中止的原因
+	Error string `json:"error,omitempty"`
+} // @name apiEmbeddingStatus
+
+// embeddingJob 同一时间只运行一个文档向量同步
+type embeddingJob struct {
+	mu     sync.Mutex
+	status EmbeddingStatus
+}
+
+func (j *embeddingJob) get() EmbeddingStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+// start 未在运行时开始并返回 true
+func (j *embeddingJob) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.Running {
+		return false
+	}
+	j.status = EmbeddingStatus{Running: true, Started: time.Now()}
+	return true
+}
+
+func (j *embeddingJob) finish(rep *stores.SyncReport, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status.Running, j.status.Report = false, rep
+	if err != nil {
+		j.status.Error = err.Error()
+	}
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-documents-embedding-post
+// @Summary 重新生成文档向量 🔑
+// @Description 在后台按条件（如 ids、title）分页生成文档向量，内容未变化的跳过，limit 为每页数量；
+// @Description 中断后再次请求从断点继续。立即返回同步状态，已在运行时不再重复开始
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   query  query   stores.CobDocumentSpec  true   "Object"
+// @Success 200 {object} Done{result=EmbeddingStatus}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/documents/embedding [post]
+func (a *api) postCorpusEmbedding(w http.ResponseWriter, r *http.Request) {
+	var spec stores.CobDocumentSpec
+	if err := queryBinder.Bind(&spec, r.URL); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	if a.embedding.start() {
+		// 同步可能远超请求的超时，不随请求取消
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			rep, err := a.sto.Corpus().SyncEmbeddingDocments(ctx, &spec)
+			if err != nil {
+				logger().Infow("embedding sync fail", "err", err)
+			}
+			a.embedding.finish(rep, err)
+		}()
+	}
+
+	success(w, r, a.embedding.get())
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-documents-embedding-get
+// @Summary 文档向量同步状态 🔑
+// @Description 后台文档向量同步是否正在运行，以及上次的报告
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Success 200 {object} Done{result=EmbeddingStatus}
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Router /api/corpus/documents/embedding [get]
+func (a *api) getCorpusEmbedding(w http.ResponseWriter, r *http.Request) {
+	success(w, r, a.embedding.get())
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-sources-post
+// @Summary 上传并导入文档 🔑
+// @Description 解析 Markdown、HTML、PDF、DOCX 或纯文本文件，按标题切分为分块存为知识库文档，同名文件再次上传时替换其分块
+// @Accept mpfd
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   file  formData  file    true   "文档文件"
+// @Param   name  formData  string  false  "名称，默认为文件名"
+// @Param   title  formData  string  false  "标题，默认为文档唯一的一级标题或文件名"
+// @Param   chunkSize  formData  int  false  "分块大小"
+// @Param   overlap  formData  int  false  "分块重叠字符数"
+// @Param   collection  formData  string  false  "集合名称或编号，默认为公开的默认集合"
+// @Param   url  formData  string  false  "来源地址，回答引用时链接到此地址"
+// @Success 200 {object} Done{result=stores.IngestResult}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 413 {object} Failure "文件过大"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/sources [post]
+func (a *api) postCorpusSource(w http.ResponseWriter, r *http.Request) {
+	maxBytes := settings.Current.IngestMaxBytes
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+	file, fh, err := r.FormFile("file")
+	if err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+	defer file.Close()
+	if fh.Size > maxBytes {
+		fail(w, r, 413, "file too large")
+		return
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		fail(w, r, 400, err)
+		return
+	}
+
+	ia := stores.IngestArg{
+		Name:  strings.TrimSpace(r.FormValue("name")),
+		Title: strings.TrimSpace(r.FormValue("title")),
+		Data:  data,
+	}
+	if len(ia.Name) == 0 {
+		ia.Name = fh.Filename
+	}
+	ia.ChunkSize, _ = strconv.Atoi(r.FormValue("chunkSize"))
+	ia.Overlap, _ = strconv.Atoi(r.FormValue("overlap"))
+	ia.Collection = strings.TrimSpace(r.FormValue("collection"))
+	ia.URL = strings.TrimSpace(r.FormValue("url"))
+
+	res, err := a.sto.Corpus().IngestDocument(r.Context(), ia)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, res)
+}
+
+// @Tags 默认 文档生成
+// @Summary 对比文档修订
+// @Description 对比修订与 to 指定的修订，未指定时与文档当前内容对比，差异为 unified diff
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "修订编号"
+// @Param   to    query   string  false   "对比的修订编号"
+// @Success 200 {object} Done{result=stores.RevisionDiff}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 404 {object} Failure "目标未找到"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/revisions/{id}/diff [get]
+func (a *api) getCorpusRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	res, err := a.sto.Corpus().DiffRevision(r.Context(), id, r.URL.Query().Get("to"))
+	if errors.Is(err, stores.ErrNotFound) {
+		fail(w, r, 404, err)
+		return
+	}
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, res)
+}
+
+// @Tags 默认 文档生成
+// @ID corpus-revisions-id-restore-post
+// @Summary 恢复文档修订 🔑
+// @Description 将文档恢复为修订的标题、小节和内容，记为新的修订并重新生成向量
+// @Accept json
+// @Produce json
+// @Param token    header   string  true "登录票据凭证"
+// @Param   id    path   string  true   "修订编号"
+// @Success 200 {object} Done{result=corpus.Document}
+// @Failure 400 {object} Failure "请求或参数错误"
+// @Failure 401 {object} Failure "未登录"
+// @Failure 403 {object} Failure "无权限"
+// @Failure 503 {object} Failure "服务端错误"
+// @Router /api/corpus/revisions/{id}/restore [post]
+func (a *api) postCorpusRevisionRestore(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	doc, err := a.sto.Corpus().RestoreRevision(r.Context(), id)
+	if err != nil {
+		fail(w, r, 503, err)
+		return
+	}
+
+	success(w, r, doc)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -60,6 +61,9 @@ func (a *api) putMCPServerActivate(w http.ResponseWriter, r *http.Request) {
 		fail(w, r, 503, err)
 		return
 	}
+	go func() {
+		_ = a.toolreg.SyncToolVectors(context.WithoutCancel(r.Context()))
+	}()
 
 	// 更新 Server 状态为 connected
 	if server.Status != mcps.StatusConnected {
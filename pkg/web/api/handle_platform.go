@@ -131,6 +131,11 @@ func (chh *channelHandler) MessageHandler(p channel.Channel, msg *channel.Messag
 
 	// Build the chat request
 	cs := stores.GetOrCreateConversationBySessionKey(ctx, msg.SessionKey)
+	ctx = tools.ContextWithToolScope(ctx, tools.ToolScope{
+		Channel: cs.GetChannel(),
+		Pinned:  cs.PinnedTools(),
+	})
+	ctx = stores.ContextWithCitations(ctx)
 
 	slog.Info("channel: message received",
 		"channel", p.Name(),
@@ -216,6 +221,7 @@ func (chh *channelHandler) handleStreamingReply(ctx context.Context, p channel.C
 		if !hasToolCall {
 			break
 		}
+		tools = mergeDiscoveredTools(ctx, chh.toolreg, tools)
 	}
 
 	slog.Info("channel: streaming reply finishing",
@@ -238,6 +244,7 @@ func (chh *channelHandler) handleStreamingReply(ctx context.Context, p channel.C
 				User:      msg.Content,
 				Assistant: fullAnswer,
 			},
+			Citations: stores.CitationsFromContext(ctx),
 		}
 		if err := cs.AddHistory(ctx, hi); err == nil {
 			if err := cs.Save(ctx); err != nil {
@@ -335,7 +342,7 @@ func (chh *channelHandler) executeChannelToolCalls(ctx context.Context, messages
 
 		messages = append(messages, llm.Message{
 			Role:       llm.RoleTool,
-			Content:    formatToolResult(content),
+			Content:    chh.toolreg.FitResult(ctx, tc.Function.Name, formatToolResult(content)),
 			ToolCallID: tc.ID,
 		})
 		hasToolCall = true
@@ -373,6 +380,7 @@ func (chh *channelHandler) handleRegularReply(ctx context.Context, p channel.Cha
 				User:      msg.Content,
 				Assistant: answer,
 			},
+			Citations: stores.CitationsFromContext(ctx),
 		}
 		if err := cs.AddHistory(ctx, hi); err == nil {
 			if err := cs.Save(ctx); err != nil {
@@ -75,9 +75,10 @@ func (e *ToolExecutor) ExecuteToolCallLoop(
 				"content", toolsvc.ResultLogs(content))
 			messages = append(messages, llm.Message{
 				Role:       llm.RoleTool,
-				Content:    formatToolResult(content),
+				Content:    e.toolreg.FitResult(ctx, tc.Function.Name, formatToolResult(content)),
 				ToolCallID: tc.ID,
 			})
 		}
+		tools = mergeDiscoveredTools(ctx, e.toolreg, tools)
 	}
 }